@@ -0,0 +1,312 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dataExts are the text formats an upload parses. SQLite files are listed
+// too but need a file on disk, so archives can't carry them.
+var dataExts = map[string]bool{
+	".csv":  true,
+	".json": true,
+	".yaml": true,
+	".yml":  true,
+}
+
+// maxArchiveEntry caps how much of one archived file is read into memory
+const maxArchiveEntry = 64 << 20
+
+// isArchive reports whether name is a zip or (gzipped) tar archive
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveEntry is a data file read from inside an archive
+type archiveEntry struct {
+	Name string // path inside the archive
+	Data []byte
+}
+
+// Ext is the entry's lowercased extension, the format it is parsed as
+func (e archiveEntry) Ext() string {
+	return strings.ToLower(path.Ext(e.Name))
+}
+
+// ReadArchive reads the data files inside a zip or tar archive into memory,
+// without extracting anything to disk. Directories, dotfiles and files of
+// other formats are left out. Entries come in upload order: reference tables
+// before the exercises that name them, then by name.
+func ReadArchive(archivePath string) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	var err error
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		entries, err = readZip(archivePath)
+	} else {
+		entries, err = readTar(archivePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", filepath.Base(archivePath), err)
+	}
+
+	rank := func(e archiveEntry) int {
+		uploadType, err := guessUploadType(e.Name, "")
+		if err != nil {
+			return len(uploadTypes)
+		}
+		return uploadTypeIndex(uploadType.Table)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if ri, rj := rank(entries[i]), rank(entries[j]); ri != rj {
+			return ri < rj
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// wantArchiveEntry reports whether an archived file is a data file to upload
+func wantArchiveEntry(name string) bool {
+	base := path.Base(name)
+	if strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	return dataExts[strings.ToLower(path.Ext(base))]
+}
+
+// readArchiveEntry reads one entry's contents, refusing any larger than
+// maxArchiveEntry
+func readArchiveEntry(name string, r io.Reader) (archiveEntry, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveEntry+1))
+	if err != nil {
+		return archiveEntry{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(data) > maxArchiveEntry {
+		return archiveEntry{}, fmt.Errorf("%s is larger than %d MiB", name, maxArchiveEntry>>20)
+	}
+	return archiveEntry{Name: name, Data: data}, nil
+}
+
+func readZip(archivePath string) ([]archiveEntry, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []archiveEntry
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !wantArchiveEntry(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		entry, err := readArchiveEntry(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func readTar(archivePath string) ([]archiveEntry, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if name := strings.ToLower(archivePath); strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var entries []archiveEntry
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !wantArchiveEntry(hdr.Name) {
+			continue
+		}
+		entry, err := readArchiveEntry(hdr.Name, tr)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// archiveSource names an archived file in upload results and provenance
+func archiveSource(archivePath string, entry archiveEntry) string {
+	return filepath.Base(archivePath) + "/" + entry.Name
+}
+
+// UploadArchive uploads every data file inside an archive, each as the type
+// its filename suggests, and returns one result per file. Files whose type
+// can't be told fail without stopping the rest.
+func UploadArchive(db *sql.DB, archivePath string) ([]UploadResult, error) {
+	entries, err := ReadArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]UploadResult, len(entries))
+	for i, entry := range entries {
+		source := archiveSource(archivePath, entry)
+		uploadType, err := guessUploadType(entry.Name, "")
+		if err != nil {
+			results[i] = UploadResult{File: source, Error: err.Error()}
+			recordUpload(results[i])
+			continue
+		}
+		results[i] = uploadBytes(db, uploadType, source, entry.Ext(), entry.Data)
+	}
+	return results, nil
+}
+
+// openArchive lists the data files inside the selected archive, each marked
+// for upload when its type can be told from its name
+func openArchive(m model, archivePath string) (tea.Model, tea.Cmd) {
+	entries, err := ReadArchive(archivePath)
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error reading archive: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+
+	m.archivePath, m.archiveEntries, m.archiveChoice = archivePath, entries, 0
+	m.archiveTypes = make([]int, len(entries))
+	m.archiveSelected = make(map[int]bool)
+	for i, entry := range entries {
+		m.archiveTypes[i] = -1
+		if uploadType, err := guessUploadType(entry.Name, ""); err == nil {
+			m.archiveTypes[i] = uploadTypeIndex(uploadType.Table)
+			m.archiveSelected[i] = true
+		}
+	}
+	m.state = stateArchive
+	return m, nil
+}
+
+// startArchiveUpload uploads the marked archive entries through the batch
+// progress screen
+func startArchiveUpload(m model) (tea.Model, tea.Cmd) {
+	var picked []int
+	for i := range m.archiveEntries {
+		if m.archiveSelected[i] && m.archiveTypes[i] >= 0 {
+			picked = append(picked, i)
+		}
+	}
+	if len(picked) == 0 {
+		return m, nil
+	}
+
+	db, archivePath, entries, types := m.db, m.archivePath, m.archiveEntries, m.archiveTypes
+	m.batchFiles = make([]batchFile, len(picked))
+	for j, i := range picked {
+		m.batchFiles[j] = batchFile{name: entries[i].Name}
+	}
+	m.archiveEntries, m.archiveTypes, m.archiveSelected = nil, nil, nil
+	m.batchStart, m.batchDuration = time.Now(), 0
+	m.state = stateBatchProgress
+	m.uploadCh = runBatch(len(picked), func(j int) UploadResult {
+		entry := entries[picked[j]]
+		return uploadBytes(db, uploadTypes[types[picked[j]]], archiveSource(archivePath, entry), entry.Ext(), entry.Data)
+	})
+	return m, waitForUpload(m.uploadCh)
+}
+
+func updateArchive(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.archiveChoice > 0 {
+				m.archiveChoice--
+			}
+		case "down", "j":
+			if m.archiveChoice < len(m.archiveEntries)-1 {
+				m.archiveChoice++
+			}
+		case " ", "x":
+			if len(m.archiveEntries) > 0 && m.archiveTypes[m.archiveChoice] >= 0 {
+				m.archiveSelected[m.archiveChoice] = !m.archiveSelected[m.archiveChoice]
+			}
+		case "t":
+			// Cycle the upload type, for files named without a hint
+			if len(m.archiveEntries) > 0 {
+				m.archiveTypes[m.archiveChoice] = (m.archiveTypes[m.archiveChoice] + 1) % len(uploadTypes)
+				m.archiveSelected[m.archiveChoice] = true
+			}
+		case "q", "esc":
+			m.archiveEntries, m.archiveTypes, m.archiveSelected = nil, nil, nil
+			m.state = stateFileSelector
+		case "enter":
+			return startArchiveUpload(m)
+		}
+	}
+	return m, nil
+}
+
+func viewArchive(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle(filepath.Base(m.archivePath)))
+	parts = append(parts, "")
+
+	if len(m.archiveEntries) == 0 {
+		parts = append(parts, RenderHelpText("No CSV, JSON or YAML files inside"))
+	}
+	marked := 0
+	for i, entry := range m.archiveEntries {
+		mark := "[ ]"
+		if m.archiveSelected[i] {
+			mark = "[x]"
+			marked++
+		}
+		typeLabel := "unknown type, press t"
+		if t := m.archiveTypes[i]; t >= 0 {
+			typeLabel = uploadTypes[t].Label
+		}
+		label := fmt.Sprintf("%s %s (%s, %d bytes) → %s", mark, entry.Name, strings.TrimPrefix(entry.Ext(), "."), len(entry.Data), typeLabel)
+		parts = append(parts, RenderFileItem(label, i == m.archiveChoice, false))
+	}
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText(fmt.Sprintf("%d of %d files marked • Navigation: ↑/↓ or j/k • Toggle: space/x • Change type: t • Upload: enter • Back: q/esc",
+		marked, len(m.archiveEntries))))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// auditLogFile records one JSON line per completed upload
+const auditLogFile = "fitrkr-audit.jsonl"
+
+// auditPageSize is how many audit records the history view shows at once
+const auditPageSize = 10
+
+// AuditRecord is one line of the audit log
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	UploadResult
+}
+
+// session collects every upload of this run for the summary InitMenu returns.
+// Uploads report from background goroutines, hence the lock.
+var session struct {
+	sync.Mutex
+	uploads []UploadResult
+}
+
+// sessionUploads returns the uploads recorded this run after the first from,
+// and how many have been recorded in all
+func sessionUploads(from int) ([]UploadResult, int) {
+	session.Lock()
+	defer session.Unlock()
+	return append([]UploadResult(nil), session.uploads[min(from, len(session.uploads)):]...), len(session.uploads)
+}
+
+// recordUpload adds result to the session summary and, outside offline mode,
+// to the audit log. Offline results are marked, since nothing was stored.
+func recordUpload(result UploadResult) {
+	result.Offline = offlineMode
+	session.Lock()
+	session.uploads = append(session.uploads, result)
+	session.Unlock()
+
+	if !offlineMode {
+		writeAudit(result)
+	}
+}
+
+// SessionSummary is what happened during one run of the TUI
+type SessionSummary struct {
+	Uploads []UploadResult
+}
+
+// Failed returns the uploads that ended in an error
+func (s SessionSummary) Failed() []UploadResult {
+	var failed []UploadResult
+	for _, u := range s.Uploads {
+		if !u.Success {
+			failed = append(failed, u)
+		}
+	}
+	return failed
+}
+
+// sessionSummary snapshots the uploads recorded so far
+func sessionSummary() SessionSummary {
+	session.Lock()
+	defer session.Unlock()
+	return SessionSummary{Uploads: append([]UploadResult(nil), session.uploads...)}
+}
+
+// writeAudit appends an upload result to the audit log
+func writeAudit(result UploadResult) {
+	line, err := json.Marshal(AuditRecord{Time: time.Now(), UploadResult: result})
+	if err != nil {
+		logger.Warn("could not encode audit record", "err", err)
+		return
+	}
+
+	f, err := os.OpenFile(auditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		logger.Warn("could not open audit log", "err", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logger.Warn("could not write audit record", "err", err)
+	}
+}
+
+// readRecentAudit returns up to n of the most recent audit records, newest first
+func readRecentAudit(n int) ([]AuditRecord, error) {
+	data, err := os.ReadFile(auditLogFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	var records []AuditRecord
+	for i := len(lines) - 1; i >= 0 && len(records) < n; i-- {
+		if len(lines[i]) == 0 {
+			continue
+		}
+		var rec AuditRecord
+		if err := json.Unmarshal(lines[i], &rec); err != nil {
+			logger.Warn("skipping malformed audit record", "line", i+1, "err", err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func updateAudit(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.auditOffset > 0 {
+				m.auditOffset--
+			}
+		case "down", "j":
+			if m.auditOffset < len(m.auditRecords)-auditPageSize {
+				m.auditOffset++
+			}
+		case "q", "esc", "enter":
+			m.state = stateMenu
+			m.auditRecords = nil
+		}
+	}
+	return m, nil
+}
+
+func viewAudit(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Recent imports"))
+	parts = append(parts, "")
+
+	if len(m.auditRecords) == 0 {
+		parts = append(parts, RenderHelpText("No imports recorded yet"))
+	}
+
+	end := min(m.auditOffset+auditPageSize, len(m.auditRecords))
+	for _, rec := range m.auditRecords[m.auditOffset:end] {
+		line := fmt.Sprintf("%s  %-26s %-24s parsed %d • inserted %d • skipped %d",
+			rec.Time.Format("Jan 2 15:04"), rec.Type, rec.File, rec.Parsed, rec.Inserted, rec.Skipped)
+		if !rec.Success {
+			line = RenderAuditFailure(line + " • " + rec.Error)
+		}
+		parts = append(parts, line)
+	}
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("Scroll: ↑/↓ or j/k • Back: q/esc"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecordUploadOffline(t *testing.T) {
+	t.Chdir(t.TempDir())
+	offlineMode = true
+	t.Cleanup(func() { offlineMode = false })
+	_, from := sessionUploads(0)
+
+	recordUpload(UploadResult{Type: "Upload Equipment", File: "equipment.csv", Parsed: 3, Inserted: 3, Success: true})
+
+	if _, err := os.Stat(auditLogFile); !os.IsNotExist(err) {
+		t.Errorf("offline upload reached the audit log (stat err %v)", err)
+	}
+	uploads, _ := sessionUploads(from)
+	if len(uploads) != 1 || !uploads[0].Offline {
+		t.Fatalf("session uploads = %+v, want one marked offline", uploads)
+	}
+	if got := uploads[0].String(); !strings.HasPrefix(got, "offline: ") {
+		t.Errorf("String() = %q, want it marked offline", got)
+	}
+}
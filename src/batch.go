@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// batchStatus is where one file of a multi-file batch upload stands
+type batchStatus int
+
+const (
+	batchPending batchStatus = iota
+	batchRunning
+	batchDone
+	batchFailed
+)
+
+func (s batchStatus) String() string {
+	switch s {
+	case batchRunning:
+		return "running"
+	case batchDone:
+		return "done"
+	case batchFailed:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+// batchFile is one file of a batch upload and its outcome
+type batchFile struct {
+	name   string
+	status batchStatus
+	result UploadResult
+}
+
+// batchFileMsg reports a status change for the file at index
+type batchFileMsg struct {
+	index  int
+	status batchStatus
+	result UploadResult
+}
+
+// batchDoneMsg marks the end of a batch upload
+type batchDoneMsg struct{}
+
+// readUploadFile reads a data file for uploadType, returning its contents and
+// the format to parse them as. SQLite files are read as CSV of the mapped table.
+func readUploadFile(path string, uploadType UploadType) ([]byte, string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if isArchive(path) {
+		return nil, "", fmt.Errorf("%s is an archive; open it to upload the files inside", filepath.Base(path))
+	}
+	if sqliteExts[ext] {
+		data, err := ReadSQLiteAsCSV(path, uploadType.Table, uploadType.Header)
+		return data, ".csv", err
+	}
+	data, err := os.ReadFile(path)
+	return data, ext, err
+}
+
+// uploadFile runs the whole non-interactive pipeline for one file: parse,
+// validate and insert. Exercises skip the preview and resume checkpoints.
+func uploadFile(db *sql.DB, uploadType UploadType, path string) UploadResult {
+	data, ext, err := readUploadFile(path, uploadType)
+	if err != nil {
+		result := UploadResult{Type: uploadType.Label, File: filepath.Base(path), Error: err.Error()}
+		recordUpload(result)
+		return result
+	}
+	return uploadBytes(db, uploadType, filepath.Base(path), ext, data)
+}
+
+// uploadBytes is uploadFile for contents already read, such as an archive
+// entry; source names them in the results and provenance
+func uploadBytes(db *sql.DB, uploadType UploadType, source, ext string, data []byte) (result UploadResult) {
+	result = UploadResult{Type: uploadType.Label, File: source}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+		recordUpload(result)
+	}()
+	fail := func(err error) UploadResult {
+		result.Error = err.Error()
+		return result
+	}
+
+	if uploadType.Upload != nil {
+		inserted, seen, err := uploadType.Upload(db, ext, data, source)
+		result.Parsed = seen
+		if err != nil {
+			return fail(err)
+		}
+		result.Inserted, result.Skipped, result.Success = inserted, seen-inserted, true
+		return result
+	}
+
+	if uploadType.Parser == nil {
+		rows, seen, problems, err := ParseExercisesCSVReader(bytes.NewReader(data))
+		if err != nil {
+			result.Parsed = seen
+			return fail(err)
+		}
+		all := len(rows) + len(problems)
+		rows, problems, _ = applyExerciseLineRange(rows, problems, seen)
+		for _, p := range problems {
+			result.Malformed = append(result.Malformed, p.Error())
+		}
+		result.Parsed = seen - (all - len(rows) - len(problems))
+		if errs := ValidateExerciseRows(rows); len(errs) > 0 {
+			return fail(fmt.Errorf("validation failed: %s", formatValidationErrors(errs, 3)))
+		}
+		if strictRefs {
+			// Nobody can be asked here, so RESOLVE_REFS doesn't apply
+			refs, err := FindUnknownRefs(db, rows)
+			if err == nil && len(refs) > 0 {
+				err = unknownRefsError(refs)
+			}
+			if err != nil {
+				return fail(err)
+			}
+		}
+		imported, err := InsertExercisesInBatches(db, rows, 0, source, nil, nil)
+		if err != nil {
+			return fail(err)
+		}
+		result.Inserted = len(rows) - imported.Unchanged
+		result.Skipped = imported.Unchanged
+		result.Created = imported.Created
+		result.NoEquipment = imported.NoEquipment
+		result.Success = true
+		return result
+	}
+
+	names, lines, seen, err := uploadType.Parser(ext, data)
+	if err != nil {
+		result.Parsed = seen
+		return fail(err)
+	}
+	all := len(names)
+	names, _ = applyLineRange(names)
+	lines, _ = applyLineRange(lines)
+	result.Parsed = seen - (all - len(names))
+	if errs := ValidateNames(names, lines); len(errs) > 0 {
+		return fail(fmt.Errorf("validation failed: %s", formatValidationErrors(errs, 3)))
+	}
+	parsed := len(names)
+	lineOf := nameLines(names, lines)
+	names, _ = CollapseCaseVariants(names)
+
+	var parents []NameParent
+	if uploadType.Parents != nil {
+		if parents, err = uploadType.Parents(ext, data); err != nil {
+			return fail(err)
+		}
+	}
+
+	var inserted int
+	var failed []FailedName
+	if uploadType.Custom != nil {
+		inserted, _, err = BulkInsertCustomNames(db, *uploadType.Custom, names, nil)
+	} else {
+		inserted, _, failed, result.Unresolved, err = BulkInsertNames(db, uploadType.Table, names, parents, source, nil)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	result.Inserted = inserted
+	result.Skipped = parsed - inserted - len(failed)
+	locateFailedNames(failed, lineOf)
+	for _, f := range failed {
+		result.Failed = append(result.Failed, f.String())
+	}
+	result.Success = true
+	return result
+}
+
+// startBatchUpload uploads files one after another in the background,
+// reporting each file's status changes over the returned channel
+func startBatchUpload(db *sql.DB, uploadType UploadType, files []string) <-chan tea.Msg {
+	return runBatch(len(files), func(i int) UploadResult {
+		return uploadFile(db, uploadType, filepath.Join(dataDir, files[i]))
+	})
+}
+
+// runBatch runs upload for items 0..n-1 one after another in the background,
+// reporting each item's status changes over the returned channel
+func runBatch(n int, upload func(i int) UploadResult) <-chan tea.Msg {
+	ch := make(chan tea.Msg)
+	go func() {
+		for i := range n {
+			ch <- batchFileMsg{index: i, status: batchRunning}
+			result := upload(i)
+			status := batchDone
+			if !result.Success {
+				status = batchFailed
+			}
+			ch <- batchFileMsg{index: i, status: status, result: result}
+		}
+		ch <- batchDoneMsg{}
+	}()
+	return ch
+}
+
+// startBatch begins uploading the files marked in the file selector
+func startBatch(m model) (tea.Model, tea.Cmd) {
+	var files []string
+	for _, name := range m.allFiles {
+		if m.batchSelected[name] {
+			files = append(files, name)
+		}
+	}
+
+	m.batchFiles = make([]batchFile, len(files))
+	for i, name := range files {
+		m.batchFiles[i] = batchFile{name: name}
+	}
+	m.batchSelected = nil
+	m.batchStart, m.batchDuration = time.Now(), 0
+	m.state = stateBatchProgress
+	m.uploadCh = startBatchUpload(m.db, m.selectedUploadType(), files)
+	return m, waitForUpload(m.uploadCh)
+}
+
+func updateBatchProgress(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.uploadCh != nil {
+		// Still running
+		return m, nil
+	}
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "e":
+			if results, _ := sessionUploads(m.reportFrom); len(results) > 0 {
+				return saveHTMLReport(m, results)
+			}
+		case "enter", "q", "esc":
+			m.state = stateMenu
+			m.batchFiles = nil
+			_, m.reportFrom = sessionUploads(0)
+			cmd := m.refreshCounts()
+			return m, cmd
+		}
+	}
+	return m, nil
+}
+
+func viewBatchProgress(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Batch upload"))
+	parts = append(parts, "")
+
+	var rows, failures int
+	for _, f := range m.batchFiles {
+		line := fmt.Sprintf("%-8s %s", f.status, f.name)
+		switch f.status {
+		case batchDone:
+			rows += f.result.Inserted
+			line += fmt.Sprintf("  inserted %d • skipped %d", f.result.Inserted, f.result.Skipped)
+			parts = append(parts, RenderRowStatus(line, RowNew))
+		case batchFailed:
+			failures++
+			parts = append(parts, RenderRowStatus(line+"  "+f.result.Error, RowInvalid))
+		case batchRunning:
+			parts = append(parts, CursorStyle.Render(line))
+		default:
+			parts = append(parts, RenderRowStatus(line, RowUnchanged))
+		}
+	}
+
+	parts = append(parts, "")
+	if m.uploadCh != nil {
+		parts = append(parts, RenderHelpText(fmt.Sprintf("Uploading… %s elapsed", time.Since(m.batchStart).Round(time.Second))))
+	} else {
+		summary := fmt.Sprintf("%d files • %d rows inserted • %d failed • %s",
+			len(m.batchFiles), rows, failures, m.batchDuration.Round(time.Millisecond))
+		if failures > 0 {
+			parts = append(parts, RenderErrorMessage(summary))
+		} else {
+			parts = append(parts, RenderSuccessMessage(summary))
+		}
+		parts = append(parts, RenderHelpText("Press enter, q, or esc to continue • Save an HTML report: e"))
+	}
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// browsePageSize is how many reference entities the browse view shows at once
+const browsePageSize = 15
+
+// dependentSampleSize is how many dependent exercise names CountDependents returns
+const dependentSampleSize = 5
+
+// dependentJoins maps each reference table to how exercises point at it: the
+// junction table and its column, or the exercise table itself for categories
+var dependentJoins = map[string]struct {
+	table  string
+	column string
+}{
+	"muscle_group":      {"exercise_muscles", "muscle_group_id"},
+	"training_type":     {"exercise_training_types", "training_type_id"},
+	"equipment":         {"exercise_equipment", "equipment_id"},
+	"tags":              {"exercise_tags", "tag_id"},
+	"exercise_category": {"exercise", "category_id"},
+}
+
+// CountDependents counts the exercises referencing row id of a reference table
+// and returns a sample of their names
+func CountDependents(db *sql.DB, table string, id string) (int, []string, error) {
+	join, ok := dependentJoins[table]
+	if !ok {
+		return 0, nil, fmt.Errorf("%s has no known dependents", table)
+	}
+
+	query := fmt.Sprintf(`SELECT e.name, COUNT(*) OVER () FROM %s j JOIN exercise e ON e.id = j.exercise_id
+		WHERE j.%s = $1 ORDER BY e.name LIMIT %d`, join.table, join.column, dependentSampleSize)
+	if join.table == "exercise" {
+		query = fmt.Sprintf(`SELECT e.name, COUNT(*) OVER () FROM exercise e
+			WHERE e.%s = $1 ORDER BY e.name LIMIT %d`, join.column, dependentSampleSize)
+	}
+	logSQL(query, id)
+	rows, err := db.Query(query, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var count int
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name, &count); err != nil {
+			return 0, nil, err
+		}
+		names = append(names, name)
+	}
+	return count, names, rows.Err()
+}
+
+// browseRow is one reference entity in the browse view
+type browseRow struct {
+	id      string
+	name    string
+	deleted bool
+	// sourceFile and importedAt are the row's provenance (migration 0002)
+	sourceFile string
+	importedAt sql.NullTime
+}
+
+// provenance describes where and when the row was imported, e.g.
+// "equipment.csv, Jan 2 15:04"
+func (r browseRow) provenance() string {
+	source := r.sourceFile
+	if source == "" {
+		source = "unknown source"
+	}
+	if !r.importedAt.Valid {
+		return source
+	}
+	return source + ", " + r.importedAt.Time.Local().Format("Jan 2 15:04")
+}
+
+// ListReferenceRows returns the rows of a reference table ordered by name,
+// with their provenance, including soft-deleted ones when showDeleted is set.
+// Tables without soft deletes, like exercise, have no deleted_at and list
+// every row.
+func ListReferenceRows(db *sql.DB, table string, showDeleted bool) ([]browseRow, error) {
+	where := liveRowsClause(table)
+	if showDeleted {
+		where = ""
+	}
+	deleted := "false"
+	if softDeleteTables[table] {
+		deleted = "deleted_at IS NOT NULL"
+	}
+	query := fmt.Sprintf("SELECT id, name, %s, COALESCE(source_file, ''), imported_at FROM %s%s ORDER BY name", deleted, table, where)
+	logSQL(query)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []browseRow
+	for rows.Next() {
+		var r browseRow
+		if err := rows.Scan(&r.id, &r.name, &r.deleted, &r.sourceFile, &r.importedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// visibleBrowseRows returns the rows matching the browse filter, case-insensitively
+func (m model) visibleBrowseRows() []browseRow {
+	if m.browseFilter == "" {
+		return m.browseRows
+	}
+	filter := strings.ToLower(m.browseFilter)
+	var rows []browseRow
+	for _, r := range m.browseRows {
+		if strings.Contains(strings.ToLower(r.name), filter) {
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+// encodeNamesCSV renders names as a single-column CSV with a name header
+func encodeNamesCSV(names []string) (string, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write([]string{"name"}); err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if err := w.Write([]string{name}); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// updateBrowseFilter edits the filter while it is being typed
+func updateBrowseFilter(m model, key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.Type {
+	case tea.KeyEnter, tea.KeyEsc:
+		m.browseFiltering = false
+	case tea.KeyBackspace:
+		if r := []rune(m.browseFilter); len(r) > 0 {
+			m.browseFilter = string(r[:len(r)-1])
+		}
+	case tea.KeySpace:
+		m.browseFilter += " "
+	case tea.KeyRunes:
+		m.browseFilter += string(key.Runes)
+	}
+	m.browseChoice, m.browseDeps = 0, ""
+	return m, nil
+}
+
+func updateBrowse(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.browseFiltering {
+		return updateBrowseFilter(m, key)
+	}
+
+	rows := m.visibleBrowseRows()
+	switch key.String() {
+	case "up", "k":
+		if m.browseChoice > 0 {
+			m.browseChoice--
+			m.browseDeps = ""
+		}
+	case "down", "j":
+		if m.browseChoice < len(rows)-1 {
+			m.browseChoice++
+			m.browseDeps = ""
+		}
+	case "/":
+		m.browseFiltering = true
+	case "y":
+		names := make([]string, len(rows))
+		for i, r := range rows {
+			names[i] = r.name
+		}
+		text, err := encodeNamesCSV(names)
+		if err == nil {
+			err = clipboard.WriteAll(text)
+		}
+		if err != nil {
+			m.browseDeps = RenderAuditFailure(fmt.Sprintf("Could not copy to clipboard: %v", err))
+		} else {
+			m.browseDeps = fmt.Sprintf("Copied %d names to the clipboard as CSV.", len(names))
+		}
+	case "enter":
+		if len(rows) == 0 {
+			return m, nil
+		}
+		row := rows[m.browseChoice]
+		count, names, err := CountDependents(m.db, m.browseTable, row.id)
+		switch {
+		case err != nil:
+			m.browseDeps = RenderAuditFailure(fmt.Sprintf("Could not count dependents: %v", err))
+		case count == 0:
+			m.browseDeps = fmt.Sprintf("No exercises reference %s; it is safe to delete.", row.name)
+		default:
+			m.browseDeps = fmt.Sprintf("%d exercises reference %s, e.g. %s", count, row.name, strings.Join(names, ", "))
+		}
+	case "x":
+		if len(rows) == 0 {
+			return m, nil
+		}
+		if rows[m.browseChoice].deleted {
+			m.browseDeps = fmt.Sprintf("%s is already deleted; press r to restore it.", rows[m.browseChoice].name)
+			return m, nil
+		}
+		return confirmDelete(m, rows[m.browseChoice].name)
+	case "s":
+		all, err := ListReferenceRows(m.db, m.browseTable, !m.browseShowDeleted)
+		if err != nil {
+			m.browseDeps = RenderAuditFailure(fmt.Sprintf("Could not reload %s: %v", m.browseTable, err))
+			return m, nil
+		}
+		m.browseShowDeleted = !m.browseShowDeleted
+		m.browseRows, m.browseChoice, m.browseDeps = all, 0, ""
+	case "r":
+		if len(rows) == 0 || !rows[m.browseChoice].deleted {
+			return m, nil
+		}
+		name := rows[m.browseChoice].name
+		if err := RestoreName(m.db, m.browseTable, name); err != nil {
+			m.browseDeps = RenderAuditFailure(fmt.Sprintf("Could not restore %s: %v", name, err))
+			return m, nil
+		}
+		m.browseRows[slices.IndexFunc(m.browseRows, func(r browseRow) bool { return r.name == name })].deleted = false
+		m.browseDeps = fmt.Sprintf("Restored %s.", name)
+	case "p":
+		m.browseShowProvenance = !m.browseShowProvenance
+	case "X":
+		return confirmDelete(m, "")
+	case "q", "esc":
+		m.state = stateMenu
+		m.browseRows, m.browseDeps, m.browseFilter = nil, "", ""
+		m.browseShowDeleted, m.browseShowProvenance = false, false
+	}
+	return m, nil
+}
+
+func viewBrowse(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Browse "+m.browseTable))
+	parts = append(parts, "")
+
+	if m.browseFiltering || m.browseFilter != "" {
+		filter := CursorStyle.Render("filter> ") + m.browseFilter
+		if m.browseFiltering {
+			filter += "█"
+		}
+		parts = append(parts, filter, "")
+	}
+
+	rows := m.visibleBrowseRows()
+	switch {
+	case len(m.browseRows) == 0:
+		parts = append(parts, RenderHelpText("The table is empty"))
+	case len(rows) == 0:
+		parts = append(parts, RenderHelpText("No rows match the filter"))
+	}
+
+	// Keep the selected row on screen
+	offset := max(0, m.browseChoice-browsePageSize+1)
+	end := min(offset+browsePageSize, len(rows))
+	for i := offset; i < end; i++ {
+		label := rows[i].name
+		if m.browseShowProvenance {
+			label += "  " + rows[i].provenance()
+		}
+		if rows[i].deleted {
+			parts = append(parts, RenderDeletedItem(label+" (deleted)", i == m.browseChoice))
+			continue
+		}
+		parts = append(parts, RenderFileItem(label, i == m.browseChoice, false))
+	}
+
+	if m.browseDeps != "" {
+		parts = append(parts, "", m.browseDeps)
+	}
+
+	parts = append(parts, "")
+	if m.browseFiltering {
+		parts = append(parts, RenderHelpText("Type to filter • Done: enter/esc"))
+	} else {
+		parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Check dependents: enter • Filter: / • Copy as CSV: y • Delete: x • Clear table: X • Show deleted: s • Restore: r • Provenance: p • Back: q/esc"))
+	}
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
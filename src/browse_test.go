@@ -0,0 +1,41 @@
+package main
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBrowseShowsProvenance(t *testing.T) {
+	imported := time.Date(2026, 3, 4, 9, 30, 0, 0, time.Local)
+	db, _ := openFakeDB(t, func(query string, args []driver.Value) fakeResult {
+		return fakeResult{
+			columns: []string{"id", "name", "deleted", "source_file", "imported_at"},
+			rows: [][]driver.Value{
+				{"1", "Barbell", false, "equipment.csv", imported},
+				{"2", "Bench", false, "", nil},
+			},
+		}
+	})
+	rows, err := ListReferenceRows(db, "equipment", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel(db, "")
+	m.state, m.browseTable, m.browseRows = stateBrowse, "equipment", rows
+	if view := viewBrowse(m); strings.Contains(view, "equipment.csv") {
+		t.Errorf("provenance shown before toggling it:\n%s", view)
+	}
+
+	next, _ := updateBrowse(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	view := viewBrowse(next.(model))
+	for _, want := range []string{"Barbell  equipment.csv, Mar 4 09:30", "Bench  unknown source", "Provenance: p"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view lacks %q:\n%s", want, view)
+		}
+	}
+}
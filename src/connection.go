@@ -1,21 +1,126 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib" // Register pgx driver
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
+// pingTimeout bounds liveness checks so a vanished database fails fast
+const pingTimeout = 3 * time.Second
+
+// currentDB is the handle in use. A reconnect swaps it, so readers outside the
+// model, like the metrics server, never hold on to a closed pool.
+var currentDB atomic.Pointer[sql.DB]
+
+// closeCurrentDB closes the handle in use at exit, whichever reconnect made it
+func closeCurrentDB() {
+	if db := currentDB.Load(); db != nil {
+		db.Close()
+	}
+}
+
+// errConnLost marks a refresh that failed because the database stopped answering
+var errConnLost = errors.New("database connection lost")
+
+// isConnBroken reports whether err comes from an unusable handle or
+// connection (a closed pool, a dropped socket) rather than from the query
+func isConnBroken(err error) bool {
+	if err == nil {
+		return false
+	}
+	var opErr *net.OpError
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &opErr) ||
+		// database/sql doesn't export the error for a closed *sql.DB
+		strings.Contains(err.Error(), "sql: database is closed")
+}
+
+// connLost marks err with errConnLost when the connection is what failed, so
+// the menu offers to reconnect
+func connLost(err error) error {
+	if isConnBroken(err) && !errors.Is(err, errConnLost) {
+		return fmt.Errorf("%w: %v", errConnLost, err)
+	}
+	return err
+}
+
 func NewConnection(connString string) *sql.DB {
-	db, err := sql.Open("pgx", connString)
+	db, err := OpenConnection(connString)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	return db
+}
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+// OpenConnection opens and pings a database handle, returning any failure
+// instead of exiting so callers can retry
+func OpenConnection(connString string) (*sql.DB, error) {
+	config, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, err
 	}
 
-	return db
+	// In staging mode unqualified table names resolve to the staging copies first
+	if stagingSchema != "" {
+		config.RuntimeParams["search_path"] = stagingSchema + ", public"
+	}
+
+	db := stdlib.OpenDB(*config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+
+	return db, nil
+}
+
+// validateConnString checks that s parses as a pgx connection string and names
+// both a host and a database, so misconfiguration fails with a clear message
+func validateConnString(s string) error {
+	config, err := pgx.ParseConfig(s)
+	if err != nil {
+		return fmt.Errorf("cannot parse connection string: %w", err)
+	}
+	if config.Host == "" {
+		return errors.New("connection string has no host")
+	}
+	if config.Database == "" {
+		return errors.New("connection string has no database name")
+	}
+	return nil
+}
+
+// expandConnString replaces ${VAR} and $VAR placeholders in the connection
+// string with values from the environment. Exits if any referenced variable is unset.
+func expandConnString(s string) string {
+	var missing []string
+	expanded := os.Expand(s, func(key string) string {
+		val, ok := os.LookupEnv(key)
+		if !ok {
+			missing = append(missing, key)
+		}
+		return val
+	})
+
+	if len(missing) > 0 {
+		log.Fatalf("DB_CONN_STRING references unset environment variables: %s", strings.Join(missing, ", "))
+	}
+
+	return expanded
 }
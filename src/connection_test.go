@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestIsConnBroken(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"bad conn", driver.ErrBadConn, true},
+		{"wrapped bad conn", fmt.Errorf("count: %w", driver.ErrBadConn), true},
+		{"conn done", sql.ErrConnDone, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"dropped socket", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}, true},
+		{"closed pool", errors.New("sql: database is closed"), true},
+		{"query error", errors.New(`relation "nope" does not exist`), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnBroken(tt.err); got != tt.want {
+				t.Errorf("isConnBroken(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// refreshOnce runs a count refresh against db and applies its result
+func refreshOnce(t *testing.T, db *sql.DB) model {
+	t.Helper()
+	m := model{db: db, counts: []int{1, 2, 3}}
+	msg, ok := m.refreshCounts()().(countsMsg)
+	if !ok {
+		t.Fatal("refresh did not return a countsMsg")
+	}
+	next, _ := m.Update(msg)
+	return next.(model)
+}
+
+func TestRefreshCountsClosedDB(t *testing.T) {
+	db := OpenOffline()
+	db.Close()
+
+	m := refreshOnce(t, db)
+	if !errors.Is(m.countsErr, errConnLost) {
+		t.Fatalf("countsErr = %v, want a lost connection", m.countsErr)
+	}
+	if m.counts != nil {
+		t.Errorf("counts = %v, want them cleared rather than left stale", m.counts)
+	}
+	if m.countsLoading {
+		t.Error("still loading after the refresh finished")
+	}
+}
+
+func TestRefreshCountsBadConn(t *testing.T) {
+	db, _ := openFakeDB(t, func(string, []driver.Value) fakeResult {
+		return fakeResult{err: driver.ErrBadConn}
+	})
+
+	if m := refreshOnce(t, db); !errors.Is(m.countsErr, errConnLost) {
+		t.Errorf("countsErr = %v, want a lost connection", m.countsErr)
+	}
+}
+
+func TestRefreshCountsNoConnection(t *testing.T) {
+	if m := refreshOnce(t, nil); !errors.Is(m.countsErr, errConnLost) {
+		t.Errorf("countsErr = %v, want a lost connection", m.countsErr)
+	}
+}
+
+func TestRefreshCountsQueryErrorIsNotLostConnection(t *testing.T) {
+	db, _ := openFakeDB(t, func(string, []driver.Value) fakeResult {
+		return fakeResult{err: errors.New("permission denied")}
+	})
+
+	m := refreshOnce(t, db)
+	if m.countsErr == nil || errors.Is(m.countsErr, errConnLost) {
+		t.Errorf("countsErr = %v, want a plain query error", m.countsErr)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// compactMode starts the TUI on the dashboard instead of the action menu (COMPACT_MODE=1)
+var compactMode bool
+
+// TableStatus is one row of the dashboard: a table's size and latest import
+type TableStatus struct {
+	Label      string
+	Table      string
+	Count      int
+	LastImport sql.NullTime
+}
+
+// dashboardMsg carries the result of an asynchronous dashboard load
+type dashboardMsg struct {
+	statuses []TableStatus
+	err      error
+}
+
+// GetTableStatuses counts every upload type's table and finds its most recent
+// imported_at, in a single query unless a table is missing
+func GetTableStatuses(ctx context.Context, db *sql.DB) ([]TableStatus, error) {
+	tables := make([]string, len(uploadTypes))
+	for i, t := range uploadTypes {
+		tables[i] = t.Table
+	}
+	counts, lastImports, err := getAllCountsAndLastImports(ctx, db, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]TableStatus, len(uploadTypes))
+	for i, t := range uploadTypes {
+		statuses[i] = TableStatus{Label: t.Label, Table: t.Table, Count: counts[i], LastImport: lastImports[i]}
+	}
+	return statuses, nil
+}
+
+// loadDashboard fetches the dashboard rows in the background
+func loadDashboard(db *sql.DB) tea.Cmd {
+	return func() tea.Msg {
+		if db == nil {
+			return dashboardMsg{err: fmt.Errorf("%w: no open connection", errConnLost)}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		statuses, err := GetTableStatuses(ctx, db)
+		return dashboardMsg{statuses: statuses, err: connLost(err)}
+	}
+}
+
+func updateDashboard(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.menuChoice > 0 {
+				m.menuChoice--
+			}
+		case "down", "j":
+			if m.menuChoice < len(uploadTypes)-1 {
+				m.menuChoice++
+			}
+		case "r":
+			m.dashboardLoading = true
+			if errors.Is(m.dashboardErr, errConnLost) && m.connString != "" && !m.reconnecting {
+				m.reconnecting = true
+				return m, reconnect(m.connString)
+			}
+			return m, loadDashboard(m.db)
+		case "enter":
+			return openFileSelector(m)
+		case "a":
+			m.state = stateMenu
+			return updateMenu(m, msg)
+		case "d", "esc":
+			m.state = stateMenu
+			return m, nil
+		case "q", "ctrl+c":
+			m.cancelCountRefresh()
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+func viewDashboard(m model) string {
+	var parts []string
+
+	parts = append(parts, m.renderHeader())
+	parts = append(parts, "")
+
+	rows := make([][]string, len(m.dashboard))
+	for i, s := range m.dashboard {
+		last := "never"
+		if s.LastImport.Valid {
+			last = s.LastImport.Time.Local().Format("Jan 2 15:04")
+		}
+		count := fmt.Sprint(s.Count)
+		if s.Count == countNA {
+			count = "n/a"
+		}
+		rows[i] = []string{strings.TrimPrefix(s.Label, "Upload "), s.Table, count, last}
+	}
+
+	switch {
+	case errors.Is(m.dashboardErr, errConnLost):
+		parts = append(parts, RenderErrorMessage(fmt.Sprintf("%v\nPress r to reconnect.", m.dashboardErr)))
+	case m.dashboardErr != nil:
+		parts = append(parts, RenderErrorMessage(fmt.Sprintf("Could not load table status: %v", m.dashboardErr)))
+	case m.dashboard == nil:
+		parts = append(parts, RenderHelpText("Loading…"))
+	default:
+		table := strings.Split(RenderQueryTable([]string{"Type", "Table", "Rows", "Last import"}, rows), "\n")
+		parts = append(parts, cursorPad()+table[0])
+		for i, line := range table[1:] {
+			prefix := cursorPad()
+			if i == m.menuChoice {
+				prefix = CursorStyle.Render(cursorForward)
+			}
+			parts = append(parts, prefix+line)
+		}
+	}
+	parts = append(parts, "", RenderHelpText(describePoolStats(m.db.Stats())))
+	if m.dashboardLoading && m.dashboard != nil {
+		parts = append(parts, "", RenderHelpText("Refreshing…"))
+	}
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Upload: enter • Refresh: r • History: a • Menu: d/esc • Quit: q"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
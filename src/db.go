@@ -1,29 +1,546 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-)
+	"sort"
+	"strings"
 
-const (
-	InsertMuscleGroupQuery  = "INSERT INTO muscle_group (name) VALUES ($1) ON CONFLICT (name) DO NOTHING"
-	InsertTrainingTypeQuery = "INSERT INTO training_type (name) VALUES ($1) ON CONFLICT (name) DO NOTHING"
-	InsertCategoryQuery     = "INSERT INTO exercise_category (name) VALUES ($1) ON CONFLICT (name) DO NOTHING"
-	InsertEquipmentQuery    = "INSERT INTO equipment (name) VALUES ($1) ON CONFLICT (name) DO NOTHING"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
-func InsertNamesToDB(db *sql.DB, query string, names []string) error {
+// nameBatchSize is how many names BulkInsertNames sends per INSERT statement
+const nameBatchSize = 500
+
+// batchFallback retries a failed name batch one row at a time, so a single
+// bad name is left out and reported instead of failing the whole upload
+// (BATCH_FALLBACK=1). Off, any failure rolls back everything.
+var batchFallback bool
+
+// FailedName is a name left out of an upload because inserting it failed
+type FailedName struct {
+	Name string
+	Line int // file line of the name, when known
+	Err  error
+}
+
+func (f FailedName) String() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s (line %d: %v)", f.Name, f.Line, f.Err)
+	}
+	return fmt.Sprintf("%s (%v)", f.Name, f.Err)
+}
+
+// locateFailedNames sets each failed name's Line from lines, as built by nameLines
+func locateFailedNames(failed []FailedName, lines map[string]int) {
+	for i := range failed {
+		failed[i].Line = lines[failed[i].Name]
+	}
+}
+
+// BulkInsertNames dedupes names and inserts them into table in multi-row batches
+// within a single transaction, recording source as their provenance and calling
+// onProgress after each batch. Returns how many rows were actually inserted,
+// the names skipped because they already existed and, with batchFallback, the
+// names left out because inserting them failed. parents are then set in the
+// same transaction; those that couldn't be are returned as unresolved.
+func BulkInsertNames(db *sql.DB, table string, names []string, parents []NameParent, source string, onProgress func(done, total int)) (inserted int, skipped []string, failed []FailedName, unresolved []string, err error) {
+	unique := dedupeNames(names)
+	if sortBeforeInsert {
+		sort.SliceStable(unique, func(i, j int) bool {
+			return strings.ToLower(unique[i]) < strings.ToLower(unique[j])
+		})
+	}
+
+	tx, err := beginUploadTx(db)
+	if err != nil {
+		return 0, nil, nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		logTxEnd(tx, table, source, len(unique), err)
+	}()
+	inserted, skipped, failed, err = insertNameBatches(tx, table, unique, source, onProgress)
+	if err != nil || len(parents) == 0 {
+		return inserted, skipped, failed, nil, err
+	}
+	bad := make(map[string]bool, len(failed))
+	for _, f := range failed {
+		bad[f.Name] = true
+	}
+	var present []string
+	for _, name := range unique {
+		if !bad[name] {
+			present = append(present, name)
+		}
+	}
+	unresolved, err = setParents(tx, table, parents, present)
+	return inserted, skipped, failed, unresolved, err
+}
+
+// insertNameBatches inserts deduplicated names into table within tx, in
+// batches of nameBatchSize. Restored soft-deleted names count as inserted;
+// names that already existed are returned as skipped.
+func insertNameBatches(tx *sql.Tx, table string, unique []string, source string, onProgress func(done, total int)) (inserted int, skipped []string, failed []FailedName, err error) {
+	for start := 0; start < len(unique); start += nameBatchSize {
+		batch := unique[start:min(start+nameBatchSize, len(unique))]
+
+		restored, err := softDeletedNames(tx, table, batch)
+		if err != nil {
+			return inserted, skipped, failed, err
+		}
+		added, batchFailed, err := insertNameBatch(tx, table, batch, source)
+		if err != nil {
+			return inserted, skipped, failed, err
+		}
+		failed = append(failed, batchFailed...)
+		bad := make(map[string]bool, len(batchFailed))
+		for _, f := range batchFailed {
+			bad[f.Name] = true
+			logRow(tx, rowEvent{Table: table, Source: source, Name: f.Name, Outcome: rowFailed, Error: f.Err.Error()})
+		}
+		for name := range restored {
+			if !bad[name] {
+				added[name] = true
+			}
+		}
+		inserted += len(added)
+		for _, name := range batch {
+			if bad[name] {
+				continue
+			}
+			outcome := rowInserted
+			if !added[name] {
+				skipped = append(skipped, name)
+				outcome = rowSkipped
+			}
+			logRow(tx, rowEvent{Table: table, Source: source, Name: name, Outcome: outcome})
+		}
+
+		if onProgress != nil {
+			onProgress(start+len(batch), len(unique))
+		}
+	}
+	return inserted, skipped, failed, nil
+}
+
+// insertNameBatch inserts one batch of names with a multi-row INSERT. With
+// batchFallback, a failing batch is rolled back to a savepoint and retried
+// one name at a time, returning the names that still fail.
+func insertNameBatch(tx *sql.Tx, table string, batch []string, source string) (map[string]bool, []FailedName, error) {
+	if !batchFallback {
+		added, err := insertedNames(tx, nameInsertQuery(table, len(batch)), nameInsertArgs(source, batch)...)
+		return added, nil, err
+	}
+
+	added, err := withSavepoint(tx, "name_batch", func() (map[string]bool, error) {
+		return insertedNames(tx, nameInsertQuery(table, len(batch)), nameInsertArgs(source, batch)...)
+	})
+	if err == nil || isConnBroken(err) {
+		return added, nil, err
+	}
+	logger.Info("name batch failed, retrying one row at a time", "table", table, "err", err)
+
+	added = make(map[string]bool)
+	var failed []FailedName
+	for _, name := range batch {
+		one, err := withSavepoint(tx, "name_row", func() (map[string]bool, error) {
+			return insertedNames(tx, nameInsertQuery(table, 1), nameInsertArgs(source, []string{name})...)
+		})
+		if isConnBroken(err) {
+			return nil, nil, err
+		}
+		if err != nil {
+			failed = append(failed, FailedName{Name: name, Err: err})
+			continue
+		}
+		for n := range one {
+			added[n] = true
+		}
+	}
+	return added, failed, nil
+}
+
+// withSavepoint runs fn after a savepoint, rolling back to it when fn fails so
+// the transaction stays usable
+func withSavepoint(tx *sql.Tx, name string, fn func() (map[string]bool, error)) (map[string]bool, error) {
+	query := "SAVEPOINT " + name
+	logSQL(query)
+	if _, err := tx.Exec(query); err != nil {
+		return nil, err
+	}
+	result, err := fn()
+	if err != nil {
+		query = "ROLLBACK TO SAVEPOINT " + name
+		logSQL(query)
+		if _, rbErr := tx.Exec(query); rbErr != nil {
+			return nil, fmt.Errorf("%w (rolling back to savepoint: %v)", err, rbErr)
+		}
+		return nil, err
+	}
+	query = "RELEASE SAVEPOINT " + name
+	logSQL(query)
+	_, err = tx.Exec(query)
+	return result, err
+}
+
+// nameInsertQuery inserts n names into table, taking the shared source file
+// as $1 and the names from $2
+func nameInsertQuery(table string, n int) string {
+	placeholders := make([]string, n)
+	for i := range n {
+		placeholders[i] = fmt.Sprintf("($%d, $1)", i+2)
+	}
+	return fmt.Sprintf("INSERT INTO %s (name, source_file) VALUES %s %s RETURNING name, (xmax = 0)",
+		table, strings.Join(placeholders, ", "), provenanceConflictClause(table))
+}
+
+// nameInsertArgs are the arguments of nameInsertQuery
+func nameInsertArgs(source string, names []string) []any {
+	args := []any{source}
 	for _, name := range names {
-		_, err := db.Exec(query, name)
+		args = append(args, name)
+	}
+	return args
+}
+
+// CustomTarget is a table and text column that a generic name upload can target
+type CustomTarget struct {
+	Table  string
+	Column string
+}
+
+func (t CustomTarget) String() string {
+	return t.Table + "." + t.Column
+}
+
+// ListCustomTargets returns the text columns of the tables in the current
+// schema, which is the allowlist of targets for custom table uploads
+func ListCustomTargets(db *sql.DB) ([]CustomTarget, error) {
+	query := `SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND data_type IN ('text', 'character varying')
+		AND column_name <> 'source_file'
+		ORDER BY table_name, ordinal_position`
+	logSQL(query)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []CustomTarget
+	for rows.Next() {
+		var t CustomTarget
+		if err := rows.Scan(&t.Table, &t.Column); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	return targets, rows.Err()
+}
+
+// BulkInsertCustomNames dedupes names and inserts them into target in multi-row
+// batches within a single transaction. Custom tables have no provenance columns
+// and may lack a unique constraint, so conflicting rows are skipped without a
+// conflict target. Returns how many rows were actually inserted.
+func BulkInsertCustomNames(db *sql.DB, target CustomTarget, names []string, onProgress func(done, total int)) (inserted int, skipped []string, err error) {
+	unique := dedupeNames(names)
+	table := pgx.Identifier{target.Table}.Sanitize()
+	column := pgx.Identifier{target.Column}.Sanitize()
+
+	tx, err := beginUploadTx(db)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		logTxEnd(tx, target.String(), "", len(unique), err)
+	}()
+
+	for start := 0; start < len(unique); start += nameBatchSize {
+		batch := unique[start:min(start+nameBatchSize, len(unique))]
+
+		placeholders := make([]string, len(batch))
+		args := make([]any, len(batch))
+		for i, name := range batch {
+			placeholders[i] = fmt.Sprintf("($%d)", i+1)
+			args[i] = name
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s ON CONFLICT DO NOTHING RETURNING %s, (xmax = 0)",
+			table, column, strings.Join(placeholders, ", "), column)
+
+		added, err := insertedNames(tx, query, args...)
+		if err != nil {
+			return inserted, skipped, err
+		}
+		inserted += len(added)
+		for _, name := range batch {
+			outcome := rowInserted
+			if !added[name] {
+				skipped = append(skipped, name)
+				outcome = rowSkipped
+			}
+			logRow(tx, rowEvent{Table: target.String(), Name: name, Outcome: outcome})
+		}
+
+		if onProgress != nil {
+			onProgress(start+len(batch), len(unique))
+		}
+	}
+	return inserted, skipped, nil
+}
+
+// uploadIsolation is the isolation level for upload transactions; the zero
+// value keeps the driver default (ISOLATION_LEVEL=serializable)
+var uploadIsolation sql.IsolationLevel
+
+// ParseIsolationLevel maps an ISOLATION_LEVEL value to its sql.IsolationLevel
+func ParseIsolationLevel(s string) (sql.IsolationLevel, error) {
+	switch strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(s, "_", " ")), " ")) {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	}
+	return sql.LevelDefault, fmt.Errorf("unknown isolation level %q", s)
+}
+
+// beginUploadTx starts an upload transaction at the configured isolation level
+func beginUploadTx(db *sql.DB) (*sql.Tx, error) {
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: uploadIsolation})
+	if err == nil {
+		muteTxOf(db, tx)
+	}
+	return tx, err
+}
+
+// sortBeforeInsert inserts names alphabetically (case-insensitive) instead of in
+// file order, so serial ids follow alphabetical order (SORT_BEFORE_INSERT=1)
+var sortBeforeInsert bool
+
+// updateProvenance makes re-imported rows take the latest source file and
+// import time instead of keeping the original (UPDATE_PROVENANCE=1)
+var updateProvenance bool
+
+// provenanceConflictClause is the ON CONFLICT clause for name inserts into
+// table. A soft-deleted name is restored as if it were inserted fresh.
+func provenanceConflictClause(table string) string {
+	switch {
+	case updateProvenance && softDeleteTables[table]:
+		return "ON CONFLICT (name) DO UPDATE SET source_file=EXCLUDED.source_file, imported_at=now(), deleted_at=NULL"
+	case updateProvenance:
+		return "ON CONFLICT (name) DO UPDATE SET source_file=EXCLUDED.source_file, imported_at=now()"
+	case softDeleteTables[table]:
+		return fmt.Sprintf("ON CONFLICT (name) DO UPDATE SET source_file=EXCLUDED.source_file, imported_at=now(), deleted_at=NULL WHERE %s.deleted_at IS NOT NULL", table)
+	}
+	return "ON CONFLICT (name) DO NOTHING"
+}
+
+// softDeletedNames returns which of names are soft-deleted in table, so an
+// upload can count restoring them as inserting them
+func softDeletedNames(tx *sql.Tx, table string, names []string) (map[string]bool, error) {
+	deleted := make(map[string]bool)
+	if !softDeleteTables[table] {
+		return deleted, nil
+	}
+	placeholders := make([]string, len(names))
+	args := make([]any, len(names))
+	for i, name := range names {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = name
+	}
+	query := fmt.Sprintf("SELECT name FROM %s WHERE name IN (%s) AND deleted_at IS NOT NULL", table, strings.Join(placeholders, ", "))
+	logSQL(query, args...)
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		deleted[name] = true
+	}
+	return deleted, rows.Err()
+}
+
+// countInserted runs an INSERT ... RETURNING (xmax = 0) and counts the rows that
+// were newly inserted rather than updated
+func countInserted(tx *sql.Tx, query string, args ...any) (int, error) {
+	logSQL(query, args...)
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	inserted := 0
+	for rows.Next() {
+		var isNew bool
+		if err := rows.Scan(&isNew); err != nil {
+			return inserted, err
+		}
+		if isNew {
+			inserted++
+		}
+	}
+	return inserted, rows.Err()
+}
+
+// insertedNames runs an INSERT ... RETURNING <name>, (xmax = 0) and returns the
+// names that were newly inserted rather than updated
+func insertedNames(tx *sql.Tx, query string, args ...any) (map[string]bool, error) {
+	logSQL(query, args...)
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var isNew bool
+		if err := rows.Scan(&name, &isNew); err != nil {
+			return inserted, err
+		}
+		if isNew {
+			inserted[name] = true
+		}
+	}
+	return inserted, rows.Err()
+}
+
+// dedupeNames returns names with exact duplicates removed, keeping first occurrence order
+func dedupeNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var out []string
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+// isUndefinedTable reports whether err is Postgres' undefined_table error
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+}
+
+// describeDeferredFailure names the constraint a deferred check rejected at commit
+func describeDeferredFailure(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName != "" {
+		return fmt.Errorf("deferred constraint %s failed at commit on %s: %w", pgErr.ConstraintName, pgErr.TableName, err)
+	}
+	return fmt.Errorf("deferred constraint check failed at commit: %w", err)
+}
+
+// isUndefinedColumn reports whether err is Postgres' "column does not exist"
+func isUndefinedColumn(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42703"
+}
+
+// GetAllCounts counts the live rows of every table in one round trip. See
+// getAllCountsAndLastImports for how a failing table is handled.
+func GetAllCounts(db *sql.DB, tables []string) ([]int, error) {
+	counts, _, err := getAllCountsAndLastImports(context.Background(), db, tables)
+	return counts, err
+}
+
+// getAllCountsAndLastImports counts each table's live rows and finds its most
+// recent imported_at with a single UNION ALL query. If that fails, e.g. because
+// one table is missing or lacks imported_at, it degrades to one query per
+// table, reporting countNA for tables that don't exist.
+func getAllCountsAndLastImports(ctx context.Context, db *sql.DB, tables []string) ([]int, []sql.NullTime, error) {
+	counts := make([]int, len(tables))
+	lastImports := make([]sql.NullTime, len(tables))
+	if len(tables) == 0 {
+		return counts, lastImports, nil
+	}
+
+	selects := make([]string, len(tables))
+	for i, table := range tables {
+		selects[i] = fmt.Sprintf("SELECT %d, COUNT(*), MAX(imported_at) FROM %s%s", i, table, liveRowsClause(table))
+	}
+	query := strings.Join(selects, " UNION ALL ")
+	logSQL(query)
+	err := func() error {
+		rows, err := db.QueryContext(ctx, query)
 		if err != nil {
 			return err
 		}
+		defer rows.Close()
+		for rows.Next() {
+			var i, count int
+			var last sql.NullTime
+			if err := rows.Scan(&i, &count, &last); err != nil {
+				return err
+			}
+			counts[i], lastImports[i] = count, last
+		}
+		return rows.Err()
+	}()
+	if err == nil || ctx.Err() != nil || isConnBroken(err) {
+		return counts, lastImports, err
+	}
+
+	logger.Info("combined count failed, counting tables one at a time", "err", err)
+	for i, table := range tables {
+		count, last, err := GetTableCountAndLastImport(ctx, db, table)
+		if isUndefinedTable(err) {
+			counts[i], lastImports[i] = countNA, sql.NullTime{}
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		counts[i], lastImports[i] = count, last
+	}
+	return counts, lastImports, nil
+}
+
+// GetTableCountAndLastImport counts table's live rows and finds its most recent
+// imported_at. Tables without provenance or soft-delete columns fall back to
+// counting every row, with no import time.
+func GetTableCountAndLastImport(ctx context.Context, db *sql.DB, table string) (int, sql.NullTime, error) {
+	var count int
+	var last sql.NullTime
+	query := fmt.Sprintf("SELECT COUNT(*), MAX(imported_at) FROM %s%s", table, liveRowsClause(table))
+	logSQL(query)
+	err := db.QueryRowContext(ctx, query).Scan(&count, &last)
+	if isUndefinedColumn(err) {
+		count, err = GetTableCount(ctx, db, table)
 	}
-	return nil
+	return count, last, err
 }
 
-func GetTableCount(db *sql.DB, table string) int {
+func GetTableCount(ctx context.Context, db *sql.DB, table string) (int, error) {
 	var count int
-	_ = db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count)
-	return count
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", table)
+	logSQL(query)
+	err := db.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
 }
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// deleteCascades lists, for each managed table, the columns in other tables
+// that reference its rows. Deleting a row cascades to, detaches, or is blocked
+// by these rows depending on each foreign key's ON DELETE action.
+var deleteCascades = map[string][]struct {
+	table  string
+	column string
+}{
+	"muscle_group":      {{"exercise_muscles", "muscle_group_id"}, {"muscle_synonyms", "muscle_group_id"}},
+	"training_type":     {{"exercise_training_types", "training_type_id"}},
+	"equipment":         {{"exercise_equipment", "equipment_id"}},
+	"tags":              {{"exercise_tags", "tag_id"}},
+	"exercise_category": {{"exercise", "category_id"}},
+	"exercise": {
+		{"exercise_equipment", "exercise_id"},
+		{"exercise_training_types", "exercise_id"},
+		{"exercise_muscles", "exercise_id"},
+		{"exercise_tags", "exercise_id"},
+		{"exercise", "parent_id"},
+	},
+}
+
+// softDeleteTables are the reference tables whose deletes set deleted_at
+// instead of removing the row (migration 0008)
+var softDeleteTables = map[string]bool{
+	"muscle_group":      true,
+	"training_type":     true,
+	"exercise_category": true,
+	"equipment":         true,
+	"tags":              true,
+}
+
+// liveRowsClause is the WHERE clause hiding soft-deleted rows of table, or ""
+// for tables that are never soft-deleted
+func liveRowsClause(table string) string {
+	if softDeleteTables[table] {
+		return " WHERE deleted_at IS NULL"
+	}
+	return ""
+}
+
+// resetTables is the order ResetAll clears tables in: exercises first, so the
+// reference tables they point at are no longer in use
+var resetTables = []string{"exercise", "tags", "equipment", "training_type", "muscle_group", "exercise_category"}
+
+// CascadeCount is how many rows of another table reference the deleted rows
+type CascadeCount struct {
+	Table  string
+	Column string
+	Rows   int
+}
+
+// DeleteImpact is what a delete removes from one table and what it touches elsewhere
+type DeleteImpact struct {
+	Table    string
+	Rows     int
+	Soft     bool // rows were marked deleted and referencing rows kept
+	Cascades []CascadeCount
+}
+
+func (d DeleteImpact) String() string {
+	verb, refs := "", "reference them"
+	if d.Soft {
+		verb, refs = " soft-deleted", "reference them and are kept"
+	}
+	lines := []string{fmt.Sprintf("%s: %d rows%s", d.Table, d.Rows, verb)}
+	for _, c := range d.Cascades {
+		if c.Rows > 0 {
+			lines = append(lines, fmt.Sprintf("  ↳ %d rows in %s %s (%s)", c.Rows, c.Table, refs, c.Column))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeImpacts renders several delete impacts, one table per block
+func describeImpacts(impacts []DeleteImpact) string {
+	parts := make([]string, len(impacts))
+	for i, d := range impacts {
+		parts[i] = d.String()
+	}
+	return strings.Join(parts, "\n")
+}
+
+// DeleteName deletes the row of table with the given name, soft-deleting it in
+// reference tables. With dryRun the delete runs in a transaction that is rolled
+// back, reporting what it would do.
+func DeleteName(db *sql.DB, table, name string, dryRun bool) ([]DeleteImpact, error) {
+	if softDeleteTables[table] {
+		return SoftDeleteName(db, table, name, dryRun)
+	}
+	return runDelete(db, dryRun, func(tx *sql.Tx) ([]DeleteImpact, error) {
+		impact, err := deleteWhere(tx, table, "name = $1", name)
+		return []DeleteImpact{impact}, err
+	})
+}
+
+// SoftDeleteName marks the row of a reference table with the given name as
+// deleted, or reports what that would do with dryRun
+func SoftDeleteName(db *sql.DB, table, name string, dryRun bool) ([]DeleteImpact, error) {
+	return runDelete(db, dryRun, func(tx *sql.Tx) ([]DeleteImpact, error) {
+		impact, err := softDeleteWhere(tx, table, "name = $1", name)
+		return []DeleteImpact{impact}, err
+	})
+}
+
+// RestoreName clears deleted_at on a soft-deleted row of table
+func RestoreName(db *sql.DB, table, name string) error {
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = NULL WHERE name = $1 AND deleted_at IS NOT NULL", table)
+	logSQL(query, name)
+	res, err := db.Exec(query, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s is not deleted", name)
+	}
+	return nil
+}
+
+// ClearTable deletes every row of table, or reports what that would do with dryRun
+func ClearTable(db *sql.DB, table string, dryRun bool) ([]DeleteImpact, error) {
+	return runDelete(db, dryRun, func(tx *sql.Tx) ([]DeleteImpact, error) {
+		impact, err := deleteWhere(tx, table, "TRUE")
+		return []DeleteImpact{impact}, err
+	})
+}
+
+// ResetAll clears every managed table in one transaction, or reports what that
+// would do with dryRun
+func ResetAll(db *sql.DB, dryRun bool) ([]DeleteImpact, error) {
+	return runDelete(db, dryRun, func(tx *sql.Tx) ([]DeleteImpact, error) {
+		var impacts []DeleteImpact
+		for _, table := range resetTables {
+			impact, err := deleteWhere(tx, table, "TRUE")
+			if err != nil {
+				return impacts, err
+			}
+			impacts = append(impacts, impact)
+		}
+		return impacts, nil
+	})
+}
+
+// runDelete runs del in a transaction, rolling it back for a dry run
+func runDelete(db *sql.DB, dryRun bool, del func(tx *sql.Tx) ([]DeleteImpact, error)) (impacts []DeleteImpact, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil || dryRun {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	return del(tx)
+}
+
+// deleteWhere counts the rows referencing the matching rows of table, then
+// deletes them. Reference tables are soft-deleted instead.
+func deleteWhere(tx *sql.Tx, table, where string, args ...any) (DeleteImpact, error) {
+	if softDeleteTables[table] {
+		return softDeleteWhere(tx, table, where, args...)
+	}
+
+	cascades, err := countReferences(tx, table, where, args...)
+	impact := DeleteImpact{Table: table, Cascades: cascades}
+	if err != nil {
+		return impact, err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", table, where)
+	logSQL(query, args...)
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return impact, fmt.Errorf("delete from %s: %w", table, err)
+	}
+	n, err := res.RowsAffected()
+	impact.Rows = int(n)
+	return impact, err
+}
+
+// softDeleteWhere sets deleted_at on the matching live rows of table
+func softDeleteWhere(tx *sql.Tx, table, where string, args ...any) (DeleteImpact, error) {
+	where = "(" + where + ") AND deleted_at IS NULL"
+	cascades, err := countReferences(tx, table, where, args...)
+	impact := DeleteImpact{Table: table, Soft: true, Cascades: cascades}
+	if err != nil {
+		return impact, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = now() WHERE %s", table, where)
+	logSQL(query, args...)
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return impact, fmt.Errorf("soft-delete from %s: %w", table, err)
+	}
+	n, err := res.RowsAffected()
+	impact.Rows = int(n)
+	return impact, err
+}
+
+// countReferences counts, per referencing table, the rows pointing at the
+// matching rows of table. Referencing tables that don't exist yet are skipped.
+func countReferences(tx *sql.Tx, table, where string, args ...any) ([]CascadeCount, error) {
+	var cascades []CascadeCount
+	for _, dep := range deleteCascades[table] {
+		var exists bool
+		query := `SELECT to_regclass($1) IS NOT NULL`
+		logSQL(query, dep.table)
+		if err := tx.QueryRow(query, dep.table).Scan(&exists); err != nil {
+			return cascades, err
+		}
+		if !exists {
+			continue
+		}
+
+		var n int
+		query = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IN (SELECT id FROM %s WHERE %s)", dep.table, dep.column, table, where)
+		logSQL(query, args...)
+		if err := tx.QueryRow(query, args...).Scan(&n); err != nil {
+			return cascades, fmt.Errorf("count %s: %w", dep.table, err)
+		}
+		cascades = append(cascades, CascadeCount{Table: dep.table, Column: dep.column, Rows: n})
+	}
+	return cascades, nil
+}
+
+// confirmDelete runs the dry run for a delete from the browse view and asks
+// for confirmation. An empty name clears the whole table.
+func confirmDelete(m model, name string) (tea.Model, tea.Cmd) {
+	var impacts []DeleteImpact
+	var err error
+	if name == "" {
+		impacts, err = ClearTable(m.db, m.browseTable, true)
+	} else {
+		impacts, err = DeleteName(m.db, m.browseTable, name, true)
+	}
+	if err != nil {
+		m.browseDeps = RenderAuditFailure(fmt.Sprintf("Delete would fail: %v", err))
+		return m, nil
+	}
+	m.deleteName, m.deleteImpacts = name, impacts
+	m.state = stateConfirmDelete
+	return m, nil
+}
+
+func updateConfirmDelete(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "y":
+		var err error
+		if m.deleteName == "" {
+			_, err = ClearTable(m.db, m.browseTable, false)
+		} else {
+			_, err = DeleteName(m.db, m.browseTable, m.deleteName, false)
+		}
+		m.state = stateBrowse
+		if err != nil {
+			m.browseDeps = RenderAuditFailure(fmt.Sprintf("Delete failed, nothing was changed: %v", err))
+			return m, nil
+		}
+		rows, err := ListReferenceRows(m.db, m.browseTable, m.browseShowDeleted)
+		if err != nil {
+			m.browseDeps = RenderAuditFailure(fmt.Sprintf("Deleted, but could not reload %s: %v", m.browseTable, err))
+			return m, nil
+		}
+		m.browseRows, m.browseChoice = rows, 0
+		m.browseDeps = fmt.Sprintf("Deleted %d rows from %s.", m.deleteImpacts[0].Rows, m.browseTable)
+		m.deleteImpacts = nil
+	case "n", "q", "esc":
+		m.state = stateBrowse
+		m.deleteImpacts = nil
+	}
+	return m, nil
+}
+
+func viewConfirmDelete(m model) string {
+	target := m.browseTable + " (all rows)"
+	if m.deleteName != "" {
+		target = fmt.Sprintf("%s from %s", m.deleteName, m.browseTable)
+	}
+	content := RenderErrorMessage(fmt.Sprintf("Delete %s?\n\nDry run:\n%s", target, describeImpacts(m.deleteImpacts))) +
+		"\n\n" + RenderHelpText("Delete: y • Cancel: n/esc")
+	return ContainerStyle.Render(content)
+}
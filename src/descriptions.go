@@ -0,0 +1,192 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// stripMarkdown makes --descriptions-dir store descriptions as plain text
+// instead of the Markdown source (--strip-markdown)
+var stripMarkdown bool
+
+// descriptionReportLimit caps how many names each list of a DescriptionReport shows
+const descriptionReportLimit = 20
+
+// DescriptionReport is the outcome of updating descriptions from a directory
+type DescriptionReport struct {
+	Updated    int
+	NoFile     []string // exercises without a matching file
+	NoExercise []string // files naming no existing exercise
+}
+
+func (r DescriptionReport) String() string {
+	lines := []string{fmt.Sprintf("Updated %d descriptions", r.Updated)}
+	list := func(title string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s (%d):", title, len(names)))
+		for _, name := range names[:min(len(names), descriptionReportLimit)] {
+			lines = append(lines, "  - "+name)
+		}
+		if extra := len(names) - descriptionReportLimit; extra > 0 {
+			lines = append(lines, fmt.Sprintf("  … and %d more", extra))
+		}
+	}
+	list("Files with no matching exercise", r.NoExercise)
+	list("Exercises with no file", r.NoFile)
+	return strings.Join(lines, "\n")
+}
+
+// UpdateDescriptionsFromDir sets the description of each existing exercise
+// that has a "<Exercise Name>.md" file in dir to the file's contents. It
+// returns how many were updated and how many files matched no exercise.
+func UpdateDescriptionsFromDir(db *sql.DB, dir string) (updated, missing int, err error) {
+	report, err := updateDescriptionsFromDir(db, dir)
+	return report.Updated, len(report.NoExercise), err
+}
+
+// updateDescriptionsFromDir is UpdateDescriptionsFromDir with the full report.
+// Names match case-insensitively; every update happens in one transaction.
+func updateDescriptionsFromDir(db *sql.DB, dir string) (report DescriptionReport, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return report, err
+	}
+	files := make(map[string]string) // lowercased exercise name → path
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !strings.EqualFold(ext, ".md") {
+			continue
+		}
+		name := strings.TrimSpace(strings.TrimSuffix(entry.Name(), ext))
+		files[strings.ToLower(name)] = filepath.Join(dir, entry.Name())
+	}
+
+	tx, err := beginUploadTx(db)
+	if err != nil {
+		return report, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query := `SELECT id, name FROM exercise ORDER BY name`
+	logSQL(query)
+	rows, err := tx.Query(query)
+	if err != nil {
+		return report, err
+	}
+	type exercise struct {
+		id   string
+		name string
+	}
+	var exercises []exercise
+	for rows.Next() {
+		var e exercise
+		if err := rows.Scan(&e.id, &e.name); err != nil {
+			rows.Close()
+			return report, err
+		}
+		exercises = append(exercises, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	for _, e := range exercises {
+		path, ok := files[strings.ToLower(e.name)]
+		if !ok {
+			report.NoFile = append(report.NoFile, e.name)
+			continue
+		}
+		delete(files, strings.ToLower(e.name))
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return report, err
+		}
+		description := strings.TrimSpace(string(normalizeInput(data)))
+		if stripMarkdown {
+			description = markdownToText(description)
+		}
+
+		query := `UPDATE exercise SET description = $1 WHERE id = $2`
+		logSQL(query, description, e.id)
+		if _, err := tx.Exec(query, description, e.id); err != nil {
+			return report, fmt.Errorf("update %s: %w", e.name, err)
+		}
+		report.Updated++
+	}
+
+	for _, path := range files {
+		report.NoExercise = append(report.NoExercise, filepath.Base(path))
+	}
+	sort.Slice(report.NoExercise, func(i, j int) bool {
+		return strings.ToLower(report.NoExercise[i]) < strings.ToLower(report.NoExercise[j])
+	})
+	return report, nil
+}
+
+var (
+	mdImage = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink  = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	// RE2 has no backreferences, so each emphasis marker gets its own pattern,
+	// strongest first
+	mdEmphasis = []*regexp.Regexp{
+		regexp.MustCompile(`\*\*(.+?)\*\*`),
+		regexp.MustCompile(`__(.+?)__`),
+		regexp.MustCompile(`~~(.+?)~~`),
+		regexp.MustCompile("`([^`]+)`"),
+		regexp.MustCompile(`\*(\S[^*]*?)\*`),
+		regexp.MustCompile(`\b_(\S[^_]*?)_\b`),
+	}
+	mdHeading = regexp.MustCompile(`^#{1,6}\s+`)
+	mdQuote   = regexp.MustCompile(`^>\s?`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	mdRule    = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+)
+
+// markdownToText renders the common Markdown constructs as plain text:
+// headings, emphasis, links and images lose their markup, bullets become "•",
+// and horizontal rules and code fence lines are dropped
+func markdownToText(md string) string {
+	var out []string
+	blank := false
+	for _, line := range strings.Split(md, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") || mdRule.MatchString(line) {
+			continue
+		}
+		line = mdHeading.ReplaceAllString(line, "")
+		line = mdQuote.ReplaceAllString(line, "")
+		line = mdBullet.ReplaceAllString(line, "$1• ")
+		line = mdImage.ReplaceAllString(line, "$1")
+		line = mdLink.ReplaceAllString(line, "$1")
+		for _, re := range mdEmphasis {
+			line = re.ReplaceAllString(line, "$1")
+		}
+		line = strings.TrimRight(line, " \t")
+
+		// Collapse runs of blank lines into one
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
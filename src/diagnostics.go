@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// diagnosticsPageSize is how many diagnostics lines show at once
+const diagnosticsPageSize = 20
+
+// setting is one resolved configuration value and where it is set
+type setting struct {
+	name   string
+	source string // env var or flag
+	value  string
+}
+
+// activeSettings lists the effective configuration after flags, .env and
+// defaults are resolved
+func activeSettings() []setting {
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+	var transforms []string
+	for _, t := range columnTransforms {
+		transforms = append(transforms, strings.TrimSuffix(exerciseHeader[t.column], "?")+"="+t.spec)
+	}
+	lines := "all"
+	if uploadLines.first > 0 {
+		lines = uploadLines.String()
+	}
+
+	return []setting{
+		{"Offline", "OFFLINE, --offline", onOff(offlineMode)},
+		{"Staging schema", "STAGING_SCHEMA", stagingSchema},
+		{"Primary keys", "PRIMARY_KEYS", primaryKeys},
+		{"Isolation level", "ISOLATION_LEVEL", uploadIsolation.String()},
+		{"Name batch size", "", fmt.Sprint(nameBatchSize)},
+		{"Batch fallback", "BATCH_FALLBACK", onOff(batchFallback)},
+		{"Exercise commit size", "EXERCISE_COMMIT_SIZE", fmt.Sprint(exerciseCommitSize)},
+		{"Confirm threshold", "CONFIRM_THRESHOLD", fmt.Sprint(confirmThreshold)},
+		{"Similarity threshold", "SIMILARITY_THRESHOLD", fmt.Sprint(similarityThreshold)},
+		{"Skip unchanged", "SKIP_UNCHANGED", onOff(skipUnchanged)},
+		{"Update provenance", "UPDATE_PROVENANCE", onOff(updateProvenance)},
+		{"Defer constraints", "DEFER_CONSTRAINTS", onOff(deferConstraints)},
+		{"Sort before insert", "SORT_BEFORE_INSERT", onOff(sortBeforeInsert)},
+		{"Fail fast", "FAIL_FAST", onOff(failFast)},
+		{"Resolve references", "RESOLVE_REFS", onOff(resolveRefs)},
+		{"Strict references", "STRICT_REFS", onOff(strictRefs)},
+		{"Strict columns", "--strict-columns", onOff(strictColumns)},
+		{"Validate schema", "--validate-schema", onOff(validateSchema)},
+		{"CSV header", "--has-header, --no-header", csvHeader.String()},
+		{"Rows", "--lines", lines},
+		{"Name columns", "NAME_COLUMNS", strings.Join(nameColumns, ", ")},
+		{"Column transforms", "COLUMN_TRANSFORMS", strings.Join(transforms, "; ")},
+		{"No-equipment values", "NO_EQUIPMENT", strings.Join(noEquipment, ", ")},
+		{"Count tables", "COUNT_TABLES", strings.Join(countTables, ", ")},
+		{"Default upload type", "DEFAULT_UPLOAD_TYPE", defaultUploadType},
+		{"Migration lock timeout", "MIGRATION_LOCK_TIMEOUT", migrationLockTimeout.String()},
+		{"Ping timeout", "", pingTimeout.String()},
+		{"Row log", "ROW_LOG, --row-log", rowLogPath},
+		{"Metrics address", "METRICS_ADDR", metricsAddr},
+		{"Notify command", "NOTIFY_COMMAND", onOff(notifyCommand != "")},
+		{"Notify webhook", "NOTIFY_WEBHOOK", redactWebhook(notifyWebhook)},
+		{"Notify bell", "NOTIFY_BELL", onOff(notifyBell)},
+		{"Debug SQL", "DEBUG_SQL", onOff(debugSQL)},
+		{"Color profile", "COLOR_PROFILE", lipgloss.ColorProfile().Name()},
+		{"Accessible", "ACCESSIBLE", onOff(accessibleMode)},
+		{"Compact", "COMPACT_MODE", onOff(compactMode)},
+	}
+}
+
+// redactConnString describes the database a connection string targets
+// without its password or other parameters
+func redactConnString(connString string) string {
+	if offlineMode {
+		return "offline fake"
+	}
+	if connString == "" {
+		return "unknown"
+	}
+	cfg, err := pgconn.ParseConfig(connString)
+	if err != nil {
+		return "unparseable connection string"
+	}
+	return fmt.Sprintf("%s@%s:%d/%s", cfg.User, cfg.Host, cfg.Port, cfg.Database)
+}
+
+// redactWebhook describes a webhook URL by its scheme and host only; the path
+// and query often carry the webhook's secret
+func redactWebhook(webhook string) string {
+	if webhook == "" {
+		return ""
+	}
+	u, err := url.Parse(webhook)
+	if err != nil || u.Host == "" {
+		return "set (unparseable URL)"
+	}
+	return u.Scheme + "://" + u.Hostname() + "/…"
+}
+
+// collectDiagnostics describes the current setup for a bug report: the
+// database target, live connection and schema state, pool stats and the
+// effective configuration
+func collectDiagnostics(m model) []string {
+	lines := []string{"Database: " + redactConnString(m.connString)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	switch {
+	case m.db == nil:
+		lines = append(lines, "Connection: none open")
+	default:
+		start := time.Now()
+		if err := m.db.PingContext(ctx); err != nil {
+			lines = append(lines, fmt.Sprintf("Connection: failed: %v", err))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("Connection: ok (%s)", time.Since(start).Round(time.Millisecond)))
+
+		if version, err := SchemaVersion(ctx, m.db); err != nil {
+			lines = append(lines, fmt.Sprintf("Schema version: unknown: %v", err))
+		} else {
+			lines = append(lines, fmt.Sprintf("Schema version: %d of %d", version, latestSchemaVersion()))
+		}
+
+		tables := make([]string, len(uploadTypes))
+		for i, t := range uploadTypes {
+			tables[i] = t.Table
+		}
+		exists, err := CheckSchema(ctx, m.db, tables)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("Tables: check failed: %v", err))
+			break
+		}
+		var missing []string
+		for _, table := range tables {
+			if !exists[table] {
+				missing = append(missing, table)
+			}
+		}
+		if len(missing) == 0 {
+			lines = append(lines, fmt.Sprintf("Tables: all %d present", len(tables)))
+		} else {
+			lines = append(lines, fmt.Sprintf("Tables: missing %s", strings.Join(missing, ", ")))
+		}
+	}
+	if m.db != nil {
+		lines = append(lines, describePoolStats(m.db.Stats()))
+	}
+
+	lines = append(lines, "", "Configuration:")
+	for _, s := range activeSettings() {
+		value := s.value
+		if value == "" {
+			value = "unset"
+		}
+		line := fmt.Sprintf("  %-24s %s", s.name, value)
+		if s.source != "" {
+			line += "  (" + s.source + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// openDiagnostics shows the diagnostics screen, gathered now
+func openDiagnostics(m model) (tea.Model, tea.Cmd) {
+	m.diagnostics, m.diagnosticsOffset, m.diagnosticsNote = collectDiagnostics(m), 0, ""
+	m.state = stateDiagnostics
+	return m, nil
+}
+
+func updateDiagnostics(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.diagnosticsOffset > 0 {
+				m.diagnosticsOffset--
+			}
+		case "down", "j":
+			if m.diagnosticsOffset < len(m.diagnostics)-diagnosticsPageSize {
+				m.diagnosticsOffset++
+			}
+		case "r":
+			return openDiagnostics(m)
+		case "y":
+			m.diagnosticsNote = "Copied to the clipboard"
+			if err := clipboard.WriteAll(strings.Join(m.diagnostics, "\n")); err != nil {
+				m.diagnosticsNote = fmt.Sprintf("Could not copy: %v", err)
+			}
+		case "q", "esc":
+			m.diagnostics = nil
+			m.state = stateMenu
+		}
+	}
+	return m, nil
+}
+
+func viewDiagnostics(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Diagnostics"))
+	parts = append(parts, "")
+
+	end := min(m.diagnosticsOffset+diagnosticsPageSize, len(m.diagnostics))
+	parts = append(parts, m.diagnostics[m.diagnosticsOffset:end]...)
+
+	parts = append(parts, "")
+	if m.diagnosticsNote != "" {
+		parts = append(parts, m.diagnosticsNote)
+	}
+	parts = append(parts, RenderHelpText("Scroll: ↑/↓ or j/k • Refresh: r • Copy: y • Back: q/esc"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"strings"
+)
+
+// exportListSep separates the names string_agg packs into one column; it can't
+// appear in a name typed into a CSV file
+const exportListSep = "\x1f"
+
+// ExportExercises returns every exercise as the row that would upload it,
+// ordered by name, so an export re-imports to the same exercises
+func ExportExercises(db *sql.DB) ([]ExerciseUploadRow, error) {
+	list := func(junction, column, table string) string {
+		return `COALESCE((SELECT string_agg(r.name, E'\x1f' ORDER BY r.name) FROM ` + junction + ` j
+			JOIN ` + table + ` r ON r.id = j.` + column + ` WHERE j.exercise_id = e.id), '')`
+	}
+	query := `SELECT e.name, COALESCE(e.description, ''), COALESCE(c.name, ''),
+		` + list("exercise_equipment", "equipment_id", "equipment") + `,
+		` + list("exercise_training_types", "training_type_id", "training_type") + `,
+		` + list("exercise_muscles", "muscle_group_id", "muscle_group") + `,
+		` + list("exercise_tags", "tag_id", "tags") + `,
+		COALESCE(p.name, ''), COALESCE(e.default_scheme, '')
+		FROM exercise e
+		LEFT JOIN exercise_category c ON c.id = e.category_id
+		LEFT JOIN exercise p ON p.id = e.parent_id
+		ORDER BY e.name`
+	logSQL(query)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, exportListSep)
+	}
+	var out []ExerciseUploadRow
+	for rows.Next() {
+		var r ExerciseUploadRow
+		var equipment, types, muscles, tags string
+		if err := rows.Scan(&r.Name, &r.Description, &r.Category, &equipment, &types, &muscles, &tags, &r.VariationOf, &r.DefaultScheme); err != nil {
+			return nil, err
+		}
+		r.Equipment, r.Types, r.Muscles, r.Tags = split(equipment), split(types), split(muscles), split(tags)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// encodeExercisesCSV renders rows as an exercise CSV with a header in
+// exerciseHeader order, list columns joined by ; as the parser expects
+func encodeExercisesCSV(rows []ExerciseUploadRow) (string, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	header := make([]string, len(exerciseHeader))
+	for i, col := range exerciseHeader {
+		header[i] = strings.TrimSuffix(col, "?")
+	}
+	if err := w.Write(header); err != nil {
+		return "", err
+	}
+	for _, r := range rows {
+		record := []string{r.Name, r.Description, r.Category, joinList(r.Equipment), joinList(r.Types),
+			joinList(r.Muscles), joinList(r.Tags), r.VariationOf, r.DefaultScheme}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// joinList joins the values of a list column with ;, quoting a value that
+// holds one so SplitAndTrim keeps it whole
+func joinList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		if strings.Contains(v, ";") {
+			v = `"` + v + `"`
+		}
+		quoted[i] = v
+	}
+	return strings.Join(quoted, ";")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+// fakeResult is the answer a fakeDB gives one statement
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+}
+
+// respondFunc answers a statement run against a fakeDB. Returning the zero
+// fakeResult means no rows for a query and one affected row for an exec.
+type respondFunc func(query string, args []driver.Value) fakeResult
+
+// openFakeDB returns a *sql.DB whose statements are answered by respond and
+// recorded, in order, like OpenRecording
+func openFakeDB(t *testing.T, respond respondFunc) (*sql.DB, *sqlRecording) {
+	t.Helper()
+	rec := &sqlRecording{}
+	db := sql.OpenDB(fakeConnector{rec: rec, respond: respond})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, rec
+}
+
+// returningID answers the GetOrInsert* upserts with id "table:name", reporting
+// each name as newly inserted the first time it is seen
+func returningID() respondFunc {
+	seen := map[string]bool{}
+	return func(query string, args []driver.Value) fakeResult {
+		if strings.Contains(query, "to_regclass") {
+			// Tables added by migrations don't exist
+			return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{false}}}
+		}
+		if !strings.Contains(query, "RETURNING id") || len(args) == 0 {
+			return fakeResult{}
+		}
+		fields := strings.Fields(query)
+		id := fields[2] + ":" + args[0].(string)
+		inserted := !seen[id]
+		seen[id] = true
+		if strings.Contains(query, "(xmax = 0)") {
+			return fakeResult{columns: []string{"id", "inserted"}, rows: [][]driver.Value{{id, inserted}}}
+		}
+		return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{id}}}
+	}
+}
+
+// statementsMatching returns the recorded statements containing fragment
+func statementsMatching(rec *sqlRecording, fragment string) []sqlStatement {
+	var out []sqlStatement
+	for _, s := range rec.Statements() {
+		if strings.Contains(s.Query, fragment) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+type fakeConnector struct {
+	rec     *sqlRecording
+	respond respondFunc
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{fakeConnector: c}, nil
+}
+
+func (fakeConnector) Driver() driver.Driver { return offlineDriver{} }
+
+type fakeConn struct {
+	fakeConnector
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.rec.record("BEGIN", nil)
+	return offlineTx{rec: c.rec}, nil
+}
+
+func (c *fakeConn) answer(query string, args []driver.NamedValue) fakeResult {
+	c.rec.record(query, args)
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	if c.respond == nil {
+		return fakeResult{}
+	}
+	return c.respond(query, values)
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r := c.answer(query, args)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.columns != nil {
+		return driver.RowsAffected(len(r.rows)), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r := c.answer(query, args)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &offlineRows{columns: r.columns, values: r.rows}, nil
+}
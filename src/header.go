@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// HeaderReport compares a CSV header with the canonical one for an upload type
+type HeaderReport struct {
+	Missing    []string // required columns not in the file
+	Extra      []string // columns the upload type doesn't know
+	Misordered bool     // known columns are present but in the wrong order
+	Suggested  string   // corrected header line
+}
+
+// OK reports whether the header needs no changes
+func (r HeaderReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && !r.Misordered
+}
+
+func (r HeaderReport) String() string {
+	if r.OK() {
+		return "Header matches the expected columns."
+	}
+	var lines []string
+	if len(r.Missing) > 0 {
+		lines = append(lines, "Missing: "+strings.Join(r.Missing, ", "))
+	}
+	if len(r.Extra) > 0 {
+		lines = append(lines, "Extra: "+strings.Join(r.Extra, ", "))
+	}
+	if r.Misordered {
+		lines = append(lines, "Columns are out of order")
+	}
+	lines = append(lines, "Suggested header:", "  "+r.Suggested)
+	return strings.Join(lines, "\n")
+}
+
+// CheckHeader reads the first row of the CSV at path and compares it with
+// expected, ignoring case and surrounding spaces. Expected columns ending in
+// "?" are optional and only reported when out of order.
+func CheckHeader(path string, expected []string) (HeaderReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return HeaderReport{}, err
+	}
+	defer f.Close()
+
+	header, err := csv.NewReader(f).Read()
+	if err != nil {
+		return HeaderReport{}, fmt.Errorf("read header: %w", err)
+	}
+	cleanRecord(header)
+
+	present := make(map[string]int, len(header))
+	for i, col := range header {
+		present[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+
+	var report HeaderReport
+	var suggested []string
+	known := make(map[string]bool, len(expected))
+	last := -1
+	for _, col := range expected {
+		name, optional := strings.CutSuffix(col, "?")
+		known[strings.ToLower(name)] = true
+
+		i, ok := present[strings.ToLower(name)]
+		if !ok {
+			if !optional {
+				report.Missing = append(report.Missing, name)
+				suggested = append(suggested, name)
+			}
+			continue
+		}
+		if i < last {
+			report.Misordered = true
+		}
+		last = i
+		suggested = append(suggested, name)
+	}
+
+	for _, col := range header {
+		if !known[strings.ToLower(strings.TrimSpace(col))] {
+			report.Extra = append(report.Extra, col)
+		}
+	}
+	report.Suggested = strings.Join(suggested, ",")
+	return report, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyBinding is one entry in the help overlay
+type keyBinding struct {
+	keys   string
+	action string
+}
+
+// helpSection lists the keybindings available in one screen
+type helpSection struct {
+	title    string
+	state    appState
+	bindings []keyBinding
+}
+
+// helpSections is the keybinding reference shown by the ? overlay. Keep it in
+// step with the key handling when adding keys.
+var helpSections = []helpSection{
+	{"Main menu", stateMenu, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"1-9", "jump to the numbered option"},
+		{"enter", "select upload type"},
+		{"r", "refresh counts, or reconnect when the connection is lost"},
+		{"esc/c", "cancel a running count refresh"},
+		{"p", "toggle counts as percentages"},
+		{"v", "upload clipboard contents as the selected type"},
+		{"u", "upload from a URL, including Google Sheets links"},
+		{"/", "run a read-only query"},
+		{"b", "browse the selected reference table"},
+		{"m", "merge near-duplicate entries of the selected reference table"},
+		{"d", "open the dashboard"},
+		{"a", "show import history"},
+		{"i", "show diagnostics: configuration, connection and schema state"},
+		{"?", "show this help"},
+		{"ctrl+r", "reconnect to the database"},
+		{"q", "quit"},
+	}},
+	{"File selector", stateFileSelector, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"enter", "upload the selected file, or every marked file as a batch; open an archive"},
+		{"space", "mark or unmark the file for a batch upload"},
+		{"m", "only show files modified since the last run"},
+		{".", "show or hide dotfiles and editor swap, backup and lock files"},
+		{"h", "check the CSV header against the expected columns"},
+		{"t", "cycle CSV header handling: auto, has header, no header"},
+		{"s", "sync the table with the file, after a dry run"},
+		{"p", "show the SQL uploading the file would run, without running it"},
+		{"a", "add the links in an exercises file to existing exercises, without updating them"},
+		{"q/esc", "back to menu"},
+	}},
+	{"Custom table", stateCustomTarget, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"enter", "pick the table and name column"},
+		{"q/esc", "back to menu"},
+	}},
+	{"Junction table", stateJunctionTarget, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"enter", "pick the junction table, then a CSV of leftName,rightName pairs"},
+		{"q/esc", "back to menu"},
+	}},
+	{"Exercises preview", statePreview, []keyBinding{
+		{"↑/↓ j/k", "scroll"},
+		{"enter", "upload, once no rows are invalid"},
+		{"c", "pick which categories to upload"},
+		{"q/esc", "cancel"},
+	}},
+	{"Browse", stateBrowse, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"enter", "count exercises referencing the selected entry"},
+		{"/", "filter by name"},
+		{"y", "copy the visible names to the clipboard as CSV"},
+		{"x", "delete the selected entry, after a dry run"},
+		{"X", "delete every entry in the table, after a dry run"},
+		{"s", "show or hide soft-deleted entries"},
+		{"r", "restore the selected soft-deleted entry"},
+		{"q/esc", "back to menu"},
+	}},
+	{"Possible duplicates", stateSimilarReview, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"space/x", "merge the name into the existing one instead of inserting"},
+		{"enter", "upload the kept names"},
+		{"q/esc", "cancel"},
+	}},
+	{"Merge duplicates", stateMerge, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"enter", "merge the left entry into the right one, after confirming"},
+		{"s", "swap which entry is kept"},
+		{"q/esc", "back to menu"},
+	}},
+	{"Dashboard", stateDashboard, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"enter", "upload into the selected table"},
+		{"r", "refresh, or reconnect when the connection is lost"},
+		{"a", "show import history"},
+		{"d/esc", "back to menu"},
+		{"q", "quit"},
+	}},
+	{"Category filter", stateCategoryFilter, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"space/x", "toggle the category"},
+		{"a", "clear the filter"},
+		{"enter/esc", "back to the preview"},
+	}},
+	{"Unknown reference", stateResolveRef, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"enter", "take the selected choice"},
+		{"c", "create it"},
+		{"s", "skip it"},
+		{"b", "back to the previous reference"},
+		{"q/esc", "cancel the upload"},
+	}},
+	{"Archive", stateArchive, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"space/x", "mark or unmark the file"},
+		{"t", "change the file's upload type"},
+		{"enter", "upload the marked files"},
+		{"q/esc", "back to the file selector"},
+	}},
+	{"Clipboard format", stateClipboardFormat, []keyBinding{
+		{"↑/↓ j/k", "move"},
+		{"enter", "parse the clipboard in this format"},
+		{"q/esc", "back to menu"},
+	}},
+	{"Query", stateQuery, []keyBinding{
+		{"enter", "run the query"},
+		{"↑/↓", "scroll results"},
+		{"esc", "back to menu"},
+	}},
+	{"Import history", stateAudit, []keyBinding{
+		{"↑/↓ j/k", "scroll"},
+		{"q/esc", "back to menu"},
+	}},
+	{"Diagnostics", stateDiagnostics, []keyBinding{
+		{"↑/↓ j/k", "scroll"},
+		{"r", "gather again"},
+		{"y", "copy to the clipboard, e.g. for a bug report"},
+		{"q/esc", "back to menu"},
+	}},
+	{"Prompts", stateResumePrompt, []keyBinding{
+		{"y", "resume an interrupted upload, or upload despite a type mismatch"},
+		{"n", "start over, or pick another file"},
+	}},
+	{"Sync", stateConfirmSync, []keyBinding{
+		{"y", "add new rows and delete rows missing from the file"},
+		{"a", "only add new rows"},
+		{"n/esc", "cancel"},
+	}},
+	{"SQL preview", stateSQLPreview, []keyBinding{
+		{"↑/↓ j/k", "scroll"},
+		{"pgup/pgdown", "scroll a page"},
+		{"q/esc", "back to the file selector"},
+	}},
+	{"Batch upload", stateBatchProgress, []keyBinding{
+		{"e", "save an HTML report of the batch, once it finishes"},
+		{"enter/q/esc", "back to menu, once it finishes"},
+	}},
+	{"Confirm upload", stateConfirmUpload, []keyBinding{
+		{"↑/↓ j/k", "scroll the names, marked new or already existing"},
+		{"y/enter", "upload the names"},
+		{"n/q/esc", "cancel, back to menu"},
+	}},
+	{"Result", stateResult, []keyBinding{
+		{"y", "append newly created reference names to the data files"},
+		{"w", "write names skipped as already existing to a CSV file"},
+		{"e", "save an HTML report of the uploads"},
+		{"enter/q/esc", "back to menu"},
+	}},
+}
+
+// openHelp shows the help overlay, remembering the current state to return to.
+// Text input screens are excluded because ? is valid input there.
+func openHelp(m model) (model, bool) {
+	if m.state == stateHelp || m.state == stateQuery || m.state == stateURLInput || m.state == stateUploading || m.browseFiltering {
+		return m, false
+	}
+	m.helpReturn = m.state
+	m.state = stateHelp
+	return m, true
+}
+
+func updateHelp(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m.state = m.helpReturn
+	}
+	return m, nil
+}
+
+func viewHelp(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Keybindings"))
+
+	for _, section := range helpSections {
+		title := section.title
+		if section.state == m.helpReturn {
+			title += " (current)"
+		}
+		parts = append(parts, "", QueryHeaderStyle.Render(title))
+		for _, b := range section.bindings {
+			parts = append(parts, fmt.Sprintf("  %s  %s", CursorStyle.Render(fmt.Sprintf("%-12s", b.keys)), b.action))
+		}
+	}
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("Press any key to close"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NameParent places a name under another name of the same table, such as
+// Upper Chest under Chest
+type NameParent struct {
+	Name   string
+	Parent string
+}
+
+// parseMuscleParents reads the optional Parent column of a muscle groups
+// file: a CSV header column named Parent, or a "parent" field on JSON and
+// YAML records. Files without one have no parents.
+func parseMuscleParents(ext string, data []byte) ([]NameParent, error) {
+	var parents []NameParent
+	add := func(name, parent string) {
+		name, parent = strings.TrimSpace(name), strings.TrimSpace(parent)
+		if name != "" && parent != "" {
+			parents = append(parents, NameParent{Name: name, Parent: parent})
+		}
+	}
+
+	switch ext {
+	case ".csv":
+		if csvHeader == headerAbsent {
+			return nil, nil
+		}
+		records, err := readCSVRecords(csv.NewReader(bytes.NewReader(data)))
+		if err != nil || len(records) == 0 {
+			return nil, err
+		}
+		nameCol, parentCol := 0, -1
+		for i, col := range records[0] {
+			switch strings.ToLower(strings.TrimSpace(col)) {
+			case "name":
+				nameCol = i
+			case "parent":
+				parentCol = i
+			}
+		}
+		if parentCol < 0 {
+			return nil, nil
+		}
+		for _, rec := range records[1:] {
+			if nameCol < len(rec) && parentCol < len(rec) {
+				add(rec[nameCol], rec[parentCol])
+			}
+		}
+	case ".json", ".yaml", ".yml":
+		var arr []map[string]any
+		var err error
+		if ext == ".json" {
+			err = json.Unmarshal(normalizeInput(data), &arr)
+		} else {
+			err = yaml.Unmarshal(normalizeInput(data), &arr)
+		}
+		if err != nil {
+			// The names parser has already reported it
+			return nil, nil
+		}
+		for _, obj := range arr {
+			name, _ := obj["name"].(string)
+			parent, _ := obj["parent"].(string)
+			add(name, parent)
+		}
+	}
+	return parents, nil
+}
+
+// setParents points each uploaded name's parent_id at its parent, in a pass
+// after every name is inserted so a child may come before its parent in the
+// file. Only names in uploaded are touched. Parents that don't exist, or that
+// would make a name its own ancestor, are returned as "child → parent".
+func setParents(tx *sql.Tx, table string, parents []NameParent, uploaded []string) (unresolved []string, err error) {
+	inUpload := make(map[string]bool, len(uploaded))
+	for _, name := range uploaded {
+		inUpload[name] = true
+	}
+
+	lookup := fmt.Sprintf(`SELECT id FROM %s WHERE name = $1`, table)
+	// The child must not already be among the parent's ancestors. UNION stops
+	// on a loop already in the table.
+	cycle := fmt.Sprintf(`WITH RECURSIVE up AS (
+			SELECT id, parent_id FROM %s WHERE name = $1
+			UNION
+			SELECT t.id, t.parent_id FROM %s t JOIN up ON t.id = up.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM up WHERE id = $2)`, table, table)
+	update := fmt.Sprintf(`UPDATE %s SET parent_id = $1 WHERE name = $2`, table)
+
+	for _, p := range parents {
+		if !inUpload[p.Name] {
+			continue
+		}
+		var childID, parentID string
+		logSQL(lookup, p.Parent)
+		err := tx.QueryRow(lookup, p.Parent).Scan(&parentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			unresolved = append(unresolved, p.Name+" → "+p.Parent)
+			continue
+		}
+		if err != nil {
+			return unresolved, fmt.Errorf("%s: parent: %w", p.Name, err)
+		}
+		logSQL(lookup, p.Name)
+		if err := tx.QueryRow(lookup, p.Name).Scan(&childID); err != nil {
+			return unresolved, fmt.Errorf("%s: %w", p.Name, err)
+		}
+
+		var loops bool
+		logSQL(cycle, p.Parent, childID)
+		if err := tx.QueryRow(cycle, p.Parent, childID).Scan(&loops); err != nil {
+			return unresolved, fmt.Errorf("%s: check for a cycle: %w", p.Name, err)
+		}
+		if loops {
+			unresolved = append(unresolved, p.Name+" → "+p.Parent+" (cycle)")
+			continue
+		}
+
+		logSQL(update, parentID, p.Name)
+		if _, err := tx.Exec(update, parentID, p.Name); err != nil {
+			return unresolved, fmt.Errorf("%s: set parent: %w", p.Name, err)
+		}
+	}
+	return unresolved, nil
+}
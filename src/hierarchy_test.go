@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseMuscleParents(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		data string
+		want []NameParent
+	}{
+		{
+			name: "csv, children before parents",
+			ext:  ".csv",
+			data: "Name,Parent\nUpper Chest,Chest\nChest,Torso\nTorso,\n",
+			want: []NameParent{{"Upper Chest", "Chest"}, {"Chest", "Torso"}},
+		},
+		{
+			name: "csv columns in any order",
+			ext:  ".csv",
+			data: "parent , name\nChest,Lower Chest\n",
+			want: []NameParent{{"Lower Chest", "Chest"}},
+		},
+		{
+			name: "csv without a parent column",
+			ext:  ".csv",
+			data: "Name\nChest\n",
+		},
+		{
+			name: "json",
+			ext:  ".json",
+			data: `[{"name": "Upper Chest", "parent": "Chest"}, {"name": "Chest"}]`,
+			want: []NameParent{{"Upper Chest", "Chest"}},
+		},
+		{
+			name: "yaml",
+			ext:  ".yaml",
+			data: "- name: Upper Chest\n  parent: Chest\n- name: Chest\n",
+			want: []NameParent{{"Upper Chest", "Chest"}},
+		},
+		{
+			name: "malformed json is left to the names parser",
+			ext:  ".json",
+			data: `[{"name": `,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMuscleParents(tt.ext, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// muscleTree is an in-memory muscle_group table answering setParents'
+// statements: an id per name and each id's parent_id
+type muscleTree struct {
+	ids    map[string]string
+	parent map[string]string
+}
+
+func newMuscleTree(names ...string) *muscleTree {
+	tree := &muscleTree{ids: map[string]string{}, parent: map[string]string{}}
+	for _, name := range names {
+		tree.ids[name] = "id:" + name
+	}
+	return tree
+}
+
+func (tree *muscleTree) respond(query string, args []driver.Value) fakeResult {
+	switch {
+	case strings.Contains(query, "WITH RECURSIVE"):
+		// Is $2 among the ancestors of $1, $1 included?
+		found := false
+		seen := map[string]bool{}
+		for id, ok := tree.ids[args[0].(string)]; ok && !seen[id]; id, ok = tree.parent[id] {
+			seen[id] = true
+			found = found || id == args[1]
+		}
+		return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{found}}}
+	case strings.HasPrefix(query, "SELECT id FROM muscle_group"):
+		if id, ok := tree.ids[args[0].(string)]; ok {
+			return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{id}}}
+		}
+		return fakeResult{}
+	case strings.HasPrefix(query, "UPDATE muscle_group SET parent_id"):
+		tree.parent[tree.ids[args[1].(string)]] = args[0].(string)
+	}
+	return fakeResult{}
+}
+
+// setTreeParents runs setParents for every name in tree
+func setTreeParents(t *testing.T, tree *muscleTree, parents []NameParent) []string {
+	t.Helper()
+	db, _ := openFakeDB(t, tree.respond)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var uploaded []string
+	for name := range tree.ids {
+		uploaded = append(uploaded, name)
+	}
+	unresolved, err := setParents(tx, "muscle_group", parents, uploaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unresolved
+}
+
+func TestSetParentsTwoLevelsOutOfOrder(t *testing.T) {
+	tree := newMuscleTree("Upper Chest", "Lower Chest", "Chest", "Torso")
+	parents := []NameParent{{"Upper Chest", "Chest"}, {"Lower Chest", "Chest"}, {"Chest", "Torso"}}
+
+	if unresolved := setTreeParents(t, tree, parents); len(unresolved) != 0 {
+		t.Fatalf("unresolved = %q, want none", unresolved)
+	}
+	want := map[string]string{"id:Upper Chest": "id:Chest", "id:Lower Chest": "id:Chest", "id:Chest": "id:Torso"}
+	for child, parent := range want {
+		if tree.parent[child] != parent {
+			t.Errorf("parent of %s = %q, want %q", child, tree.parent[child], parent)
+		}
+	}
+}
+
+func TestSetParentsUnresolved(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		parents []NameParent
+		want    []string
+	}{
+		{"missing parent", []string{"Upper Chest"}, []NameParent{{"Upper Chest", "Chest"}},
+			[]string{"Upper Chest → Chest"}},
+		{"own parent", []string{"Chest"}, []NameParent{{"Chest", "Chest"}},
+			[]string{"Chest → Chest (cycle)"}},
+		{"two-name cycle", []string{"Chest", "Upper Chest"}, []NameParent{{"Upper Chest", "Chest"}, {"Chest", "Upper Chest"}},
+			[]string{"Chest → Upper Chest (cycle)"}},
+		{"three-name cycle", []string{"A", "B", "C"}, []NameParent{{"A", "B"}, {"B", "C"}, {"C", "A"}},
+			[]string{"C → A (cycle)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newMuscleTree(tt.names...)
+			if got := setTreeParents(t, tree, tt.parents); !slices.Equal(got, tt.want) {
+				t.Errorf("unresolved = %q, want %q", got, tt.want)
+			}
+			for child, parent := range tree.parent {
+				if child == parent {
+					t.Errorf("%s was made its own parent", child)
+				}
+			}
+		})
+	}
+}
+
+func TestSetParentsOnlyTouchesUploadedNames(t *testing.T) {
+	tree := newMuscleTree("Chest", "Upper Chest")
+	db, rec := openFakeDB(t, tree.respond)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := setParents(tx, "muscle_group", []NameParent{{"Upper Chest", "Chest"}}, []string{"Chest"}); err != nil {
+		t.Fatal(err)
+	}
+	if updates := statementsMatching(rec, "UPDATE"); len(updates) != 0 {
+		t.Errorf("updated a name outside the upload: %v", updates)
+	}
+}
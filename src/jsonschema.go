@@ -0,0 +1,191 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateSchema checks JSON and YAML uploads against recordsSchema before
+// parsing them, instead of quietly skipping records without a name
+// (--validate-schema)
+var validateSchema bool
+
+// recordsSchemaJSON describes a JSON or YAML upload. Only the keywords
+// jsonSchema implements may be used in it.
+//
+//go:embed schemas/records.schema.json
+var recordsSchemaJSON []byte
+
+// schemaViolationLimit caps how many violations an error lists
+const schemaViolationLimit = 10
+
+// jsonSchema is the subset of JSON Schema the records schema needs: type,
+// required, properties, additionalProperties, items, minLength and minItems
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinLength            *int                   `json:"minLength"`
+	MinItems             *int                   `json:"minItems"`
+}
+
+// schemaTypes is a schema's type keyword, which may be a name or a list
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// SchemaViolation is one constraint a value breaks, at a JSON Pointer path
+// such as /3/name
+type SchemaViolation struct {
+	Path   string
+	Reason string
+}
+
+func (v SchemaViolation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Reason
+}
+
+// SchemaError lists every schema violation found in an upload
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	lines := []string{fmt.Sprintf("the file breaks the records schema in %d places:", len(e.Violations))}
+	for i, v := range e.Violations {
+		if i == schemaViolationLimit {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(e.Violations)-i))
+			break
+		}
+		lines = append(lines, "  "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkRecordsSchema validates a decoded JSON or YAML document against the
+// records schema, returning a *SchemaError listing what it breaks
+func checkRecordsSchema(doc any) error {
+	var schema jsonSchema
+	if err := json.Unmarshal(recordsSchemaJSON, &schema); err != nil {
+		return fmt.Errorf("embedded records schema: %w", err)
+	}
+	var violations []SchemaViolation
+	schema.validate("", doc, &violations)
+	if len(violations) > 0 {
+		return &SchemaError{Violations: violations}
+	}
+	return nil
+}
+
+// validate appends the violations of value, found at path, to out
+func (s *jsonSchema) validate(path string, value any, out *[]SchemaViolation) {
+	fail := func(format string, args ...any) {
+		*out = append(*out, SchemaViolation{Path: path, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.Type.match(value) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), schemaTypeOf(value))
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.MinLength != nil && utf8.RuneCountInString(v) < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s/%d", path, i), item, out)
+			}
+		}
+	case map[string]any:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				fail("missing required property %q", key)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if prop, ok := s.Properties[key]; ok {
+				prop.validate(path+"/"+escapePointer(key), v[key], out)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				fail("unknown property %q", key)
+			}
+		}
+	case map[any]any:
+		// yaml.v3 decodes a mapping with a non-string key, such as 1:, this
+		// way; JSON Schema only knows string keys
+		object := make(map[string]any, len(v))
+		for key, item := range v {
+			object[fmt.Sprint(key)] = item
+		}
+		s.validate(path, object, out)
+	}
+}
+
+// match reports whether value is one of the types
+func (t schemaTypes) match(value any) bool {
+	actual := schemaTypeOf(value)
+	for _, want := range t {
+		if want == actual || (want == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaTypeOf names the JSON type of a value decoded by encoding/json or
+// yaml.v3
+func schemaTypeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any, map[any]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// escapePointer escapes a property name for use in a JSON Pointer
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
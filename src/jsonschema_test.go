@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheckRecordsSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		yaml string
+		want []string // violations as "path: reason"
+	}{
+		{
+			name: "valid names and exercise fields",
+			json: `[{"name": "Biceps"}, {"name": "Curl", "muscles": "Biceps", "equipment": ["Dumbbell", "Cable"]}]`,
+		},
+		{
+			name: "missing name",
+			json: `[{"name": "Biceps"}, {"description": "no name"}]`,
+			want: []string{`/1: missing required property "name"`},
+		},
+		{
+			name: "empty name",
+			json: `[{"name": ""}]`,
+			want: []string{"/0/name: must be at least 1 characters"},
+		},
+		{
+			name: "wrong types",
+			json: `[{"name": 5}, {"name": "Curl", "category": true, "variation_of": null}, "Biceps"]`,
+			want: []string{
+				"/0/name: expected string, got integer",
+				"/1/category: expected string, got boolean",
+				"/1/variation_of: expected string, got null",
+				"/2: expected object, got string",
+			},
+		},
+		{
+			name: "not a list",
+			json: `{"name": "Biceps"}`,
+			want: []string{"/: expected array, got object"},
+		},
+		{
+			name: "string-or-array field",
+			json: `[{"name": "Curl", "tags": 3}, {"name": "Row", "muscles": ["Lats", 2.5]}]`,
+			want: []string{
+				"/0/tags: expected string or array, got integer",
+				"/1/muscles/1: expected string, got number",
+			},
+		},
+		{
+			name: "YAML integer key",
+			yaml: "- name: Biceps\n  1: extra\n- 2: no name\n",
+			want: []string{`/1: missing required property "name"`},
+		},
+		{
+			name: "YAML integer name",
+			yaml: "- name: 5\n",
+			want: []string{"/0/name: expected string, got integer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			var err error
+			if tt.yaml != "" {
+				err = yaml.Unmarshal([]byte(tt.yaml), &doc)
+			} else {
+				err = json.Unmarshal([]byte(tt.json), &doc)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			var schemaErr *SchemaError
+			if err := checkRecordsSchema(doc); errors.As(err, &schemaErr) {
+				for _, v := range schemaErr.Violations {
+					got = append(got, v.String())
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("violations:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+			}
+		})
+	}
+}
+
+func TestParseYAMLReaderIntegerKeys(t *testing.T) {
+	validateSchema = true
+	t.Cleanup(func() { validateSchema = false })
+	names, seen, err := ParseYAMLReader(strings.NewReader("- name: Biceps\n  1: extra\n- name: Triceps\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != 2 || !slices.Equal(names, []string{"Biceps", "Triceps"}) {
+		t.Errorf("names %q of %d", names, seen)
+	}
+}
+
+func TestSchemaErrorLimit(t *testing.T) {
+	doc := make([]any, schemaViolationLimit+2)
+	for i := range doc {
+		doc[i] = map[string]any{}
+	}
+	err := checkRecordsSchema(doc)
+	if err == nil {
+		t.Fatal("expected a schema error")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != schemaViolationLimit+2 || lines[len(lines)-1] != "…and 2 more" {
+		t.Errorf("error lists %d lines, ending %q", len(lines), lines[len(lines)-1])
+	}
+}
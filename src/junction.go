@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v5"
+)
+
+// JunctionTable is a two-column relationship table whose columns reference
+// tables that can be looked up by name, e.g. exercise_tags
+type JunctionTable struct {
+	Table       string
+	LeftColumn  string
+	LeftTable   string
+	RightColumn string
+	RightTable  string
+}
+
+func (j JunctionTable) String() string {
+	return fmt.Sprintf("%s (%s ↔ %s)", j.Table, j.LeftTable, j.RightTable)
+}
+
+// ListJunctionTables finds the tables in the current schema with exactly two
+// single-column foreign keys into tables that have a name column. Tables with
+// a name column of their own are entities, not junctions, and are left out.
+func ListJunctionTables(db *sql.DB) ([]JunctionTable, error) {
+	named := make(map[string]bool)
+	query := `SELECT table_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND column_name = 'name'`
+	logSQL(query)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		named[table] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT rel.relname, a.attname, ref.relname
+		FROM pg_constraint c
+		JOIN pg_class rel ON rel.oid = c.conrelid
+		JOIN pg_class ref ON ref.oid = c.confrelid
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+		WHERE c.contype = 'f' AND array_length(c.conkey, 1) = 1
+			AND rel.relnamespace = current_schema()::regnamespace
+		ORDER BY rel.relname, a.attnum`
+	logSQL(query)
+	rows, err = db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type foreignKey struct{ column, refTable string }
+	var order []string
+	keys := make(map[string][]foreignKey)
+	for rows.Next() {
+		var table string
+		var fk foreignKey
+		if err := rows.Scan(&table, &fk.column, &fk.refTable); err != nil {
+			return nil, err
+		}
+		if _, seen := keys[table]; !seen {
+			order = append(order, table)
+		}
+		keys[table] = append(keys[table], fk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var junctions []JunctionTable
+	for _, table := range order {
+		fks := keys[table]
+		if named[table] || len(fks) != 2 || !named[fks[0].refTable] || !named[fks[1].refTable] {
+			continue
+		}
+		junctions = append(junctions, JunctionTable{
+			Table:       table,
+			LeftColumn:  fks[0].column,
+			LeftTable:   fks[0].refTable,
+			RightColumn: fks[1].column,
+			RightTable:  fks[1].refTable,
+		})
+	}
+	return junctions, nil
+}
+
+// JunctionPair is one leftName,rightName row of a junction upload
+type JunctionPair struct {
+	Left  string
+	Right string
+}
+
+// ParseJunctionCSV reads leftName,rightName pairs, skipping a header row that
+// names the two tables or their columns
+func ParseJunctionCSV(j JunctionTable, data []byte) ([]JunctionPair, int, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	records, err := readCSVRecords(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(records) == 0 {
+		return nil, 0, errors.New("no records found")
+	}
+
+	first := records[0]
+	headerLike := func(cell string, names ...string) bool {
+		cell = strings.TrimSpace(cell)
+		for _, name := range names {
+			if strings.EqualFold(cell, name) {
+				return true
+			}
+		}
+		return false
+	}
+	guess := len(first) >= 2 &&
+		headerLike(first[0], j.LeftTable, j.LeftColumn) &&
+		headerLike(first[1], j.RightTable, j.RightColumn)
+	if isHeader(guess) {
+		records = records[1:]
+	}
+
+	var pairs []JunctionPair
+	for _, rec := range records {
+		if len(rec) < 2 {
+			continue
+		}
+		pair := JunctionPair{Left: strings.TrimSpace(rec[0]), Right: strings.TrimSpace(rec[1])}
+		if pair.Left == "" || pair.Right == "" {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, len(records), nil
+}
+
+// GetIDByName returns the id of the row in table with the given name, or
+// sql.ErrNoRows when there is none
+func GetIDByName(tx *sql.Tx, table, name string) (string, error) {
+	var id string
+	query := fmt.Sprintf("SELECT id FROM %s WHERE name = $1", pgx.Identifier{table}.Sanitize())
+	logSQL(query, name)
+	err := tx.QueryRow(query, name).Scan(&id)
+	return id, err
+}
+
+// InsertJunctionPairs resolves both sides of every pair by name and links them
+// in j, all in one transaction. Names that don't resolve fail the whole upload,
+// listing the first few, so nothing is half-linked.
+func InsertJunctionPairs(db *sql.DB, j JunctionTable, pairs []JunctionPair) (inserted int, err error) {
+	tx, err := beginUploadTx(db)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		logTxEnd(tx, j.Table, "", len(pairs), err)
+	}()
+
+	ids := make(map[string]string)
+	var unknown []string
+	resolve := func(table, name string) (string, bool, error) {
+		key := table + "\x1f" + name
+		if id, ok := ids[key]; ok {
+			return id, id != "", nil
+		}
+		id, err := GetIDByName(tx, table, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			ids[key] = ""
+			unknown = append(unknown, fmt.Sprintf("%s %q", table, name))
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("look up %s %q: %w", table, name, err)
+		}
+		ids[key] = id
+		return id, true, nil
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES ($1, $2) ON CONFLICT DO NOTHING RETURNING (xmax = 0)",
+		pgx.Identifier{j.Table}.Sanitize(), pgx.Identifier{j.LeftColumn}.Sanitize(), pgx.Identifier{j.RightColumn}.Sanitize())
+	for _, pair := range pairs {
+		leftID, leftOK, err := resolve(j.LeftTable, pair.Left)
+		if err != nil {
+			return inserted, err
+		}
+		rightID, rightOK, err := resolve(j.RightTable, pair.Right)
+		if err != nil {
+			return inserted, err
+		}
+		name := pair.Left + " → " + pair.Right
+		if !leftOK || !rightOK {
+			logRow(tx, rowEvent{Table: j.Table, Name: name, Outcome: rowFailed, Error: "unknown name"})
+			continue
+		}
+		n, err := countInserted(tx, query, leftID, rightID)
+		if err != nil {
+			logRow(tx, rowEvent{Table: j.Table, Name: name, Outcome: rowFailed, Error: err.Error()})
+			return inserted, fmt.Errorf("link %s: %w", name, err)
+		}
+		inserted += n
+		outcome := rowInserted
+		if n == 0 {
+			outcome = rowSkipped
+		}
+		logRow(tx, rowEvent{Table: j.Table, Name: name, Outcome: outcome})
+	}
+
+	if len(unknown) > 0 {
+		shown := unknown[:min(len(unknown), 5)]
+		more := ""
+		if len(unknown) > len(shown) {
+			more = fmt.Sprintf(" and %d more", len(unknown)-len(shown))
+		}
+		return 0, fmt.Errorf("unknown names: %s%s", strings.Join(shown, ", "), more)
+	}
+	return inserted, nil
+}
+
+// junctionUploadType wraps a junction table as an upload type, so the file
+// selector and upload dispatch handle it like any other
+func junctionUploadType(j JunctionTable) UploadType {
+	return UploadType{
+		Label:  "Upload to " + j.String(),
+		Table:  j.Table,
+		Header: []string{j.LeftTable, j.RightTable},
+		Upload: func(db *sql.DB, ext string, data []byte, source string) (int, int, error) {
+			if ext != ".csv" {
+				return 0, 0, fmt.Errorf("junction uploads take CSV files, got %s", ext)
+			}
+			pairs, seen, err := ParseJunctionCSV(j, data)
+			if err != nil {
+				return 0, seen, err
+			}
+			inserted, err := InsertJunctionPairs(db, j, pairs)
+			return inserted, seen, err
+		},
+	}
+}
+
+func updateJunctionTarget(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.junctionChoice > 0 {
+				m.junctionChoice--
+			}
+		case "down", "j":
+			// The last entry is Back
+			if m.junctionChoice < len(m.junctionTables) {
+				m.junctionChoice++
+			}
+		case "q", "esc":
+			m.state = stateMenu
+			return m, nil
+		case "enter":
+			if m.junctionChoice == len(m.junctionTables) {
+				m.state = stateMenu
+				return m, nil
+			}
+			m.junctionTable = m.junctionTables[m.junctionChoice]
+			return openFileSelector(m)
+		}
+	}
+	return m, nil
+}
+
+func viewJunctionTarget(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Select a junction table:"))
+	parts = append(parts, "")
+
+	if len(m.junctionTables) == 0 {
+		parts = append(parts, RenderHelpText("No two-column junction tables in the current schema"))
+	}
+	for i, j := range m.junctionTables {
+		parts = append(parts, RenderFileItem(j.String(), i == m.junctionChoice, false))
+	}
+	parts = append(parts, RenderFileItem("Back", m.junctionChoice == len(m.junctionTables), true))
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("CSV rows are leftName,rightName • Navigation: ↑/↓ or j/k • Select: enter • Back: q/esc"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
+
+// appendJunctions adds the links in the selected exercises file to exercises
+// that already exist, without re-upserting the exercises
+func appendJunctions(m model) (tea.Model, tea.Cmd) {
+	filename := m.fileList[m.fileChoice]
+	uploadType := m.selectedUploadType()
+	if filename == "Back" {
+		return m, nil
+	}
+	m.state = stateResult
+	if uploadType.Parser != nil || uploadType.Upload != nil || uploadType.Custom != nil {
+		m.resultMsg = "Only an exercises file can add links to existing exercises.\nPress enter or q to return to menu."
+		m.isError = true
+		return m, nil
+	}
+
+	data, _, err := readUploadFile(filepath.Join(dataDir, filename), uploadType)
+	var rows []ExerciseUploadRow
+	var problems []ValidationError
+	var seen int
+	if err == nil {
+		rows, seen, problems, err = ParseExercisesCSVReader(bytes.NewReader(data))
+	}
+	if err != nil {
+		m.resultMsg = fmt.Sprintf("Error parsing file: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+	rows, problems, note := applyExerciseLineRange(rows, problems, seen)
+	note = joinNotes(note, describeMalformedRows(problems))
+
+	start := time.Now()
+	appended, err := AppendExerciseJunctions(m.db, rows)
+	result := UploadResult{Type: "Append exercise links", File: filename, Parsed: len(rows), Inserted: appended.Added,
+		Skipped: len(appended.Missing), Success: err == nil, Duration: time.Since(start), Created: appended.Created}
+	if err != nil {
+		result.Inserted, result.Skipped, result.Created, result.Error = 0, 0, CreatedRefs{}, err.Error()
+	}
+	recordUpload(result)
+
+	if err != nil {
+		m.resultMsg = fmt.Sprintf("Adding links failed, nothing was changed: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, notifyCompletion(true)
+	}
+	m.resultMsg = appended.String()
+	if note != "" {
+		m.resultMsg += "\n" + note
+	}
+	if appended.Created.Total() > 0 {
+		m.createdRefs = appended.Created
+		m.resultMsg += fmt.Sprintf("\n\n%s\nPress y to append them to the data files.", describeCreatedRefs(appended.Created))
+	}
+	m.resultMsg += "\nPress enter or q to return to menu."
+	m.isError = false
+	return m, notifyCompletion(false)
+}
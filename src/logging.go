@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logFile is where diagnostic output goes so it never corrupts the TUI
+const logFile = "fitrkr-cli.log"
+
+var (
+	logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	// debugSQL enables logging of every SQL statement before execution (DEBUG_SQL=1)
+	debugSQL bool
+	// maskSQLArgs replaces statement arguments with *** in the debug log (DEBUG_SQL_MASK=1)
+	maskSQLArgs bool
+)
+
+// InitLogger routes slog output to the log file and reads the debug flags from the environment.
+// The returned function closes the log file.
+func InitLogger() (func(), error) {
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return func() {}, err
+	}
+
+	level := slog.LevelInfo
+	debugSQL = envFlag("DEBUG_SQL")
+	maskSQLArgs = envFlag("DEBUG_SQL_MASK")
+	if debugSQL {
+		level = slog.LevelDebug
+	}
+
+	logger = slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: level}))
+	return func() { f.Close() }, nil
+}
+
+// envFlag reports whether an environment variable is set to a truthy value
+func envFlag(key string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(key))) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// logSQL logs a statement and its arguments when DEBUG_SQL is enabled
+func logSQL(query string, args ...any) {
+	if !debugSQL {
+		return
+	}
+	if maskSQLArgs {
+		masked := make([]any, len(args))
+		for i := range masked {
+			masked[i] = "***"
+		}
+		args = masked
+	}
+	logger.Debug("sql", "query", strings.Join(strings.Fields(query), " "), "args", args)
+}
@@ -1,13 +1,79 @@
 package main
 
 import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	migrate := flag.Bool("migrate", false, "apply schema migrations and exit")
+	promote := flag.Bool("promote", false, "promote rows from the staging schema into the real tables and exit")
+	dumpSchema := flag.String("dump-schema", "", "write CREATE TABLE DDL for the managed tables to this file and exit")
+	resetAll := flag.Bool("reset-all", false, "delete every row of the managed tables, after a dry run and confirmation, and exit")
+	importRelational := flag.String("import-relational", "", "import a relational JSON export with explicit ids and exit")
+	descriptionsDir := flag.String("descriptions-dir", "", "set exercise descriptions from the <Exercise Name>.md files in this directory and exit")
+	flag.BoolVar(&stripMarkdown, "strip-markdown", false, "store --descriptions-dir files as plain text instead of Markdown")
+	offline := flag.Bool("offline", false, "run the TUI against an in-memory fake instead of a database")
+	hasHeader := flag.Bool("has-header", false, "treat the first CSV row as a header")
+	noHeader := flag.Bool("no-header", false, "treat the first CSV row as data")
+	lines := flag.String("lines", "", "upload only this range of data rows after the header, e.g. 1-100 or 500-")
+	parseOnly := flag.Bool("parse-only", false, "parse --file and print the rows as JSON without connecting to the database, then exit")
+	file := flag.String("file", "", "file to upload and exit, without the menu; with --parse-only, only parse it. A .zip/.tar archive uploads every data file inside")
+	upload := flag.String("upload", "", "upload --file as this type (table or menu label, e.g. exercises) and exit; same as --type")
+	format := flag.String("format", "", "with --parse-only, parse --file as this format (csv, json, yaml) instead of going by its extension; uploads always go by the extension")
+	htmlReport := flag.String("html-report", "", "with --file <archive>, also write an HTML report of the uploads to this file")
+	uploadType := flag.String("type", "", "upload type (table or menu label) of --file; guessed from the filename when unset")
+	flag.StringVar(&rowLogPath, "row-log", "", "append a JSON line per uploaded row to this file as uploads run (or ROW_LOG)")
+	flag.BoolVar(&validateSchema, "validate-schema", false, "check JSON and YAML files against the embedded records schema, reporting every violation")
+	flag.BoolVar(&strictColumns, "strict-columns", false, "fail exercise imports on unknown CSV columns instead of ignoring them")
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, cmdFlags, err := lookupSubcommand(flag.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case *hasHeader && *noHeader:
+		log.Fatal("--has-header and --no-header are mutually exclusive")
+	case *hasHeader:
+		csvHeader = headerPresent
+	case *noHeader:
+		csvHeader = headerAbsent
+	}
+
+	if *upload != "" {
+		if *uploadType != "" && *uploadType != *upload {
+			log.Fatal("--upload and --type name different upload types")
+		}
+		if *file == "" {
+			log.Fatal("--upload needs --file")
+		}
+		*uploadType = *upload
+	}
+
+	if *lines != "" {
+		r, err := ParseLineRange(*lines)
+		if err != nil {
+			log.Fatalf("Invalid --lines: %v", err)
+		}
+		uploadLines = r
+	}
+
 	if err := os.Setenv("PGAPPNAME", "fitrkrcli"); err != nil {
 		log.Fatalf("could not set app name: %v", err)
 	}
@@ -16,14 +82,277 @@ func main() {
 		log.Printf("No .env file found: %v", err)
 	}
 
+	failFast = envFlag("FAIL_FAST")
+	updateProvenance = envFlag("UPDATE_PROVENANCE")
+	skipUnchanged = envFlag("SKIP_UNCHANGED")
+	deferConstraints = envFlag("DEFER_CONSTRAINTS")
+	sortBeforeInsert = envFlag("SORT_BEFORE_INSERT")
+	batchFallback = envFlag("BATCH_FALLBACK")
+	resolveRefs = envFlag("RESOLVE_REFS")
+	strictRefs = envFlag("STRICT_REFS")
+	stagingSchema = os.Getenv("STAGING_SCHEMA")
+	notifyBell = envFlag("NOTIFY_BELL")
+	compactMode = envFlag("COMPACT_MODE")
+	notifyCommand = os.Getenv("NOTIFY_COMMAND")
+	notifyWebhook = os.Getenv("NOTIFY_WEBHOOK")
+	defaultUploadType = os.Getenv("DEFAULT_UPLOAD_TYPE")
+	if rowLogPath == "" {
+		rowLogPath = os.Getenv("ROW_LOG")
+	}
+	metricsAddr = os.Getenv("METRICS_ADDR")
+	ConfigureCursor(os.Getenv("CURSOR"), os.Getenv("HIGHLIGHT"))
+	if err := ConfigureColors(os.Getenv("COLOR_PROFILE")); err != nil {
+		log.Fatalf("Invalid COLOR_PROFILE: %v", err)
+	}
+	if envFlag("ACCESSIBLE") {
+		UseAccessibleStyles()
+	}
+	if size := os.Getenv("EXERCISE_COMMIT_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 0 {
+			log.Fatalf("EXERCISE_COMMIT_SIZE must be a non-negative integer, got %q", size)
+		}
+		exerciseCommitSize = n
+	}
+	if threshold := os.Getenv("CONFIRM_THRESHOLD"); threshold != "" {
+		n, err := strconv.Atoi(threshold)
+		if err != nil || n < 0 {
+			log.Fatalf("CONFIRM_THRESHOLD must be a row count of 0 or more, got %q", threshold)
+		}
+		confirmThreshold = n
+	}
+	if timeout := os.Getenv("MIGRATION_LOCK_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			log.Fatalf("MIGRATION_LOCK_TIMEOUT must be a positive duration such as 30s, got %q", timeout)
+		}
+		migrationLockTimeout = d
+	}
+	keys, err := ParsePrimaryKeys(os.Getenv("PRIMARY_KEYS"))
+	if err != nil {
+		log.Fatalf("Invalid PRIMARY_KEYS: %v", err)
+	}
+	primaryKeys = keys
+	isolation, err := ParseIsolationLevel(os.Getenv("ISOLATION_LEVEL"))
+	if err != nil {
+		log.Fatalf("Invalid ISOLATION_LEVEL: %v", err)
+	}
+	uploadIsolation = isolation
+	if threshold := os.Getenv("SIMILARITY_THRESHOLD"); threshold != "" {
+		t, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || t < 0 || t > 1 {
+			log.Fatalf("SIMILARITY_THRESHOLD must be between 0 and 1, got %q", threshold)
+		}
+		similarityThreshold = t
+	}
+	if mapping := os.Getenv("SQLITE_MAPPING"); mapping != "" {
+		sqliteMapping, err = ParseSQLiteMapping(mapping)
+		if err != nil {
+			log.Fatalf("Invalid SQLITE_MAPPING: %v", err)
+		}
+	}
+	if values := os.Getenv("NO_EQUIPMENT"); values != "" {
+		noEquipment = SplitAndTrim(values, ",")
+	}
+	if tables := os.Getenv("COUNT_TABLES"); tables != "" {
+		countTables = SplitAndTrim(tables, ",")
+	}
+	if transforms := os.Getenv("COLUMN_TRANSFORMS"); transforms != "" {
+		columnTransforms, err = ParseColumnTransforms(transforms)
+		if err != nil {
+			log.Fatalf("Invalid COLUMN_TRANSFORMS: %v", err)
+		}
+	}
+	if cols := os.Getenv("NAME_COLUMNS"); cols != "" {
+		nameColumns = SplitAndTrim(cols, ",")
+	}
+
+	closeLog, err := InitLogger()
+	if err != nil {
+		log.Printf("could not open log file: %v", err)
+	}
+	defer closeLog()
+
+	if *parseOnly {
+		if *file == "" {
+			log.Fatal("--parse-only needs --file")
+		}
+		if err := ParseOnly(os.Stdout, *file, *format, *uploadType); err != nil {
+			log.Fatalf("Parse failed: %v", err)
+		}
+		return
+	}
+
+	offlineMode = *offline || envFlag("OFFLINE")
+	if offlineMode {
+		db := OpenOffline()
+		currentDB.Store(db)
+		defer closeCurrentDB()
+		defer serveMetrics(currentDB.Load)()
+		if cmd != nil {
+			runSubcommand(db, cmd, cmdFlags)
+			return
+		}
+		if *file != "" && !isArchive(*file) {
+			runFileUpload(db, *file, *uploadType)
+			return
+		}
+		exitWithSession(InitMenu(db, ""))
+		return
+	}
+
 	dbConn := os.Getenv("DB_CONN_STRING")
 	if dbConn == "" {
 		log.Fatal("DB_CONN_STRING environment variable is required")
 	}
 	log.Println("dbConn: ", dbConn)
 
-	db := NewConnection(dbConn)
-	defer db.Close()
+	connString := expandConnString(dbConn)
+	if err := validateConnString(connString); err != nil {
+		log.Fatalf("Invalid DB_CONN_STRING: %v", err)
+	}
+	db := NewConnection(connString)
+	currentDB.Store(db)
+	defer closeCurrentDB()
+	defer serveMetrics(currentDB.Load)()
+
+	if cmd != nil {
+		runSubcommand(db, cmd, cmdFlags)
+		return
+	}
+
+	if *migrate {
+		if err := runMigrations(db); err != nil {
+			log.Fatalf("Migrate: %v", err)
+		}
+		return
+	}
+
+	if *dumpSchema != "" {
+		if err := DumpSchemaDDL(db, *dumpSchema); err != nil {
+			log.Fatalf("Schema dump failed: %v", err)
+		}
+		fmt.Printf("Wrote schema to %s\n", *dumpSchema)
+		return
+	}
+
+	if *resetAll {
+		impacts, err := ResetAll(db, true)
+		if err != nil {
+			log.Fatalf("Reset would fail, nothing was changed: %v", err)
+		}
+		fmt.Printf("Dry run:\n%s\n\nType RESET to delete all of this: ", describeImpacts(impacts))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != "RESET" {
+			fmt.Println("Aborted, nothing was changed")
+			return
+		}
+		if _, err := ResetAll(db, false); err != nil {
+			log.Fatalf("Reset failed, all changes rolled back: %v", err)
+		}
+		fmt.Println("All managed tables cleared")
+		return
+	}
+
+	if *importRelational != "" {
+		skipped, err := ImportRelationalJSON(db, *importRelational)
+		if err != nil {
+			log.Fatalf("Relational import failed, all changes rolled back: %v", err)
+		}
+		fmt.Printf("Imported %s\n", *importRelational)
+		if len(skipped) > 0 {
+			fmt.Printf("Skipped %d rows that already existed:\n", len(skipped))
+			for _, line := range skipped {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+		return
+	}
+
+	if *descriptionsDir != "" {
+		report, err := updateDescriptionsFromDir(db, *descriptionsDir)
+		if err != nil {
+			log.Fatalf("Description update failed, all changes rolled back: %v", err)
+		}
+		fmt.Println(report)
+		return
+	}
+
+	if *file != "" && !isArchive(*file) {
+		runFileUpload(db, *file, *uploadType)
+		return
+	}
 
-	InitMenu(db)
+	if *file != "" {
+		results, err := UploadArchive(db, *file)
+		if err != nil {
+			log.Fatalf("Archive upload failed: %v", err)
+		}
+		failed := 0
+		for _, result := range results {
+			fmt.Println(result)
+			if !result.Success {
+				failed++
+			}
+		}
+		if *htmlReport != "" {
+			if err := writeHTMLReports(*htmlReport, results); err != nil {
+				log.Printf("could not write report: %v", err)
+			} else {
+				fmt.Printf("Wrote report to %s\n", *htmlReport)
+			}
+		}
+		if failed > 0 {
+			log.Fatalf("%d of %d files failed", failed, len(results))
+		}
+		return
+	}
+
+	if stagingSchema != "" {
+		if *promote {
+			if err := PromoteStaging(db, promotableTables); err != nil {
+				log.Fatalf("Promotion failed, all changes rolled back: %v", err)
+			}
+			fmt.Printf("Promoted %s into the real tables\n", stagingSchema)
+			return
+		}
+		if err := PrepareStaging(db, promotableTables); err != nil {
+			log.Fatalf("Could not prepare staging schema: %v", err)
+		}
+	} else if *promote {
+		log.Fatal("--promote requires STAGING_SCHEMA to be set")
+	}
+
+	exitWithSession(InitMenu(db, connString))
+}
+
+// runSubcommand runs a headless subcommand, exiting non-zero when it fails
+func runSubcommand(db *sql.DB, cmd *subcommand, fs *flag.FlagSet) {
+	if err := cmd.run(db, fs); err != nil {
+		log.Fatalf("%s: %v", cmd.name, err)
+	}
+}
+
+// runFileUpload uploads --file without the menu, as typeName or the type its
+// name suggests, exiting non-zero when it fails
+func runFileUpload(db *sql.DB, path, typeName string) {
+	uploadType, err := guessUploadType(path, typeName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := uploadHeadless(db, uploadType, path); err != nil {
+		log.Fatalf("Upload failed: %v", err)
+	}
+}
+
+// exitWithSession exits non-zero when the TUI failed or any upload in the
+// session did, so wrappers can tell a clean run from a failed one
+func exitWithSession(summary SessionSummary, err error) {
+	if err != nil {
+		log.Fatalf("Error running program: %v", err)
+	}
+	if failed := summary.Failed(); len(failed) > 0 {
+		log.Printf("%d of %d uploads failed this session", len(failed), len(summary.Uploads))
+		os.Exit(1)
+	}
 }
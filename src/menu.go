@@ -1,74 +1,552 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// dataDir is where upload files are read from
+const dataDir = "./src/internal/data/"
+
 type appState int
 
 const (
 	stateMenu appState = iota
 	stateFileSelector
 	stateResult
+	stateQuery
+	stateUploading
+	stateClipboardFormat
+	stateResumePrompt
+	stateConfirmMismatch
+	stateAudit
+	stateCustomTarget
+	stateJunctionTarget
+	stateConfirmDelete
+	stateConfirmSync
+	stateSQLPreview
+	statePreview
+	stateHelp
+	stateBrowse
+	stateSimilarReview
+	stateDashboard
+	stateBatchProgress
+	stateURLInput
+	stateCategoryFilter
+	stateResolveRef
+	stateArchive
+	stateConfirmUpload
+	stateDiagnostics
+	stateMerge
 )
 
 type model struct {
-	state        appState
-	menuChoice   int
-	fileList     []string
-	fileChoice   int
-	selectedFile string
-	resultMsg    string
-	isError      bool
-	db           *sql.DB
-	counts       []int
-}
-
-var menuOptions = []string{
-	"Upload Muscle Groups",
-	"Upload Exercise Types",
-	"Upload Exercise Categories",
-	"Upload Equipment",
-	"Upload Exercises",
-	"Quit",
-}
-
-func initialModel(db *sql.DB) model {
-	m := model{
-		state:      stateMenu,
-		menuChoice: 0,
-		db:         db,
+	state         appState
+	helpReturn    appState // state to go back to when the help overlay closes
+	menuChoice    int
+	fileList      []string
+	allFiles      []string // every supported file, before the recent-only filter
+	fileModTimes  map[string]time.Time
+	recentOnly    bool
+	showHidden    bool // list dotfiles and editor artifacts too
+	lastRun       time.Time
+	fileChoice    int
+	selectedFile  string
+	uploadSource  string // provenance recorded on uploaded rows
+	resultMsg     string
+	isError       bool
+	db            *sql.DB
+	connString    string // kept so a lost connection can be reopened
+	reconnecting  bool
+	counts        []int
+	lastImports   []sql.NullTime // latest imported_at per upload type, for badge colors
+	schemaVersion int            // highest applied migration, -1 if unknown
+	showPercent   bool
+	createdRefs   CreatedRefs
+	skippedNames  []string // names an upload skipped as already existing, offered for export
+	skippedFile   string
+	reportFrom    int // session uploads before this are covered by an earlier screen
+
+	// Count refresh runs asynchronously so a slow DB never blocks the menu
+	countsLoading bool
+	countsErr     error
+	refreshID     int
+	cancelRefresh context.CancelFunc
+
+	// Background upload progress
+	uploadCh      <-chan tea.Msg
+	uploadNotes   string // extra findings from parsing, shown with the result
+	progressDone  int
+	progressTotal int
+
+	// Multi-file batch upload
+	batchSelected map[string]bool // files marked in the file selector
+	batchFiles    []batchFile
+	batchStart    time.Time
+	batchDuration time.Duration
+
+	// Data files read from the selected archive, uploaded as a batch
+	archivePath     string
+	archiveEntries  []archiveEntry
+	archiveTypes    []int // index into uploadTypes per entry, -1 when unknown
+	archiveSelected map[int]bool
+	archiveChoice   int
+
+	// URL import
+	urlInput    string
+	urlFetching bool // a FetchURL is running in the background
+
+	// Clipboard import
+	clipboardData string
+	formatChoice  int
+
+	// Upload awaiting confirmation of a suspected type mismatch
+	pendingData []byte
+	pendingExt  string
+
+	// Exercises upload awaiting a resume decision
+	pendingRows []ExerciseUploadRow
+	pendingSeen int
+	pendingHash string
+	resumeFrom  int
+
+	// Exercises preview, one status per pending row
+	previewStatuses []RowStatus
+	previewErrs     []ValidationError
+	previewOffset   int
+	// previewMissingRefs holds, per reference table, the names the
+	// previewed rows would create
+	previewMissingRefs map[string]map[string]bool
+	categories         []string // distinct categories of the pending rows
+	categoryChoice     int
+	categoryFilter     map[string]bool // nil uploads every category
+	// transformSamples are values COLUMN_TRANSFORMS changed, before and after
+	transformSamples []transformSample
+
+	// References the pending exercises would create, resolved one at a time
+	unknownRefs  []UnknownRef
+	refDecisions []refDecision // one per resolved reference, in order
+	refChoice    int
+
+	// Custom table upload target, picked from the schema allowlist
+	customTargets []CustomTarget
+	customChoice  int
+	customTarget  CustomTarget
+
+	junctionTables []JunctionTable
+	junctionChoice int
+	junctionTable  JunctionTable
+
+	deleteName    string // row pending deletion from the browse table, "" for all rows
+	deleteImpacts []DeleteImpact
+
+	// File awaiting confirmation of a sync, after its dry run
+	syncList []string
+	syncRows []ExerciseUploadRow
+	syncPlan SyncResult
+
+	// Statements an upload of the selected file would run
+	sqlPreviewTitle  string
+	sqlPreviewLines  []string
+	sqlPreviewOffset int
+
+	// Names awaiting review of near-duplicates or confirmation before upload
+	pendingNames    []string
+	pendingParsed   int
+	pendingParents  []NameParent    // parents the names file sets, if any
+	pendingLines    map[string]int  // file line of each pending name, if known
+	pendingExisting map[string]bool // pending names already in the table
+	confirmOffset   int
+	similar         []SimilarName
+	similarChoice   int
+	similarMerge    map[string]bool // flagged names to drop in favour of the existing one
+
+	// Diagnostics screen
+	diagnostics       []string
+	diagnosticsOffset int
+	diagnosticsNote   string
+
+	// Reference entity browser
+	browseTable          string
+	browseRows           []browseRow
+	browseChoice         int
+	browseDeps           string // dependents of the selected row, once checked
+	browseShowDeleted    bool   // include soft-deleted rows
+	browseShowProvenance bool   // show each row's source file and import time
+	browseFilter         string
+	browseFiltering      bool // the filter is being typed
+
+	// Near-duplicate reference entries offered for merging
+	mergeTable   string
+	mergePairs   []DuplicatePair
+	mergeChoice  int
+	mergeConfirm bool // the selected merge is awaiting y/n
+	mergeNote    string
+
+	// Compact dashboard
+	dashboard        []TableStatus
+	dashboardErr     error
+	dashboardLoading bool
+
+	// Import history view
+	auditRecords []AuditRecord
+	auditOffset  int
+
+	// Read-only query runner
+	queryInput   string
+	queryColumns []string
+	queryRows    [][]string
+	queryErr     error
+	queryOffset  int
+}
+
+// countsMsg carries the result of an asynchronous count refresh
+type countsMsg struct {
+	id            int
+	counts        []int
+	lastImports   []sql.NullTime // latest imported_at per upload type
+	schemaVersion int            // -1 when it couldn't be read
+	err           error
+}
+
+// UploadType describes one kind of upload offered in the main menu. Adding an
+// entry to uploadTypes adds its menu option, its count and its upload dispatch.
+type UploadType struct {
+	Label string
+	Table string
+	// Parser turns data in the format implied by ext into names, along with
+	// the file line each name starts on when the format has them. It is nil
+	// for exercises, which have their own parse and insert pipeline.
+	Parser func(ext string, data []byte) (names []string, lines []int, seen int, err error)
+	// Header is the canonical CSV header, checked from the file selector.
+	// Columns ending in "?" are optional.
+	Header []string
+	// Upload replaces the names pipeline for types with their own file layout.
+	// It returns how many rows were inserted out of how many were read.
+	Upload func(db *sql.DB, ext string, data []byte, source string) (inserted, seen int, err error)
+	// Parents reads the parent each name sits under, for tables with a
+	// self-referencing parent_id. The names are inserted first, so a parent
+	// may come later in the file.
+	Parents func(ext string, data []byte) ([]NameParent, error)
+	// Custom is set for uploads into a table picked at runtime; names go into
+	// its Column instead of the managed name/source_file layout
+	Custom *CustomTarget
+}
+
+var uploadTypes = []UploadType{
+	{Label: "Upload Muscle Groups", Table: "muscle_group", Parser: parseNames, Parents: parseMuscleParents, Header: muscleHeader},
+	{Label: "Upload Exercise Types", Table: "training_type", Parser: parseNames, Header: nameHeader},
+	{Label: "Upload Exercise Categories", Table: "exercise_category", Parser: parseNames, Header: nameHeader},
+	{Label: "Upload Equipment", Table: "equipment", Parser: parseNames, Header: nameHeader},
+	{Label: "Upload Exercises", Table: "exercise", Header: exerciseHeader},
+	{Label: "Upload Muscle Synonyms", Table: "muscle_synonyms", Upload: uploadMuscleSynonyms, Header: []string{"Muscle", "Synonym"}},
+}
+
+var (
+	nameHeader     = []string{"Name"}
+	muscleHeader   = []string{"Name", "Parent?"}
+	exerciseHeader = []string{"Name", "Description", "Category", "Equipment", "Types", "Muscles", "Tags?", "VariationOf?", "DefaultScheme?"}
+)
+
+// customTableChoice and junctionTableChoice are the menu indexes of the
+// custom table and junction table upload options
+var (
+	customTableChoice   = len(uploadTypes)
+	junctionTableChoice = len(uploadTypes) + 1
+)
+
+// menuOptions is one entry per upload type, the custom and junction table
+// uploads, then Quit
+var menuOptions = append(uploadTypeLabels(), "Upload to Custom Table", "Upload to Junction Table", "Quit")
+
+// selectedUploadType is the upload type chosen in the menu, or the picked
+// custom or junction table when one of those options is selected
+func (m model) selectedUploadType() UploadType {
+	switch m.menuChoice {
+	case customTableChoice:
+		target := m.customTarget
+		return UploadType{Label: "Upload to " + target.String(), Table: target.Table, Parser: parseNames, Custom: &target}
+	case junctionTableChoice:
+		return junctionUploadType(m.junctionTable)
+	}
+	return uploadTypes[m.menuChoice]
+}
+
+func uploadTypeLabels() []string {
+	labels := make([]string, len(uploadTypes))
+	for i, t := range uploadTypes {
+		labels[i] = t.Label
+	}
+	return labels
+}
+
+// parseNames parses a names file in the format implied by ext. lines is nil
+// for JSON and YAML, whose names are reported by row.
+func parseNames(ext string, data []byte) (names []string, lines []int, seen int, err error) {
+	switch ext {
+	case ".csv":
+		if len(nameColumns) > 0 {
+			return ParseCSVColumns(bytes.NewReader(data), nameColumns)
+		}
+		return ParseCSVReaderLines(bytes.NewReader(data))
+	case ".json":
+		names, seen, err = ParseJSONReader(bytes.NewReader(data))
+	case ".yaml", ".yml":
+		names, seen, err = ParseYAMLReader(bytes.NewReader(data))
+	default:
+		err = fmt.Errorf("unsupported file type: %s", ext)
+	}
+	return names, nil, seen, err
+}
+
+// defaultUploadType pre-selects a menu entry on launch, by table or label
+// (DEFAULT_UPLOAD_TYPE=exercise)
+var defaultUploadType string
+
+// defaultMenuChoice is the menu index of defaultUploadType, or 0 when it is
+// unset or names no known upload type
+func defaultMenuChoice() int {
+	if defaultUploadType == "" {
+		return 0
+	}
+	if i := uploadTypeIndex(defaultUploadType); i >= 0 {
+		return i
+	}
+	logger.Warn("ignoring unknown DEFAULT_UPLOAD_TYPE", "value", defaultUploadType)
+	return 0
+}
+
+// uploadTypeIndex returns the index in uploadTypes of the type named by its
+// table or label, or -1
+func uploadTypeIndex(name string) int {
+	for i, t := range uploadTypes {
+		if strings.EqualFold(name, t.Table) || strings.EqualFold(name, t.Label) {
+			return i
+		}
+	}
+	return -1
+}
+
+func initialModel(db *sql.DB, connString string) model {
+	state := stateMenu
+	if compactMode {
+		state = stateDashboard
+	}
+	return model{
+		state:         state,
+		menuChoice:    defaultMenuChoice(),
+		db:            db,
+		connString:    connString,
+		lastRun:       recordRun(),
+		schemaVersion: -1,
 	}
-	// Initialize counts on startup
-	m.refreshCounts()
-	return m
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	// Initialize counts on startup
+	refresh := func() tea.Msg { return refreshRequestMsg{} }
+	if m.state == stateDashboard {
+		return tea.Batch(refresh, loadDashboard(m.db))
+	}
+	return refresh
 }
 
+// refreshRequestMsg asks the model to start a count refresh
+type refreshRequestMsg struct{}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		SetMessageWidth(msg.Width)
+		return m, nil
+	case refreshRequestMsg:
+		cmd := m.refreshCounts()
+		return m, cmd
+	case progressMsg:
+		m.progressDone, m.progressTotal = msg.done, msg.total
+		return m, waitForUpload(m.uploadCh)
+	case uploadDoneMsg:
+		m.uploadCh = nil
+		m.state = stateResult
+		m.resultMsg = msg.resultMsg
+		m.skippedNames, m.skippedFile = msg.skipped, msg.skippedFile
+		if msg.created.Total() > 0 {
+			m.createdRefs = msg.created
+		}
+		if m.uploadNotes != "" {
+			m.resultMsg += "\n\n" + m.uploadNotes
+			m.uploadNotes = ""
+		}
+		m.isError = msg.isError
+		return m, notifyCompletion(msg.isError)
+	case reconnectMsg:
+		m.countsLoading, m.reconnecting = false, false
+		if msg.err != nil {
+			m.countsErr = fmt.Errorf("%w: reconnect failed: %v", errConnLost, msg.err)
+			return m, nil
+		}
+		// Publish the new handle before closing the old one, so the metrics
+		// server never reads a closed pool
+		old := m.db
+		m.db = msg.db
+		currentDB.Store(msg.db)
+		old.Close()
+		muscleSynonymsReady = nil
+		cmd := m.refreshCounts()
+		if m.state == stateDashboard {
+			m.dashboardLoading = true
+			cmd = tea.Batch(cmd, loadDashboard(m.db))
+		}
+		return m, cmd
+	case batchFileMsg:
+		m.batchFiles[msg.index].status = msg.status
+		m.batchFiles[msg.index].result = msg.result
+		return m, waitForUpload(m.uploadCh)
+	case batchDoneMsg:
+		m.uploadCh = nil
+		m.batchDuration = time.Since(m.batchStart)
+		failed := false
+		for _, f := range m.batchFiles {
+			failed = failed || f.status == batchFailed
+		}
+		return m, notifyCompletion(failed)
+	case dashboardMsg:
+		m.dashboardLoading = false
+		m.dashboard, m.dashboardErr = msg.statuses, msg.err
+		return m, nil
+	case countsMsg:
+		if msg.id != m.refreshID {
+			// Stale result from a cancelled refresh
+			return m, nil
+		}
+		m.countsLoading = false
+		m.cancelRefresh = nil
+		m.countsErr = connLost(msg.err)
+		if msg.err == nil {
+			m.counts, m.lastImports = msg.counts, msg.lastImports
+			m.schemaVersion = msg.schemaVersion
+		} else if errors.Is(m.countsErr, errConnLost) {
+			// Don't keep showing counts the database can no longer confirm
+			m.counts, m.lastImports = nil, nil
+		}
+		return m, nil
+	}
+
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "?" {
+		if next, opened := openHelp(m); opened {
+			return next, nil
+		}
+	}
+
+	// Rebuild the connection from anywhere, e.g. after the laptop slept;
+	// not while an upload holds the current handle
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "ctrl+r" && m.uploadCh == nil && m.connString != "" && !m.reconnecting {
+		m.cancelCountRefresh()
+		m.reconnecting = true
+		return m, reconnect(m.connString)
+	}
+
 	switch m.state {
+	case stateHelp:
+		return updateHelp(m, msg)
+	case stateBrowse:
+		return updateBrowse(m, msg)
+	case stateSimilarReview:
+		return updateSimilarReview(m, msg)
+	case stateMerge:
+		return updateMerge(m, msg)
+	case stateDashboard:
+		return updateDashboard(m, msg)
+	case stateBatchProgress:
+		return updateBatchProgress(m, msg)
+	case stateURLInput:
+		return updateURLInput(m, msg)
+	case stateCategoryFilter:
+		return updateCategoryFilter(m, msg)
+	case stateResolveRef:
+		return updateResolveRef(m, msg)
+	case stateArchive:
+		return updateArchive(m, msg)
+	case stateConfirmUpload:
+		return updateConfirmUpload(m, msg)
+	case stateDiagnostics:
+		return updateDiagnostics(m, msg)
 	case stateMenu:
 		return updateMenu(m, msg)
 	case stateFileSelector:
 		return updateFileMenu(m, msg)
+	case stateQuery:
+		return updateQuery(m, msg)
+	case stateClipboardFormat:
+		return updateClipboardFormat(m, msg)
+	case stateResumePrompt:
+		return updateResumePrompt(m, msg)
+	case stateConfirmMismatch:
+		return updateConfirmMismatch(m, msg)
+	case stateAudit:
+		return updateAudit(m, msg)
+	case stateCustomTarget:
+		return updateCustomTarget(m, msg)
+	case stateJunctionTarget:
+		return updateJunctionTarget(m, msg)
+	case stateConfirmDelete:
+		return updateConfirmDelete(m, msg)
+	case stateConfirmSync:
+		return updateConfirmSync(m, msg)
+	case stateSQLPreview:
+		return updateSQLPreview(m, msg)
+	case statePreview:
+		return updatePreview(m, msg)
 	case stateResult:
+		if key, ok := msg.(tea.KeyMsg); ok && key.String() == "y" && m.createdRefs.Total() > 0 {
+			if err := appendCreatedRefs(m.createdRefs); err != nil {
+				m.resultMsg = fmt.Sprintf("Error appending to data files: %v\nPress enter or q to return to menu.", err)
+				m.isError = true
+			} else {
+				m.resultMsg = fmt.Sprintf("Appended %d new names to the data files.\nPress enter or q to return to menu.", m.createdRefs.Total())
+				m.isError = false
+			}
+			m.createdRefs = CreatedRefs{}
+			return m, nil
+		}
+		if key, ok := msg.(tea.KeyMsg); ok && key.String() == "w" && len(m.skippedNames) > 0 {
+			path := m.skippedFile
+			if err := writeNamesCSV(path, m.skippedNames); err != nil {
+				m.resultMsg = fmt.Sprintf("Error writing skipped names: %v\nPress enter or q to return to menu.", err)
+				m.isError = true
+			} else {
+				m.resultMsg = fmt.Sprintf("Wrote %d skipped names to %s.\nPress enter or q to return to menu.", len(m.skippedNames), path)
+				m.isError = false
+			}
+			m.skippedNames = nil
+			return m, nil
+		}
+		if key, ok := msg.(tea.KeyMsg); ok && key.String() == "e" {
+			if results, _ := sessionUploads(m.reportFrom); len(results) > 0 {
+				return saveHTMLReport(m, results)
+			}
+		}
 		if key, ok := msg.(tea.KeyMsg); ok && (key.String() == "enter" || key.String() == "q" || key.String() == "esc") {
 			m.state = stateMenu
+			_, m.reportFrom = sessionUploads(0)
+			m.createdRefs, m.skippedNames = CreatedRefs{}, nil
 			m.resultMsg = ""
 			m.isError = false
 			// Refresh counts when returning to menu
-			m.refreshCounts()
-			return m, nil
+			cmd := m.refreshCounts()
+			return m, cmd
 		}
 		return m, nil
 	default:
@@ -87,33 +565,206 @@ func updateMenu(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.menuChoice < len(menuOptions)-1 {
 				m.menuChoice++
 			}
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if n := int(key.String()[0] - '0'); n <= len(menuOptions) {
+				m.menuChoice = n - 1
+			}
 		case "enter":
 			if m.menuChoice == len(menuOptions)-1 {
+				m.cancelCountRefresh()
 				return m, tea.Quit
-			} else {
-				// List files in ./src/internal/data/
-				files, err := listDataFiles()
+			} else if m.menuChoice == customTableChoice {
+				targets, err := ListCustomTargets(m.db)
+				if err != nil {
+					m.state = stateResult
+					m.resultMsg = fmt.Sprintf("Error listing tables: %v\nPress enter or q to return to menu.", err)
+					m.isError = true
+					return m, nil
+				}
+				m.customTargets = targets
+				m.customChoice = 0
+				m.state = stateCustomTarget
+				return m, nil
+			} else if m.menuChoice == junctionTableChoice {
+				junctions, err := ListJunctionTables(m.db)
 				if err != nil {
 					m.state = stateResult
-					m.resultMsg = fmt.Sprintf("Error reading ./src/internal/data/: %v\nPress enter or q to return to menu.", err)
+					m.resultMsg = fmt.Sprintf("Error listing junction tables: %v\nPress enter or q to return to menu.", err)
 					m.isError = true
 					return m, nil
 				}
-				m.state = stateFileSelector
-				m.fileList = append(files, "Back")
-				m.fileChoice = 0
+				m.junctionTables = junctions
+				m.junctionChoice = 0
+				m.state = stateJunctionTarget
 				return m, nil
+			} else {
+				return openFileSelector(m)
 			}
 		case "q", "ctrl+c":
+			m.cancelCountRefresh()
 			return m, tea.Quit
+		case "esc", "c":
+			if m.countsLoading {
+				m.cancelCountRefresh()
+				m.countsErr = context.Canceled
+			}
+			return m, nil
 		case "r":
-			m.refreshCounts()
+			if errors.Is(m.countsErr, errConnLost) {
+				m.countsLoading, m.reconnecting = true, true
+				return m, reconnect(m.connString)
+			}
+			cmd := m.refreshCounts()
+			return m, cmd
+		case "p":
+			m.showPercent = !m.showPercent
+			return m, nil
+		case "/":
+			m.state = stateQuery
+			return m, nil
+		case "d":
+			if m.menuChoice >= len(uploadTypes) {
+				m.menuChoice = 0
+			}
+			m.state = stateDashboard
+			m.dashboardLoading = true
+			return m, loadDashboard(m.db)
+		case "b":
+			if m.menuChoice >= len(uploadTypes) {
+				return m, nil
+			}
+			table := uploadTypes[m.menuChoice].Table
+			if _, ok := dependentJoins[table]; !ok {
+				return m, nil
+			}
+			rows, err := ListReferenceRows(m.db, table, false)
+			if err != nil {
+				m.state = stateResult
+				m.resultMsg = fmt.Sprintf("Error reading %s: %v\nPress enter or q to return to menu.", table, err)
+				m.isError = true
+				return m, nil
+			}
+			m.browseTable, m.browseRows, m.browseChoice, m.browseDeps = table, rows, 0, ""
+			m.state = stateBrowse
+			return m, nil
+		case "m":
+			if m.menuChoice >= len(uploadTypes) {
+				return m, nil
+			}
+			table := uploadTypes[m.menuChoice].Table
+			if _, ok := dependentJoins[table]; !ok {
+				return m, nil
+			}
+			return openMerge(m, table)
+		case "a":
+			records, err := readRecentAudit(50)
+			if err != nil {
+				m.state = stateResult
+				m.resultMsg = fmt.Sprintf("Error reading audit log: %v\nPress enter or q to return to menu.", err)
+				m.isError = true
+				return m, nil
+			}
+			m.auditRecords = records
+			m.auditOffset = 0
+			m.state = stateAudit
+			return m, nil
+		case "i":
+			return openDiagnostics(m)
+		case "u":
+			if m.menuChoice >= customTableChoice {
+				return m, nil
+			}
+			m.state = stateURLInput
+			return m, nil
+		case "v":
+			if m.menuChoice >= customTableChoice {
+				return m, nil
+			}
+			text, err := clipboard.ReadAll()
+			if err != nil {
+				m.state = stateResult
+				m.resultMsg = fmt.Sprintf("Error reading clipboard: %v\nPress enter or q to return to menu.", err)
+				m.isError = true
+				return m, nil
+			}
+			if strings.TrimSpace(text) == "" {
+				m.state = stateResult
+				m.resultMsg = "Clipboard is empty.\nPress enter or q to return to menu."
+				m.isError = true
+				return m, nil
+			}
+			m.clipboardData = text
+			m.formatChoice = 0
+			m.state = stateClipboardFormat
+			return m, nil
+		}
+	}
+	return m, nil
+}
+
+// openFileSelector lists the files in ./src/internal/data/ for upload
+func openFileSelector(m model) (tea.Model, tea.Cmd) {
+	files, err := listDataFiles(m.showHidden)
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error reading ./src/internal/data/: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+	m.state = stateFileSelector
+	m.allFiles = files
+	m.fileModTimes = modTimes(files)
+	m.applyFileFilter()
+	return m, nil
+}
+
+func updateCustomTarget(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.customChoice > 0 {
+				m.customChoice--
+			}
+		case "down", "j":
+			// The last entry is Back
+			if m.customChoice < len(m.customTargets) {
+				m.customChoice++
+			}
+		case "q", "esc":
+			m.state = stateMenu
 			return m, nil
+		case "enter":
+			if m.customChoice == len(m.customTargets) {
+				m.state = stateMenu
+				return m, nil
+			}
+			m.customTarget = m.customTargets[m.customChoice]
+			return openFileSelector(m)
 		}
 	}
 	return m, nil
 }
 
+func viewCustomTarget(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Select a table and name column:"))
+	parts = append(parts, "")
+
+	if len(m.customTargets) == 0 {
+		parts = append(parts, RenderHelpText("No tables with text columns in the current schema"))
+	}
+	for i, target := range m.customTargets {
+		parts = append(parts, RenderFileItem(target.String(), i == m.customChoice, false))
+	}
+	parts = append(parts, RenderFileItem("Back", m.customChoice == len(m.customTargets), true))
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Select: enter • Back: q/esc"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
+
 func updateFileMenu(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
 	if key, ok := msg.(tea.KeyMsg); ok {
 		switch key.String() {
@@ -127,112 +778,589 @@ func updateFileMenu(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "q", "esc":
 			m.state = stateMenu
+			m.batchSelected = nil
+			return m, nil
+		case " ":
+			filename := m.fileList[m.fileChoice]
+			if filename == "Back" {
+				return m, nil
+			}
+			if m.batchSelected == nil {
+				m.batchSelected = make(map[string]bool)
+			}
+			m.batchSelected[filename] = !m.batchSelected[filename]
+			return m, nil
+		case "t":
+			csvHeader = (csvHeader + 1) % 3
+			return m, nil
+		case "s":
+			return startSync(m)
+		case "p":
+			return openSQLPreview(m)
+		case "a":
+			return appendJunctions(m)
+		case "m":
+			m.recentOnly = !m.recentOnly
+			m.applyFileFilter()
+			return m, nil
+		case ".":
+			m.showHidden = !m.showHidden
+			return openFileSelector(m)
+		case "h":
+			filename := m.fileList[m.fileChoice]
+			header := m.selectedUploadType().Header
+			if filename == "Back" || header == nil || strings.ToLower(filepath.Ext(filename)) != ".csv" {
+				return m, nil
+			}
+			report, err := CheckHeader(filepath.Join(dataDir, filename), header)
+			m.state = stateResult
+			m.isError = err != nil || !report.OK()
+			if err != nil {
+				m.resultMsg = fmt.Sprintf("Error checking header: %v\nPress enter or q to return to menu.", err)
+			} else {
+				m.resultMsg = fmt.Sprintf("%s\n%s", filename, report)
+			}
 			return m, nil
 		case "enter":
 			if m.fileList[m.fileChoice] == "Back" {
 				m.state = stateMenu
+				m.batchSelected = nil
 				return m, nil
 			}
-			m.selectedFile = filepath.Join("./src/internal/data/", m.fileList[m.fileChoice])
-
-			// Detect file type and process
-			ext := strings.ToLower(filepath.Ext(m.selectedFile))
-			var names []string
-			var err error
-
-			switch ext {
-			case ".csv":
-				names, err = ParseCSV(m.selectedFile)
-			case ".json":
-				names, err = ParseJSON(m.selectedFile)
-			case ".yaml", ".yml":
-				names, err = ParseYAML(m.selectedFile)
-			default:
-				err = fmt.Errorf("unsupported file type: %s", ext)
+			for _, marked := range m.batchSelected {
+				if marked {
+					return startBatch(m)
+				}
+			}
+			m.selectedFile = filepath.Join(dataDir, m.fileList[m.fileChoice])
+			m.uploadSource = m.fileList[m.fileChoice]
+			if isArchive(m.selectedFile) {
+				return openArchive(m, m.selectedFile)
 			}
 
+			data, ext, err := readUploadFile(m.selectedFile, m.selectedUploadType())
 			if err != nil {
 				m.state = stateResult
-				m.resultMsg = fmt.Sprintf("Error parsing file: %v\nPress enter or q to return to menu.", err)
+				m.resultMsg = fmt.Sprintf("Error reading file: %v\nPress enter or q to return to menu.", err)
 				m.isError = true
 				return m, nil
 			}
-
-			// Handle different upload types
-			var query string
-			switch m.menuChoice {
-			case 0: // Muscle Groups
-				query = InsertMuscleGroupQuery
-			case 1: // Exercise Types
-				query = InsertTrainingTypeQuery
-			case 2: // Exercise Categories
-				query = InsertCategoryQuery
-			case 3: // Equipment
-				query = InsertEquipmentQuery
-			case 4: // Exercises (special handling)
-				rows, err := ParseExercisesCSV(m.selectedFile)
-				if err != nil {
-					m.state = stateResult
-					m.resultMsg = fmt.Sprintf("Error parsing exercises CSV: %v\nPress enter or q to return to menu.", err)
-					m.isError = true
-					return m, nil
-				}
-				err = InsertExercises(m.db, rows)
-				if err != nil {
-					m.state = stateResult
-					m.resultMsg = fmt.Sprintf("Database error: %v\nPress enter or q to return to menu.", err)
-					m.isError = true
-					return m, nil
-				}
-				m.state = stateResult
-				m.resultMsg = fmt.Sprintf("Successfully uploaded %d exercises!\nPress enter or q to return to menu.", len(rows))
-				m.isError = false
+			if suspectTypeMismatch(m.fileList[m.fileChoice], m.menuChoice) {
+				m.state = stateConfirmMismatch
+				m.pendingData, m.pendingExt = data, ext
 				return m, nil
 			}
+			return uploadData(m, ext, data)
+		}
+	}
+	return m, nil
+}
 
-			// Insert simple name-based entries
-			err = InsertNamesToDB(m.db, query, names)
-			if err != nil {
-				m.state = stateResult
-				m.resultMsg = fmt.Sprintf("Database error: %v\nPress enter or q to return to menu.", err)
-				m.isError = true
-				return m, nil
-			}
+// filenameHints maps filename keywords to the table they suggest. Order
+// matters: "exercise_types.csv" must match "type" before "exercise".
+var filenameHints = []struct {
+	keyword string
+	table   string
+}{
+	{"synonym", "muscle_synonyms"},
+	{"muscle", "muscle_group"},
+	{"type", "training_type"},
+	{"categor", "exercise_category"},
+	{"equip", "equipment"},
+	{"exercise", "exercise"},
+}
 
-			m.state = stateResult
-			m.resultMsg = fmt.Sprintf("Successfully uploaded %d entries!\nPress enter or q to return to menu.", len(names))
-			m.isError = false
+// suspectTypeMismatch reports whether filename strongly suggests a different
+// upload type than the selected menu option
+func suspectTypeMismatch(filename string, menuChoice int) bool {
+	if menuChoice >= len(uploadTypes) {
+		return false
+	}
+	name := strings.ToLower(filename)
+	for _, hint := range filenameHints {
+		if strings.Contains(name, hint.keyword) {
+			return hint.table != uploadTypes[menuChoice].Table
+		}
+	}
+	return false
+}
+
+func updateConfirmMismatch(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "y":
+			data, ext := m.pendingData, m.pendingExt
+			m.pendingData = nil
+			return uploadData(m, ext, data)
+		case "n", "q", "esc":
+			m.pendingData = nil
+			m.state = stateFileSelector
 			return m, nil
 		}
 	}
 	return m, nil
 }
 
-func (m model) View() string {
-	switch m.state {
-	case stateMenu:
-		var parts []string
-
-		// App header
-		parts = append(parts, RenderAppHeader())
-		parts = append(parts, "")
-
-		// Menu title
-		parts = append(parts, RenderMenuTitle("Select an option:"))
-		parts = append(parts, "")
+// clipboardFormats are the formats clipboard contents can be parsed as, with their file extension
+var clipboardFormats = []struct {
+	label string
+	ext   string
+}{
+	{"CSV", ".csv"},
+	{"JSON", ".json"},
+	{"YAML", ".yaml"},
+	{"Back", ""},
+}
 
-		// Menu items
-		for i, opt := range menuOptions {
-			count := -1
-			if i < len(m.counts) {
-				count = m.counts[i]
+func updateClipboardFormat(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.formatChoice > 0 {
+				m.formatChoice--
+			}
+		case "down", "j":
+			if m.formatChoice < len(clipboardFormats)-1 {
+				m.formatChoice++
 			}
-			parts = append(parts, RenderMenuItem(opt, i == m.menuChoice, count))
+		case "q", "esc":
+			m.state = stateMenu
+			m.clipboardData = ""
+			return m, nil
+		case "enter":
+			format := clipboardFormats[m.formatChoice]
+			data := m.clipboardData
+			m.clipboardData = ""
+			if format.ext == "" {
+				m.state = stateMenu
+				return m, nil
+			}
+			m.uploadSource = "clipboard"
+			return uploadData(m, format.ext, []byte(data))
 		}
-
+	}
+	return m, nil
+}
+
+func updateResumePrompt(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "y", "enter":
+			return runExercisesUpload(m, m.resumeFrom)
+		case "n":
+			return runExercisesUpload(m, 0)
+		case "q", "esc":
+			m.state = stateMenu
+			m.pendingRows = nil
+			return m, nil
+		}
+	}
+	return m, nil
+}
+
+// continueExercisesUpload offers to resume an interrupted upload of the pending
+// rows, and otherwise starts it
+func continueExercisesUpload(m model) (tea.Model, tea.Cmd) {
+	if done := getCheckpoint(m.pendingHash); done > 0 && done < len(m.pendingRows) {
+		m.state = stateResumePrompt
+		m.resumeFrom = done
+		return m, nil
+	}
+	return runExercisesUpload(m, 0)
+}
+
+// runExercisesUpload inserts the pending exercise rows starting at start in
+// the background, checkpointing each committed batch so an interrupted upload
+// can be resumed
+func runExercisesUpload(m model, start int) (tea.Model, tea.Cmd) {
+	rows := m.pendingRows
+	m.pendingRows = nil
+
+	m.state = stateUploading
+	m.progressDone, m.progressTotal = start, len(rows)
+	m.uploadCh = startExercisesUpload(m.db, uploadTypes[m.menuChoice].Label, rows, start, m.uploadSource, m.pendingSeen, m.pendingHash, m.uploadNotes)
+	m.uploadNotes = ""
+	return m, waitForUpload(m.uploadCh)
+}
+
+// startExercisesUpload runs InsertExercisesInBatches in the background,
+// streaming per-row progress and the final result over the returned channel.
+// notes are appended to the result message.
+func startExercisesUpload(db *sql.DB, label string, rows []ExerciseUploadRow, start int, source string, seen int, hash, notes string) <-chan tea.Msg {
+	ch := make(chan tea.Msg)
+	go func() {
+		committed := start
+		began := time.Now()
+		imported, err := InsertExercisesInBatches(db, rows, start, source, func(done int) {
+			committed = done
+			if err := setCheckpoint(hash, done); err != nil {
+				logger.Warn("could not save upload checkpoint", "err", err)
+			}
+		}, func(done int) {
+			ch <- progressMsg{done: done, total: len(rows)}
+		})
+
+		uploaded := len(rows) - start - imported.Unchanged
+		result := UploadResult{Type: label, File: source, Parsed: seen, Inserted: uploaded, Skipped: imported.Unchanged, Success: err == nil,
+			Duration: time.Since(began), Created: imported.Created}
+		if err != nil {
+			result.Inserted = 0
+			result.Error = err.Error()
+		}
+		recordUpload(result)
+
+		if err != nil {
+			msg := uploadDoneMsg{resultMsg: fmt.Sprintf("Database error: %v\nPress enter or q to return to menu.", err), isError: true}
+			if committed > 0 {
+				msg.resultMsg = fmt.Sprintf("Database error: %v\n%d of %d rows were committed; re-upload the file to resume.\nPress enter or q to return to menu.", err, committed, len(rows))
+			}
+			ch <- msg
+			return
+		}
+		if err := clearCheckpoint(hash); err != nil {
+			logger.Warn("could not clear upload checkpoint", "err", err)
+		}
+
+		if offlineMode {
+			ch <- uploadDoneMsg{resultMsg: fmt.Sprintf("offline: would insert %d exercises\nPress enter or q to return to menu.", uploaded)}
+			return
+		}
+
+		msg := uploadDoneMsg{resultMsg: fmt.Sprintf("Successfully uploaded %d exercises!%s\nPress enter or q to return to menu.", uploaded, dropWarning(seen, len(rows)))}
+		if imported.Unchanged > 0 {
+			msg.resultMsg += fmt.Sprintf("\nSkipped %d unchanged exercises.", imported.Unchanged)
+		}
+		if imported.NoEquipment > 0 {
+			msg.resultMsg += fmt.Sprintf("\n%d exercises need no equipment (%s).", imported.NoEquipment, strings.Join(noEquipment, ", "))
+		}
+		if notes != "" {
+			msg.resultMsg += "\n" + notes
+		}
+		if unresolved := imported.UnresolvedParents; len(unresolved) > 0 {
+			msg.resultMsg += fmt.Sprintf("\n⚠ %d variations name an unknown base exercise:\n  %s", len(unresolved), strings.Join(unresolved, "\n  "))
+		}
+		if created := imported.Created; created.Total() > 0 {
+			msg.created = created
+			msg.resultMsg += fmt.Sprintf("\n\n%s\nPress y to append them to the data files.", describeCreatedRefs(created))
+		}
+		ch <- msg
+	}()
+	return ch
+}
+
+// uploadData parses data in the format implied by ext and uploads it as the
+// type selected in the main menu
+func uploadData(m model, ext string, data []byte) (tea.Model, tea.Cmd) {
+	uploadType := m.selectedUploadType()
+
+	if uploadType.Upload != nil {
+		start := time.Now()
+		inserted, seen, err := uploadType.Upload(m.db, ext, data, m.uploadSource)
+		result := UploadResult{Type: uploadType.Label, File: m.uploadSource, Parsed: seen, Inserted: inserted, Skipped: seen - inserted, Success: err == nil,
+			Duration: time.Since(start)}
+		if err != nil {
+			result.Inserted, result.Skipped, result.Error = 0, 0, err.Error()
+		}
+		recordUpload(result)
+
+		m.state = stateResult
+		if err != nil {
+			m.resultMsg = fmt.Sprintf("Error uploading file: %v\nPress enter or q to return to menu.", err)
+			m.isError = true
+			return m, notifyCompletion(true)
+		}
+		m.resultMsg = fmt.Sprintf("Successfully uploaded %d entries (%d already existed)!\nPress enter or q to return to menu.", inserted, seen-inserted)
+		m.isError = false
+		return m, notifyCompletion(false)
+	}
+
+	if uploadType.Parser == nil {
+		rows, seen, problems, err := parseExercisesCSV(bytes.NewReader(data))
+		if err != nil {
+			m.state = stateResult
+			m.resultMsg = fmt.Sprintf("Error parsing exercises CSV: %v\nPress enter or q to return to menu.", err)
+			m.isError = true
+			return m, nil
+		}
+		all := len(rows) + len(problems)
+		rows, problems, lineNote := applyExerciseLineRange(rows, problems, seen)
+		seen -= all - len(rows) - len(problems)
+		m.uploadNotes = joinNotes(lineNote, describeMalformedRows(problems))
+		m.transformSamples = transformExerciseRows(rows)
+		errs := ValidateExerciseRows(rows)
+		statuses, err := PreviewExerciseStatuses(m.db, rows, errs)
+		if err != nil {
+			m.state = stateResult
+			m.resultMsg = fmt.Sprintf("Error comparing exercises with the database: %v\nPress enter or q to return to menu.", err)
+			m.isError = true
+			return m, nil
+		}
+		m.pendingRows, m.pendingSeen, m.pendingHash = rows, seen, contentHash(data)
+		if uploadLines.first > 0 {
+			// Resume only into the same slice of the file
+			m.pendingHash = contentHash([]byte(m.pendingHash + "\x1f" + uploadLines.String()))
+		}
+		m.previewStatuses, m.previewErrs, m.previewOffset = statuses, errs, 0
+		if len(rows) <= confirmThreshold && len(errs) == 0 {
+			// Small and clean: no preview to confirm
+			m.previewStatuses = nil
+			return checkUnknownRefs(m)
+		}
+		missing, err := MissingRefNames(m.db, rows)
+		if err != nil {
+			m.state = stateResult
+			m.resultMsg = fmt.Sprintf("Error looking up reference names: %v\nPress enter or q to return to menu.", err)
+			m.isError = true
+			return m, nil
+		}
+		m.previewMissingRefs = missing
+		m.state = statePreview
+		return m, nil
+	}
+
+	names, lines, seen, err := uploadType.Parser(ext, data)
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error parsing file: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+
+	m.pendingParents = nil
+	if uploadType.Parents != nil {
+		if m.pendingParents, err = uploadType.Parents(ext, data); err != nil {
+			m.state = stateResult
+			m.resultMsg = fmt.Sprintf("Error reading parents: %v\nPress enter or q to return to menu.", err)
+			m.isError = true
+			return m, nil
+		}
+	}
+
+	all := len(names)
+	names, lineNote := applyLineRange(names)
+	lines, _ = applyLineRange(lines)
+	seen -= all - len(names)
+
+	if errs := ValidateNames(names, lines); len(errs) > 0 {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Validation failed:\n%s\nPress enter or q to return to menu.", formatValidationErrors(errs, 10))
+		m.isError = true
+		return m, nil
+	}
+
+	parsed := len(names)
+	m.pendingLines = nameLines(names, lines)
+	var collisions []CaseCollision
+	names, collisions = CollapseCaseVariants(names)
+	m.uploadNotes = joinNotes(lineNote, describeCaseCollisions(collisions))
+	m.pendingSeen, m.pendingParsed = seen, parsed
+
+	if similarityThreshold > 0 && uploadType.Custom == nil {
+		flagged, err := flagSimilarNames(m.db, uploadType.Table, names, similarityThreshold)
+		if err != nil {
+			m.state = stateResult
+			m.resultMsg = fmt.Sprintf("Error checking for near-duplicates: %v\nPress enter or q to return to menu.", err)
+			m.isError = true
+			return m, nil
+		}
+		if len(flagged) > 0 {
+			m.pendingNames = names
+			m.similar, m.similarChoice, m.similarMerge = flagged, 0, make(map[string]bool)
+			m.state = stateSimilarReview
+			return m, nil
+		}
+	}
+	if len(names) > confirmThreshold {
+		return confirmNamesUpload(m, names)
+	}
+	return startNamesUploadCmd(m, names)
+}
+
+// confirmNamesUpload asks before uploading names, marking each as new or
+// already existing so the screen shows what the upload will add
+func confirmNamesUpload(m model, names []string) (tea.Model, tea.Cmd) {
+	uploadType := m.selectedUploadType()
+	table, column := uploadType.Table, "name"
+	if uploadType.Custom != nil {
+		table, column = uploadType.Custom.Table, uploadType.Custom.Column
+	}
+	existing, err := ExistingNames(m.db, table, column, names)
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error checking for existing names: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+	m.pendingNames, m.pendingExisting, m.confirmOffset = names, existing, 0
+	m.state = stateConfirmUpload
+	return m, nil
+}
+
+// confirmThreshold is the row count above which an upload waits for
+// confirmation; smaller ones commit straight away (CONFIRM_THRESHOLD, 0
+// confirms every upload)
+var confirmThreshold = 25
+
+func updateConfirmUpload(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.confirmOffset > 0 {
+				m.confirmOffset--
+			}
+		case "down", "j":
+			if m.confirmOffset < len(m.pendingNames)-previewPageSize {
+				m.confirmOffset++
+			}
+		case "y", "enter":
+			names := m.pendingNames
+			m.pendingNames, m.pendingExisting = nil, nil
+			return startNamesUploadCmd(m, names)
+		case "n", "q", "esc":
+			// Clipboard and URL uploads have no file list to go back to
+			m.pendingNames, m.pendingExisting, m.uploadNotes = nil, nil, ""
+			m.state = stateMenu
+		}
+	}
+	return m, nil
+}
+
+func viewConfirmUpload(m model) string {
+	uploadType := m.selectedUploadType()
+	netNew := 0
+	for _, name := range m.pendingNames {
+		if !m.pendingExisting[name] {
+			netNew++
+		}
+	}
+
+	parts := []string{
+		RenderMenuTitle("Confirm upload"),
+		"",
+		fmt.Sprintf("Upload %d names from %s into %s?", len(m.pendingNames), m.uploadSource, uploadType.Table),
+		RenderRowStatus(fmt.Sprintf("%d new", netNew), RowNew) + ", " +
+			RenderRowStatus(fmt.Sprintf("%d already exist", len(m.pendingNames)-netNew), RowUnchanged),
+		RenderHelpText(fmt.Sprintf("Uploads of more than %d rows ask first (CONFIRM_THRESHOLD).", confirmThreshold)),
+		"",
+	}
+
+	end := min(m.confirmOffset+previewPageSize, len(m.pendingNames))
+	for i, name := range m.pendingNames[m.confirmOffset:end] {
+		line := fmt.Sprintf("%4d  %-32s new", m.confirmOffset+i+1, name)
+		status := RowNew
+		if m.pendingExisting[name] {
+			line = fmt.Sprintf("%4d  %-32s exists", m.confirmOffset+i+1, name)
+			status = RowUnchanged
+		}
+		parts = append(parts, RenderRowStatus(line, status))
+	}
+	if len(m.pendingNames) > previewPageSize {
+		parts = append(parts, RenderHelpText(fmt.Sprintf("%d-%d of %d", m.confirmOffset+1, end, len(m.pendingNames))))
+	}
+
+	if m.uploadNotes != "" {
+		parts = append(parts, "", m.uploadNotes)
+	}
+	parts = append(parts, "", RenderHelpText("Upload: y/enter • Scroll: ↑/↓ or j/k • Cancel: n/esc"))
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
+
+// startNamesUploadCmd inserts simple name-based entries in the background,
+// reporting progress per batch
+func startNamesUploadCmd(m model, names []string) (tea.Model, tea.Cmd) {
+	m.state = stateUploading
+	m.progressDone, m.progressTotal = 0, len(dedupeNames(names))
+	m.uploadCh = startNamesUpload(m.db, m.selectedUploadType(), names, m.pendingParents, m.pendingLines, m.uploadSource, m.pendingSeen, m.pendingParsed)
+	return m, waitForUpload(m.uploadCh)
+}
+
+func (m model) View() string {
+	if accessibleMode && m.state == stateMenu {
+		return viewAccessibleMenu(m)
+	}
+	return m.styledView()
+}
+
+// viewAccessibleMenu is the main menu in accessibleMode: numbered options with
+// their counts as plain text
+func viewAccessibleMenu(m model) string {
+	lines := []string{m.renderHeader(), "", "Select an option:"}
+	percents := countPercents(m.counts)
+	for i, opt := range menuOptions {
+		cursor := "  "
+		if i == m.menuChoice {
+			cursor = "> "
+		}
+		line := fmt.Sprintf("%s%d. %s", cursor, i+1, opt)
+		switch {
+		case m.countsLoading && i < len(uploadTypes):
+			line += " " + RenderLoadingBadge()
+		case i < len(m.counts) && m.counts[i] == countNA:
+			line += " " + RenderCountBadge(countNA, time.Time{})
+		case i < len(m.counts) && m.counts[i] >= 0 && m.showPercent:
+			line += " " + RenderCountBadgePercent(percents[i], time.Time{})
+		case i < len(m.counts) && m.counts[i] >= 0:
+			line += " " + RenderCountBadge(m.counts[i], time.Time{})
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "")
+	if m.countsErr != nil && !errors.Is(m.countsErr, context.Canceled) {
+		lines = append(lines, fmt.Sprintf("Could not load counts: %v", m.countsErr))
+	}
+	lines = append(lines, "Keys: up and down or j and k to move, 1 to 9 to jump to an option, enter to select, ? for all keys, q to quit.")
+	return strings.Join(lines, "\n")
+}
+
+func (m model) styledView() string {
+	switch m.state {
+	case stateMenu:
+		var parts []string
+
+		// App header
+		parts = append(parts, m.renderHeader())
+		parts = append(parts, "")
+
+		// Menu title
+		parts = append(parts, RenderMenuTitle("Select an option:"))
+		parts = append(parts, "")
+
+		// Menu items
+		percents := countPercents(m.counts)
+		for i, opt := range menuOptions {
+			if m.countsLoading && i < len(uploadTypes) {
+				parts = append(parts, RenderMenuItemWithBadge(opt, i == m.menuChoice, RenderLoadingBadge()))
+				continue
+			}
+			count := -1
+			var lastImport time.Time
+			if i < len(m.counts) {
+				count = m.counts[i]
+			}
+			if i < len(m.lastImports) && m.lastImports[i].Valid {
+				lastImport = m.lastImports[i].Time
+			}
+			if m.showPercent && count >= 0 {
+				parts = append(parts, RenderMenuItemPercent(opt, i == m.menuChoice, percents[i], lastImport))
+				continue
+			}
+			parts = append(parts, RenderMenuItem(opt, i == m.menuChoice, count, lastImport))
+		}
+
 		// Help text
 		parts = append(parts, "")
-		parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Select: enter • Refresh counts: r • Quit: q"))
+		switch {
+		case m.countsLoading:
+			parts = append(parts, RenderHelpText("Loading counts… • Cancel: esc/c"))
+		case errors.Is(m.countsErr, context.Canceled):
+			parts = append(parts, RenderHelpText("Count refresh cancelled"))
+		case errors.Is(m.countsErr, errConnLost):
+			parts = append(parts, RenderErrorMessage(fmt.Sprintf("%v\nPress r to reconnect.", m.countsErr)))
+		case m.countsErr != nil:
+			parts = append(parts, RenderHelpText(fmt.Sprintf("Could not load counts: %v", m.countsErr)))
+		}
+		parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Select: enter • Refresh counts: r • Toggle %: p • Paste: v • URL: u • Query: / • Browse: b • Merge duplicates: m • Dashboard: d • History: a • Diagnostics: i • Reconnect: ctrl+r • Help: ? • Quit: q"))
 
 		return ContainerStyle.Render(strings.Join(parts, "\n"))
 
@@ -243,18 +1371,126 @@ func (m model) View() string {
 		parts = append(parts, RenderMenuTitle("Select a file to upload:"))
 		parts = append(parts, "")
 
+		if m.recentOnly {
+			parts = append(parts, RenderHelpText(fmt.Sprintf("Showing files modified since %s", formatLastRun(m.lastRun))))
+		}
+		if m.showHidden {
+			parts = append(parts, RenderHelpText("Showing hidden files"))
+		}
+		if csvHeader != headerAuto {
+			parts = append(parts, RenderHelpText(fmt.Sprintf("CSV header: %s", csvHeader)))
+		}
+
 		// File list
 		for i, filename := range m.fileList {
 			isBackOption := filename == "Back"
-			parts = append(parts, RenderFileItem(filename, i == m.fileChoice, isBackOption))
+			label := filename
+			if m.recentOnly && !isBackOption {
+				label = fmt.Sprintf("%s  %s", filename, m.fileModTimes[filename].Format("Jan 2 15:04"))
+			}
+			if m.batchSelected[filename] {
+				label = "[x] " + label
+			}
+			parts = append(parts, RenderFileItem(label, i == m.fileChoice, isBackOption))
 		}
 
 		// Help text
+		parts = append(parts, "")
+		parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Select: enter • Mark for batch: space • Recent only: m • Hidden: . • Check header: h • Header mode: t • Sync: s • SQL: p • Add links only: a • Back: q/esc"))
+
+		return ContainerStyle.Render(strings.Join(parts, "\n"))
+
+	case stateQuery:
+		return viewQuery(m)
+
+	case stateAudit:
+		return viewAudit(m)
+
+	case stateCustomTarget:
+		return viewCustomTarget(m)
+	case stateJunctionTarget:
+		return viewJunctionTarget(m)
+	case stateConfirmDelete:
+		return viewConfirmDelete(m)
+	case stateConfirmSync:
+		return viewConfirmSync(m)
+	case stateSQLPreview:
+		return viewSQLPreview(m)
+
+	case statePreview:
+		return viewPreview(m)
+
+	case stateHelp:
+		return viewHelp(m)
+
+	case stateBrowse:
+		return viewBrowse(m)
+
+	case stateSimilarReview:
+		return viewSimilarReview(m)
+
+	case stateMerge:
+		return viewMerge(m)
+
+	case stateDashboard:
+		return viewDashboard(m)
+
+	case stateBatchProgress:
+		return viewBatchProgress(m)
+
+	case stateURLInput:
+		return viewURLInput(m)
+
+	case stateCategoryFilter:
+		return viewCategoryFilter(m)
+
+	case stateResolveRef:
+		return viewResolveRef(m)
+
+	case stateArchive:
+		return viewArchive(m)
+
+	case stateConfirmUpload:
+		return viewConfirmUpload(m)
+
+	case stateDiagnostics:
+		return viewDiagnostics(m)
+
+	case stateConfirmMismatch:
+		content := RenderErrorMessage(fmt.Sprintf("%s looks like it doesn't belong in \"%s\".\nUpload it anyway?",
+			filepath.Base(m.selectedFile), menuOptions[m.menuChoice])) +
+			"\n\n" + RenderHelpText("Upload anyway: y • Pick another file: n/esc")
+		return ContainerStyle.Render(content)
+
+	case stateResumePrompt:
+		content := RenderMenuTitle("Resume upload?") + "\n\n" +
+			fmt.Sprintf("A previous upload of this file stopped after %d of %d rows.", m.resumeFrom, len(m.pendingRows)) +
+			"\n\n" + RenderHelpText("Resume: y/enter • Start over: n • Cancel: q/esc")
+		return ContainerStyle.Render(content)
+
+	case stateClipboardFormat:
+		var parts []string
+
+		parts = append(parts, RenderMenuTitle(fmt.Sprintf("Clipboard → %s: select format", menuOptions[m.menuChoice])))
+		parts = append(parts, "")
+
+		for i, format := range clipboardFormats {
+			parts = append(parts, RenderFileItem(format.label, i == m.formatChoice, format.ext == ""))
+		}
+
 		parts = append(parts, "")
 		parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Select: enter • Back: q/esc"))
 
 		return ContainerStyle.Render(strings.Join(parts, "\n"))
 
+	case stateUploading:
+		parts := []string{
+			RenderMenuTitle("Uploading…"),
+			"",
+			RenderProgressBar(m.progressDone, m.progressTotal),
+		}
+		return ContainerStyle.Render(strings.Join(parts, "\n"))
+
 	case stateResult:
 		var content string
 		if m.isError {
@@ -264,7 +1500,11 @@ func (m model) View() string {
 		}
 
 		// Add help text
-		content += "\n\n" + RenderHelpText("Press enter, q, or esc to continue")
+		help := "Press enter, q, or esc to continue"
+		if results, _ := sessionUploads(m.reportFrom); len(results) > 0 {
+			help += " • Save an HTML report: e"
+		}
+		content += "\n\n" + RenderHelpText(help)
 
 		return ContainerStyle.Render(content)
 
@@ -273,30 +1513,304 @@ func (m model) View() string {
 	}
 }
 
-// listDataFiles returns a sorted list of supported files in ./data/
-func listDataFiles() ([]string, error) {
-	entries, err := os.ReadDir("./src/internal/data/")
+// progressMsg reports how many rows of a background upload have been processed
+type progressMsg struct {
+	done, total int
+}
+
+// uploadDoneMsg carries the final result of a background upload
+type uploadDoneMsg struct {
+	resultMsg string
+	isError   bool
+	skipped   []string // names that already existed in the table
+	// skippedFile is where w writes the skipped names
+	skippedFile string
+	// created lists reference entities an exercises upload created, for y
+	// to append to the data files
+	created CreatedRefs
+}
+
+// waitForUpload returns a command that waits for the next message from a background upload
+func waitForUpload(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
+	}
+}
+
+// startNamesUpload runs BulkInsertNames in the background, streaming progress
+// and the final result over the returned channel. seen is the number of rows in
+// the file and parsed the number of names read from them, before any dedup;
+// lines locates names that fail to insert.
+func startNamesUpload(db *sql.DB, uploadType UploadType, names []string, parents []NameParent, lines map[string]int, source string, seen, parsed int) <-chan tea.Msg {
+	ch := make(chan tea.Msg)
+	go func() {
+		onProgress := func(done, total int) {
+			ch <- progressMsg{done: done, total: total}
+		}
+		var inserted int
+		var skippedNames []string
+		var failed []FailedName
+		var unresolved []string
+		var err error
+		start := time.Now()
+		if uploadType.Custom != nil {
+			inserted, skippedNames, err = BulkInsertCustomNames(db, *uploadType.Custom, names, onProgress)
+		} else {
+			inserted, skippedNames, failed, unresolved, err = BulkInsertNames(db, uploadType.Table, names, parents, source, onProgress)
+		}
+		locateFailedNames(failed, lines)
+
+		result := UploadResult{Type: uploadType.Label, File: source, Parsed: seen, Inserted: inserted, Success: err == nil, Duration: time.Since(start), Unresolved: unresolved}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Skipped = parsed - inserted - len(failed)
+		}
+		for _, f := range failed {
+			result.Failed = append(result.Failed, f.String())
+		}
+		recordUpload(result)
+
+		if err != nil {
+			ch <- uploadDoneMsg{
+				resultMsg: fmt.Sprintf("Database error: %v\nPress enter or q to return to menu.", err),
+				isError:   true,
+			}
+			return
+		}
+
+		if offlineMode {
+			ch <- uploadDoneMsg{resultMsg: fmt.Sprintf("offline: would insert %d entries\nPress enter or q to return to menu.", len(names))}
+			return
+		}
+
+		skipped := parsed - inserted - len(failed)
+		msg := uploadDoneMsg{
+			resultMsg: fmt.Sprintf("Successfully uploaded %d entries (%d already existed)!%s%s%s\nPress enter or q to return to menu.",
+				inserted, skipped, dropWarning(seen, inserted+skipped+len(failed)), describeFailedNames(failed), describeUnresolvedParents(unresolved)),
+			skipped: skippedNames,
+		}
+		if len(skippedNames) > 0 {
+			msg.skippedFile = skippedNamesFile(uploadType)
+			msg.resultMsg += fmt.Sprintf("\nPress w to write the %d conflicting names to %s.", len(skippedNames), msg.skippedFile)
+		}
+		ch <- msg
+	}()
+	return ch
+}
+
+// describeUnresolvedParents lists the names whose parent couldn't be set
+func describeUnresolvedParents(unresolved []string) string {
+	if len(unresolved) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n⚠ %d names have a parent that doesn't exist or would form a cycle:\n  %s", len(unresolved), strings.Join(unresolved, "\n  "))
+}
+
+// describeFailedNames lists the names BATCH_FALLBACK left out of an upload
+func describeFailedNames(failed []FailedName) string {
+	if len(failed) == 0 {
+		return ""
+	}
+	lines := []string{fmt.Sprintf("\n⚠ %d names failed to insert and were left out:", len(failed))}
+	for i, f := range failed {
+		if i == 10 {
+			lines = append(lines, fmt.Sprintf("  …and %d more", len(failed)-i))
+			break
+		}
+		lines = append(lines, "  "+f.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeCaseCollisions lists names that were collapsed into a differently-cased first occurrence
+func describeCaseCollisions(collisions []CaseCollision) string {
+	if len(collisions) == 0 {
+		return ""
+	}
+	lines := []string{fmt.Sprintf("⚠ %d names appeared in several casings; kept the first:", len(collisions))}
+	for _, c := range collisions {
+		lines = append(lines, fmt.Sprintf("  %s ← %s", c.Kept, strings.Join(c.Dropped, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// refFile pairs newly created reference names with the data file they belong in
+type refFile struct {
+	kind  string
+	file  string
+	names []string
+}
+
+func createdRefFiles(c CreatedRefs) []refFile {
+	return []refFile{
+		{"categories", "exercise_categories.csv", c.Categories},
+		{"equipment", "equipment.csv", c.Equipment},
+		{"types", "exercise_types.csv", c.Types},
+		{"muscles", "muscle_groups.csv", c.Muscles},
+		{"tags", "tags.csv", c.Tags},
+	}
+}
+
+// describeCreatedRefs summarises newly created reference entities, e.g. "New: 2 muscles → muscle_groups.csv"
+func describeCreatedRefs(c CreatedRefs) string {
+	var lines []string
+	for _, rf := range createdRefFiles(c) {
+		if len(rf.names) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("New: %d %s → %s", len(rf.names), rf.kind, rf.file))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// appendCreatedRefs writes newly created reference names back into their data files
+func appendCreatedRefs(c CreatedRefs) error {
+	for _, rf := range createdRefFiles(c) {
+		if len(rf.names) == 0 {
+			continue
+		}
+		if err := appendNames(filepath.Join(dataDir, rf.file), rf.names); err != nil {
+			return fmt.Errorf("%s: %w", rf.file, err)
+		}
+	}
+	return nil
+}
+
+// skippedNamesFile is where the names an upload skipped are written, named
+// after the target table and the time of the upload
+func skippedNamesFile(uploadType UploadType) string {
+	table := uploadType.Table
+	if uploadType.Custom != nil {
+		table = uploadType.Custom.Table
+	}
+	return fmt.Sprintf("skipped_%s_%s.csv", table, time.Now().Format("20060102-1504"))
+}
+
+// writeNamesCSV writes names to path as a single-column CSV
+func writeNamesCSV(path string, names []string) error {
+	text, err := encodeNamesCSV(names)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	return os.WriteFile(path, []byte(text), 0o644)
+}
+
+// countPercents returns each count as a percentage of the total across all tables
+func countPercents(counts []int) []float64 {
+	total := 0
+	for _, c := range counts {
+		total += max(c, 0)
+	}
+
+	percents := make([]float64, len(counts))
+	if total == 0 {
+		return percents
+	}
+	for i, c := range counts {
+		percents[i] = float64(max(c, 0)) / float64(total) * 100
+	}
+	return percents
+}
+
+// joinNotes combines upload notes, one per line, skipping empty ones
+func joinNotes(notes ...string) string {
+	var kept []string
+	for _, note := range notes {
+		if note != "" {
+			kept = append(kept, note)
+		}
 	}
+	return strings.Join(kept, "\n")
+}
+
+// dropWarning reports rows that were read from the file but never reached the database
+func dropWarning(seen, handled int) string {
+	if seen == handled {
+		return ""
+	}
+	return fmt.Sprintf("\n⚠ %d of %d rows in the file were dropped during parsing", seen-handled, seen)
+}
+
+// modTimes stats each data file and returns its modification time
+func modTimes(files []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(files))
+	for _, name := range files {
+		info, err := os.Stat(filepath.Join(dataDir, name))
+		if err != nil {
+			continue
+		}
+		times[name] = info.ModTime()
+	}
+	return times
+}
 
+// applyFileFilter rebuilds the file list, keeping only files modified since the
+// last run when the recent-only filter is on
+func (m *model) applyFileFilter() {
 	var files []string
-	supportedExts := map[string]bool{
-		".csv":  true,
-		".json": true,
-		".yaml": true,
-		".yml":  true,
+	for _, name := range m.allFiles {
+		if m.recentOnly && !m.fileModTimes[name].After(m.lastRun) {
+			continue
+		}
+		files = append(files, name)
+	}
+	m.fileList = append(files, "Back")
+	m.fileChoice = 0
+}
+
+// formatLastRun describes the previous run time, which is zero on first use
+func formatLastRun(t time.Time) string {
+	if t.IsZero() {
+		return "the first run"
 	}
+	return t.Format("Jan 2 15:04")
+}
+
+// tempSuffixes mark editor swap, backup and temp copies of a data file
+var tempSuffixes = []string{".swp", ".swo", ".tmp", ".bak", "~"}
 
+// hiddenFile reports whether name is a dotfile or an editor or office
+// artifact, such as .ex.csv.swp, ex.csv~ or the ~$ex.xlsx lock file
+func hiddenFile(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") || strings.HasPrefix(name, "#") {
+		return true
+	}
+	for _, suffix := range tempSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// listDataFiles returns a sorted list of supported files in ./data/. Hidden
+// files are left out unless showHidden is set; artifacts are then listed
+// when the file they belong to would be, so ex.csv.swp shows but .DS_Store
+// doesn't.
+func listDataFiles(showHidden bool) ([]string, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 
 		name := entry.Name()
-		ext := strings.ToLower(filepath.Ext(name))
+		if hiddenFile(name) && !showHidden {
+			continue
+		}
+		base := name
+		for _, suffix := range tempSuffixes {
+			base = strings.TrimSuffix(base, suffix)
+		}
+		ext := strings.ToLower(filepath.Ext(base))
 
-		if supportedExts[ext] {
+		if dataExts[ext] || sqliteExts[ext] || isArchive(base) {
 			files = append(files, name)
 		}
 	}
@@ -305,21 +1819,119 @@ func listDataFiles() ([]string, error) {
 	return files, nil
 }
 
-func InitMenu(db *sql.DB) {
-	p := tea.NewProgram(initialModel(db))
-	if _, err := p.Run(); err != nil {
-		fmt.Println("Error starting program:", err)
-		os.Exit(1)
+// InitMenu runs the TUI until the user quits and returns a summary of the
+// uploads made during the session
+func InitMenu(db *sql.DB, connString string) (SessionSummary, error) {
+	p := tea.NewProgram(initialModel(db, connString))
+	_, err := p.Run()
+	return sessionSummary(), err
+}
+
+// countNA marks a count that wasn't taken because its table is absent or not
+// in COUNT_TABLES; it renders as "n/a"
+const countNA = -2
+
+// countTables limits the menu counts to these tables (COUNT_TABLES=exercise,equipment).
+// Empty counts every upload type's table.
+var countTables []string
+
+// countedTables is the upload type tables the menu counts
+func countedTables() []string {
+	var tables []string
+	for _, t := range uploadTypes {
+		if len(countTables) == 0 || slices.Contains(countTables, t.Table) {
+			tables = append(tables, t.Table)
+		}
+	}
+	return tables
+}
+
+// refreshCounts starts an asynchronous refresh of the database table counts,
+// cancelling any refresh already in flight
+func (m *model) refreshCounts() tea.Cmd {
+	m.cancelCountRefresh()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.refreshID++
+	m.countsLoading = true
+	m.countsErr = nil
+	m.cancelRefresh = cancel
+
+	id := m.refreshID
+	db := m.db
+	return func() tea.Msg {
+		defer cancel()
+		if db == nil {
+			return countsMsg{id: id, err: fmt.Errorf("%w: no open connection", errConnLost)}
+		}
+
+		pingCtx, pingCancel := context.WithTimeout(ctx, pingTimeout)
+		defer pingCancel()
+		if err := db.PingContext(pingCtx); err != nil && ctx.Err() == nil {
+			return countsMsg{id: id, err: fmt.Errorf("%w: %v", errConnLost, err)}
+		}
+
+		tables := countedTables()
+		exists, err := CheckSchema(ctx, db, tables)
+		if err != nil {
+			return countsMsg{id: id, err: err}
+		}
+
+		// Tables not configured, or absent from this schema (e.g. added by
+		// migrations that haven't been applied yet), aren't counted
+		var present []string
+		var index []int
+		counts := make([]int, len(uploadTypes))
+		lastImports := make([]sql.NullTime, len(uploadTypes))
+		for i, t := range uploadTypes {
+			counts[i] = countNA
+			if exists[t.Table] {
+				present = append(present, t.Table)
+				index = append(index, i)
+			}
+		}
+		found, last, err := getAllCountsAndLastImports(ctx, db, present)
+		if err != nil {
+			return countsMsg{id: id, err: err}
+		}
+		for j, i := range index {
+			counts[i], lastImports[i] = found[j], last[j]
+		}
+
+		version, err := SchemaVersion(ctx, db)
+		if err != nil {
+			logger.Warn("could not read schema version", "err", err)
+			version = -1
+		}
+		return countsMsg{id: id, counts: counts, lastImports: lastImports, schemaVersion: version}
+	}
+}
+
+// renderHeader is the app header followed by the connection status
+func (m model) renderHeader() string {
+	return RenderWithStatus(RenderAppHeader(m.schemaVersion),
+		RenderConnStatus(m.reconnecting, errors.Is(m.countsErr, errConnLost)))
+}
+
+// reconnectMsg carries a freshly opened database handle, or why opening failed
+type reconnectMsg struct {
+	db  *sql.DB
+	err error
+}
+
+// reconnect opens a new connection in the background
+func reconnect(connString string) tea.Cmd {
+	return func() tea.Msg {
+		db, err := OpenConnection(connString)
+		return reconnectMsg{db: db, err: err}
 	}
 }
 
-// refreshCounts updates the database table counts for display
-func (m *model) refreshCounts() {
-	m.counts = []int{
-		GetTableCount(m.db, "muscle_group"),
-		GetTableCount(m.db, "training_type"),
-		GetTableCount(m.db, "exercise_category"),
-		GetTableCount(m.db, "equipment"),
-		GetTableCount(m.db, "exercise"),
+// cancelCountRefresh aborts an in-flight count refresh, if any
+func (m *model) cancelCountRefresh() {
+	if m.cancelRefresh != nil {
+		m.cancelRefresh()
+		m.cancelRefresh = nil
 	}
+	m.countsLoading = false
 }
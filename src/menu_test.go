@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUploadTypesDriveMenu(t *testing.T) {
+	if got, want := len(menuOptions), len(uploadTypes)+3; got != want {
+		t.Fatalf("len(menuOptions) = %d, want %d (upload types, custom, junction, quit)", got, want)
+	}
+	for i, ut := range uploadTypes {
+		if menuOptions[i] != ut.Label {
+			t.Errorf("menuOptions[%d] = %q, want %q", i, menuOptions[i], ut.Label)
+		}
+		if got := (model{menuChoice: i}).selectedUploadType(); got.Table != ut.Table {
+			t.Errorf("menu choice %d dispatches to %q, want %q", i, got.Table, ut.Table)
+		}
+		if got := uploadTypeIndex(ut.Table); got != i {
+			t.Errorf("uploadTypeIndex(%q) = %d, want %d", ut.Table, got, i)
+		}
+	}
+	if menuOptions[customTableChoice] != "Upload to Custom Table" || menuOptions[junctionTableChoice] != "Upload to Junction Table" {
+		t.Errorf("custom and junction choices point at %q and %q", menuOptions[customTableChoice], menuOptions[junctionTableChoice])
+	}
+}
+
+func TestUploadTypesAreComplete(t *testing.T) {
+	tables := map[string]bool{}
+	for _, ut := range uploadTypes {
+		if ut.Label == "" || ut.Table == "" || len(ut.Header) == 0 {
+			t.Errorf("%+v is missing a label, table or header", ut)
+		}
+		if tables[ut.Table] {
+			t.Errorf("table %s is listed twice", ut.Table)
+		}
+		tables[ut.Table] = true
+		if ut.Parser == nil && ut.Upload == nil && ut.Table != "exercise" {
+			t.Errorf("%s has neither a parser nor an upload hook", ut.Table)
+		}
+	}
+}
+
+func TestCountedTablesFollowUploadTypes(t *testing.T) {
+	var all []string
+	for _, ut := range uploadTypes {
+		all = append(all, ut.Table)
+	}
+	tests := []struct {
+		name        string
+		countTables []string
+		want        []string
+	}{
+		{"every upload type by default", nil, all},
+		{"limited, in menu order", []string{"exercise", "equipment"}, []string{"equipment", "exercise"}},
+		{"unknown tables ignored", []string{"nope"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			countTables = tt.countTables
+			t.Cleanup(func() { countTables = nil })
+			if got := countedTables(); !slices.Equal(got, tt.want) {
+				t.Errorf("countedTables() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// mergePairLimit caps how many duplicate pairs the merge screen lists
+const mergePairLimit = 100
+
+// mergePageSize is how many duplicate pairs the merge screen shows at once
+const mergePageSize = 12
+
+// DuplicatePair is two entries of a reference table whose names nearly match,
+// ordered so From, the less used one, is merged into Into by default
+type DuplicatePair struct {
+	FromID, From string
+	FromUses     int
+	IntoID, Into string
+	IntoUses     int
+	Similarity   float64
+}
+
+// swapped returns the pair merging the other way round
+func (p DuplicatePair) swapped() DuplicatePair {
+	return DuplicatePair{
+		FromID: p.IntoID, From: p.Into, FromUses: p.IntoUses,
+		IntoID: p.FromID, Into: p.From, IntoUses: p.FromUses,
+		Similarity: p.Similarity,
+	}
+}
+
+// FindDuplicatePairs returns the pairs of live entries in a reference table
+// whose trigram similarity is at least threshold, most similar first, with how
+// many exercises use each side. Requires the pg_trgm extension.
+func FindDuplicatePairs(db *sql.DB, table string, threshold float64) ([]DuplicatePair, error) {
+	join, ok := dependentJoins[table]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a reference table", table)
+	}
+	live := ""
+	if softDeleteTables[table] {
+		live = " AND a.deleted_at IS NULL AND b.deleted_at IS NULL"
+	}
+	uses := func(alias string) string {
+		return fmt.Sprintf("(SELECT COUNT(*) FROM %s j WHERE j.%s = %s.id)", join.table, join.column, alias)
+	}
+	query := fmt.Sprintf(`SELECT a.id, a.name, %s, b.id, b.name, %s, similarity(a.name, b.name)
+		FROM %s a JOIN %s b ON a.name < b.name
+		WHERE similarity(a.name, b.name) >= $1%s
+		ORDER BY 7 DESC, a.name LIMIT %d`, uses("a"), uses("b"), table, table, live, mergePairLimit)
+	logSQL(query, threshold)
+	rows, err := db.Query(query, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs []DuplicatePair
+	for rows.Next() {
+		var p DuplicatePair
+		if err := rows.Scan(&p.FromID, &p.From, &p.FromUses, &p.IntoID, &p.Into, &p.IntoUses, &p.Similarity); err != nil {
+			return nil, err
+		}
+		if p.FromUses > p.IntoUses {
+			p = p.swapped()
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// MergeReference folds row fromID of a reference table into row toID in one
+// transaction: every row referencing fromID is repointed at toID and fromID is
+// deleted. Exercises linked to both keep a single link to toID. Ids are
+// strings, like every other reference id, so the merge works on serial and
+// PRIMARY_KEYS=uuid schemas alike.
+func MergeReference(db *sql.DB, table string, fromID, toID string) (err error) {
+	join, ok := dependentJoins[table]
+	if !ok {
+		return fmt.Errorf("%s is not a reference table", table)
+	}
+	if fromID == toID {
+		return errors.New("can't merge an entry into itself")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		logTxEnd(tx, table, "", 1, err)
+	}()
+
+	var found int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id IN ($1, $2)", table)
+	logSQL(query, fromID, toID)
+	if err := tx.QueryRow(query, fromID, toID).Scan(&found); err != nil {
+		return err
+	}
+	if found != 2 {
+		return fmt.Errorf("both entries must exist in %s", table)
+	}
+
+	for _, dep := range deleteCascades[table] {
+		var exists bool
+		query := `SELECT to_regclass($1) IS NOT NULL`
+		logSQL(query, dep.table)
+		if err := tx.QueryRow(query, dep.table).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+
+		// A junction's primary key would reject a second link to toID
+		if dep == join && dep.table != "exercise" {
+			query = fmt.Sprintf(`DELETE FROM %s WHERE %s = $1
+				AND exercise_id IN (SELECT exercise_id FROM %s WHERE %s = $2)`, dep.table, dep.column, dep.table, dep.column)
+			logSQL(query, fromID, toID)
+			if _, err := tx.Exec(query, fromID, toID); err != nil {
+				return fmt.Errorf("drop duplicate links in %s: %w", dep.table, err)
+			}
+		}
+		query = fmt.Sprintf("UPDATE %s SET %s = $2 WHERE %s = $1", dep.table, dep.column, dep.column)
+		logSQL(query, fromID, toID)
+		if _, err := tx.Exec(query, fromID, toID); err != nil {
+			return fmt.Errorf("repoint %s: %w", dep.table, err)
+		}
+	}
+
+	if err := mergeParents(tx, table, fromID, toID); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE id = $1", table)
+	logSQL(query, fromID)
+	if _, err := tx.Exec(query, fromID); err != nil {
+		return fmt.Errorf("delete from %s: %w", table, err)
+	}
+	logRow(tx, rowEvent{Table: table, Name: fromID + " → " + toID, Outcome: rowMerged})
+	return nil
+}
+
+// mergeParents moves the children of fromID under toID in tables with a
+// parent_id hierarchy (migration 0010). When toID descends from fromID, the
+// child of fromID on that path, toID itself or one of its ancestors, is
+// detached instead: moving it under toID would make it its own ancestor.
+func mergeParents(tx *sql.Tx, table, fromID, toID string) error {
+	var hasParent bool
+	query := `SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'parent_id')`
+	logSQL(query, table)
+	if err := tx.QueryRow(query, table).Scan(&hasParent); err != nil {
+		return err
+	}
+	if !hasParent {
+		return nil
+	}
+
+	// Like setParents' cycle check, UNION stops on a loop already in the table
+	query = fmt.Sprintf(`WITH RECURSIVE up AS (
+			SELECT id, parent_id FROM %s WHERE id = $2
+			UNION
+			SELECT t.id, t.parent_id FROM %s t JOIN up ON t.id = up.parent_id
+		)
+		UPDATE %s SET parent_id = NULL WHERE parent_id = $1 AND id IN (SELECT id FROM up)`, table, table, table)
+	logSQL(query, fromID, toID)
+	if _, err := tx.Exec(query, fromID, toID); err != nil {
+		return fmt.Errorf("detach %s: %w", table, err)
+	}
+	query = fmt.Sprintf("UPDATE %s SET parent_id = $2 WHERE parent_id = $1", table)
+	logSQL(query, fromID, toID)
+	if _, err := tx.Exec(query, fromID, toID); err != nil {
+		return fmt.Errorf("repoint %s children: %w", table, err)
+	}
+	return nil
+}
+
+// openMerge lists the near-duplicate pairs of a reference table for merging
+func openMerge(m model, table string) (tea.Model, tea.Cmd) {
+	threshold := similarityThreshold
+	if threshold == 0 {
+		threshold = refSuggestThreshold
+	}
+	pairs, err := FindDuplicatePairs(m.db, table, threshold)
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error finding duplicates in %s (needs pg_trgm, see --migrate): %v\nPress enter or q to return to menu.", table, err)
+		m.isError = true
+		return m, nil
+	}
+	m.mergeTable, m.mergePairs, m.mergeChoice = table, pairs, 0
+	m.mergeConfirm, m.mergeNote = false, ""
+	m.state = stateMerge
+	return m, nil
+}
+
+func updateMerge(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.mergeConfirm {
+		switch key.String() {
+		case "y":
+			p := m.mergePairs[m.mergeChoice]
+			m.mergeConfirm = false
+			if err := MergeReference(m.db, m.mergeTable, p.FromID, p.IntoID); err != nil {
+				m.mergeNote = RenderAuditFailure(fmt.Sprintf("Merge failed, nothing was changed: %v", err))
+				return m, nil
+			}
+			next, cmd := openMerge(m, m.mergeTable)
+			if nm, ok := next.(model); ok && nm.state == stateMerge {
+				nm.mergeNote = fmt.Sprintf("Merged %s into %s.", p.From, p.Into)
+				nm.mergeChoice = min(m.mergeChoice, max(0, len(nm.mergePairs)-1))
+				return nm, cmd
+			}
+			return next, cmd
+		case "n", "q", "esc":
+			m.mergeConfirm = false
+		}
+		return m, nil
+	}
+
+	switch key.String() {
+	case "up", "k":
+		if m.mergeChoice > 0 {
+			m.mergeChoice--
+		}
+	case "down", "j":
+		if m.mergeChoice < len(m.mergePairs)-1 {
+			m.mergeChoice++
+		}
+	case "s":
+		if len(m.mergePairs) > 0 {
+			m.mergePairs[m.mergeChoice] = m.mergePairs[m.mergeChoice].swapped()
+		}
+	case "enter":
+		if len(m.mergePairs) > 0 {
+			m.mergeConfirm, m.mergeNote = true, ""
+		}
+	case "q", "esc":
+		m.state = stateMenu
+		m.mergePairs, m.mergeNote = nil, ""
+	}
+	return m, nil
+}
+
+func viewMerge(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Merge duplicates in "+m.mergeTable))
+	parts = append(parts, "")
+	if len(m.mergePairs) == 0 {
+		parts = append(parts, RenderHelpText("No near-duplicate names found"))
+	}
+
+	offset := max(0, m.mergeChoice-mergePageSize+1)
+	end := min(offset+mergePageSize, len(m.mergePairs))
+	for i := offset; i < end; i++ {
+		p := m.mergePairs[i]
+		label := fmt.Sprintf("%s (%d) → %s (%d)  %.2f", p.From, p.FromUses, p.Into, p.IntoUses, p.Similarity)
+		parts = append(parts, RenderFileItem(label, i == m.mergeChoice, false))
+	}
+
+	if m.mergeConfirm {
+		p := m.mergePairs[m.mergeChoice]
+		parts = append(parts, "", RenderErrorMessage(fmt.Sprintf("Merge %s into %s? %d exercises move to %s and %s is deleted.",
+			p.From, p.Into, p.FromUses, p.Into, p.From)))
+	} else if m.mergeNote != "" {
+		parts = append(parts, "", m.mergeNote)
+	}
+
+	parts = append(parts, "")
+	if m.mergeConfirm {
+		parts = append(parts, RenderHelpText("Merge: y • Cancel: n/esc"))
+	} else {
+		parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Merge left into right: enter • Swap direction: s • Back: q/esc"))
+	}
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// refLink is one junction row: an exercise linked to a reference entry
+type refLink struct {
+	exercise, ref string
+}
+
+// mergeStore is an in-memory reference table with the rows pointing at it,
+// answering MergeReference's statements
+type mergeStore struct {
+	ids       map[string]bool
+	links     map[string][]refLink // junction table -> rows
+	synonyms  map[string]string    // synonym -> muscle id
+	hasParent bool
+	parent    map[string]string // id -> parent id
+}
+
+var (
+	mergeCountPattern  = regexp.MustCompile(`^SELECT COUNT\(\*\) FROM \w+ WHERE id IN`)
+	mergeDedupePattern = regexp.MustCompile(`^DELETE FROM (\w+) WHERE \w+ = \$1\s+AND exercise_id IN`)
+	mergeUpdatePattern = regexp.MustCompile(`^UPDATE (\w+) SET (\w+) = \$2 WHERE \w+ = \$1$`)
+	mergeDeletePattern = regexp.MustCompile(`^DELETE FROM \w+ WHERE id = \$1$`)
+)
+
+func (s *mergeStore) respond(query string, args []driver.Value) fakeResult {
+	str := func(i int) string { return args[i].(string) }
+	switch {
+	case mergeCountPattern.MatchString(query):
+		n := 0
+		for i := range args {
+			if s.ids[str(i)] {
+				n++
+			}
+		}
+		return fakeResult{columns: []string{"count"}, rows: [][]driver.Value{{int64(n)}}}
+	case strings.Contains(query, "to_regclass"):
+		return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{true}}}
+	case strings.Contains(query, "information_schema.columns"):
+		return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{s.hasParent}}}
+	case strings.Contains(query, "SET parent_id = NULL WHERE parent_id = $1 AND id IN (SELECT id FROM up)"):
+		// Detach whichever of toID and its ancestors sits directly under fromID
+		seen := map[string]bool{}
+		for id := str(1); id != "" && !seen[id]; id = s.parent[id] {
+			seen[id] = true
+			if s.parent[id] == str(0) {
+				delete(s.parent, id)
+				break
+			}
+		}
+	case strings.Contains(query, "SET parent_id = $2 WHERE parent_id = $1"):
+		for child, parent := range s.parent {
+			if parent == str(0) {
+				s.parent[child] = str(1)
+			}
+		}
+	case mergeDedupePattern.MatchString(query):
+		table := mergeDedupePattern.FindStringSubmatch(query)[1]
+		s.links[table] = slices.DeleteFunc(s.links[table], func(l refLink) bool {
+			return l.ref == str(0) && slices.Contains(s.links[table], refLink{l.exercise, str(1)})
+		})
+	case mergeUpdatePattern.MatchString(query):
+		table := mergeUpdatePattern.FindStringSubmatch(query)[1]
+		if table == "muscle_synonyms" {
+			for synonym, id := range s.synonyms {
+				if id == str(0) {
+					s.synonyms[synonym] = str(1)
+				}
+			}
+			break
+		}
+		for i, l := range s.links[table] {
+			if l.ref == str(0) {
+				s.links[table][i].ref = str(1)
+			}
+		}
+	case mergeDeletePattern.MatchString(query):
+		delete(s.ids, str(0))
+	}
+	return fakeResult{}
+}
+
+func TestMergeReferenceDropsDuplicateLinks(t *testing.T) {
+	store := &mergeStore{
+		ids: map[string]bool{"db": true, "dbs": true, "bar": true},
+		links: map[string][]refLink{"exercise_equipment": {
+			{"curl", "dbs"},  // moves to db
+			{"press", "dbs"}, // press already uses db: dropped
+			{"press", "db"},
+			{"row", "bar"},
+		}},
+	}
+	db, rec := openFakeDB(t, store.respond)
+
+	if err := MergeReference(db, "equipment", "dbs", "db"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []refLink{{"curl", "db"}, {"press", "db"}, {"row", "bar"}}
+	if got := store.links["exercise_equipment"]; !slices.Equal(got, want) {
+		t.Errorf("links = %v, want %v", got, want)
+	}
+	if store.ids["dbs"] || !store.ids["db"] {
+		t.Errorf("ids = %v, want dbs deleted and db kept", store.ids)
+	}
+	statements := rec.Statements()
+	if last := statements[len(statements)-1].Query; last != "COMMIT" {
+		t.Errorf("transaction ended with %s, want COMMIT", last)
+	}
+}
+
+func TestMergeReferenceMuscles(t *testing.T) {
+	store := &mergeStore{
+		ids:       map[string]bool{"chest": true, "pecs": true, "upper": true, "torso": true},
+		links:     map[string][]refLink{"exercise_muscles": {{"bench", "pecs"}, {"bench", "chest"}, {"fly", "pecs"}}},
+		synonyms:  map[string]string{"pectorals": "pecs"},
+		hasParent: true,
+		// pecs sits under torso and chest under pecs; upper chest is a child of pecs
+		parent: map[string]string{"pecs": "torso", "chest": "pecs", "upper": "pecs"},
+	}
+	db, _ := openFakeDB(t, store.respond)
+
+	if err := MergeReference(db, "muscle_group", "pecs", "chest"); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []refLink{{"bench", "chest"}, {"fly", "chest"}}; !slices.Equal(store.links["exercise_muscles"], want) {
+		t.Errorf("links = %v, want %v", store.links["exercise_muscles"], want)
+	}
+	if store.synonyms["pectorals"] != "chest" {
+		t.Errorf("synonym points at %q, want chest", store.synonyms["pectorals"])
+	}
+	if parent, ok := store.parent["chest"]; ok {
+		t.Errorf("chest kept parent %q; merging its parent into it must not make it its own parent", parent)
+	}
+	if store.parent["upper"] != "chest" {
+		t.Errorf("upper chest's parent = %q, want chest", store.parent["upper"])
+	}
+}
+
+func TestMergeReferenceIntoDescendant(t *testing.T) {
+	store := &mergeStore{
+		ids:       map[string]bool{"torso": true, "chest": true, "upper": true, "back": true},
+		links:     map[string][]refLink{},
+		hasParent: true,
+		// upper chest is a grandchild of torso, the entry merged into it
+		parent: map[string]string{"chest": "torso", "upper": "chest", "back": "torso"},
+	}
+	db, _ := openFakeDB(t, store.respond)
+
+	if err := MergeReference(db, "muscle_group", "torso", "upper"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"upper": "chest", "back": "upper"}
+	if len(store.parent) != len(want) || store.parent["upper"] != "chest" || store.parent["back"] != "upper" {
+		t.Errorf("parents = %v, want %v: chest must be detached rather than moved under its own child", store.parent, want)
+	}
+}
+
+func TestMergeReferenceErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		from, to string
+		want     string
+	}{
+		{"same entry", "equipment", "db", "db", "into itself"},
+		{"missing entry", "equipment", "db", "gone", "must exist"},
+		{"not a reference table", "exercise", "a", "b", "not a reference table"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mergeStore{ids: map[string]bool{"db": true}, links: map[string][]refLink{}}
+			db, rec := openFakeDB(t, store.respond)
+
+			err := MergeReference(db, tt.table, tt.from, tt.to)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.want)
+			}
+			if deletes := statementsMatching(rec, "DELETE"); len(deletes) != 0 {
+				t.Errorf("deleted despite the error: %v", deletes)
+			}
+			if commits := statementsMatching(rec, "COMMIT"); len(commits) != 0 {
+				t.Error("committed despite the error")
+			}
+		})
+	}
+}
+
+func TestFindDuplicatePairsMergesLessUsedEntry(t *testing.T) {
+	db, rec := openFakeDB(t, func(query string, args []driver.Value) fakeResult {
+		return fakeResult{
+			columns: []string{"a_id", "a", "a_uses", "b_id", "b", "b_uses", "similarity"},
+			rows: [][]driver.Value{
+				{"1", "Dumbbell", int64(12), "2", "Dumbbells", int64(3), 0.8},
+				{"3", "Band", int64(0), "4", "Bands", int64(5), 0.7},
+			},
+		}
+	})
+
+	pairs, err := FindDuplicatePairs(db, "equipment", 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DuplicatePair{
+		{FromID: "2", From: "Dumbbells", FromUses: 3, IntoID: "1", Into: "Dumbbell", IntoUses: 12, Similarity: 0.8},
+		{FromID: "3", From: "Band", FromUses: 0, IntoID: "4", Into: "Bands", IntoUses: 5, Similarity: 0.7},
+	}
+	if !slices.Equal(pairs, want) {
+		t.Errorf("pairs = %+v, want %+v", pairs, want)
+	}
+	if got := pairs[0].swapped().swapped(); got != pairs[0] {
+		t.Errorf("swapping twice gave %+v", got)
+	}
+	if query := rec.Statements()[0].Query; !strings.Contains(query, "deleted_at IS NULL") || !strings.Contains(query, "exercise_equipment") {
+		t.Errorf("query should skip soft-deleted rows and count equipment links:\n%s", query)
+	}
+}
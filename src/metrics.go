@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// metricsAddr is where the pool stats are served over HTTP, if set (METRICS_ADDR)
+var metricsAddr string
+
+// poolStats is the JSON form of sql.DBStats
+type poolStats struct {
+	MaxOpen       int     `json:"max_open_connections"`
+	Open          int     `json:"open_connections"`
+	InUse         int     `json:"in_use"`
+	Idle          int     `json:"idle"`
+	WaitCount     int64   `json:"wait_count"`
+	WaitSeconds   float64 `json:"wait_duration_seconds"`
+	MaxIdleClosed int64   `json:"max_idle_closed"`
+	MaxLifeClosed int64   `json:"max_lifetime_closed"`
+}
+
+func newPoolStats(s sql.DBStats) poolStats {
+	return poolStats{
+		MaxOpen:       s.MaxOpenConnections,
+		Open:          s.OpenConnections,
+		InUse:         s.InUse,
+		Idle:          s.Idle,
+		WaitCount:     s.WaitCount,
+		WaitSeconds:   s.WaitDuration.Seconds(),
+		MaxIdleClosed: s.MaxIdleClosed,
+		MaxLifeClosed: s.MaxLifetimeClosed,
+	}
+}
+
+// prometheusText renders the pool stats in the Prometheus text exposition format
+func (s poolStats) prometheusText() string {
+	var b strings.Builder
+	metric := func(name, kind, help string, value any) {
+		fmt.Fprintf(&b, "# HELP fitrkr_db_%s %s\n# TYPE fitrkr_db_%s %s\nfitrkr_db_%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("max_open_connections", "gauge", "Maximum number of open connections.", s.MaxOpen)
+	metric("open_connections", "gauge", "Established connections, in use and idle.", s.Open)
+	metric("in_use_connections", "gauge", "Connections currently in use.", s.InUse)
+	metric("idle_connections", "gauge", "Idle connections.", s.Idle)
+	metric("wait_count_total", "counter", "Connections waited for.", s.WaitCount)
+	metric("wait_duration_seconds_total", "counter", "Total time blocked waiting for a connection.", s.WaitSeconds)
+	metric("max_idle_closed_total", "counter", "Connections closed due to the idle limit.", s.MaxIdleClosed)
+	metric("max_lifetime_closed_total", "counter", "Connections closed due to the lifetime limit.", s.MaxLifeClosed)
+	return b.String()
+}
+
+// StartMetricsServer serves the pool stats of the handle db returns on addr:
+// Prometheus text at /metrics and JSON at /stats. db is asked on every scrape,
+// so a reconnect is picked up. The returned function shuts the server down.
+func StartMetricsServer(db func() *sql.DB, addr string) (func(), error) {
+	mux := metricsHandler(db)
+
+	// Listen up front so a bad address fails at startup, not in the background
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return func() {}, err
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server", "err", err)
+		}
+	}()
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}, nil
+}
+
+// metricsHandler routes /metrics and /stats to the stats of db's current handle
+func metricsHandler(db func() *sql.DB) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, newPoolStats(db().Stats()).prometheusText())
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newPoolStats(db().Stats()))
+	})
+	return mux
+}
+
+// serveMetrics starts the pool stats endpoint when METRICS_ADDR is set and
+// returns the function that stops it
+func serveMetrics(db func() *sql.DB) func() {
+	if metricsAddr == "" {
+		return func() {}
+	}
+	stop, err := StartMetricsServer(db, metricsAddr)
+	if err != nil {
+		log.Fatalf("Could not serve metrics on %s: %v", metricsAddr, err)
+	}
+	return stop
+}
+
+// describePoolStats summarises the pool for the dashboard
+func describePoolStats(s sql.DBStats) string {
+	return fmt.Sprintf("Pool: %d open • %d in use • %d idle • %d waits (%s)",
+		s.OpenConnections, s.InUse, s.Idle, s.WaitCount, s.WaitDuration.Round(time.Millisecond))
+}
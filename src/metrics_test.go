@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsFollowReconnect(t *testing.T) {
+	first, _ := openFakeDB(t, nil)
+	first.SetMaxOpenConns(3)
+	currentDB.Store(first)
+	t.Cleanup(func() { currentDB.Store(nil) })
+	handler := metricsHandler(currentDB.Load)
+
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		return rec.Body.String()
+	}
+	if body := scrape(); !strings.Contains(body, "fitrkr_db_max_open_connections 3\n") {
+		t.Fatalf("before reconnect:\n%s", body)
+	}
+
+	second, _ := openFakeDB(t, nil)
+	second.SetMaxOpenConns(7)
+	next, _ := initialModel(first, "").Update(reconnectMsg{db: second})
+
+	if m := next.(model); m.db != second || currentDB.Load() != second {
+		t.Fatal("reconnect didn't switch to the new handle")
+	}
+	if err := first.Ping(); err == nil {
+		t.Error("the replaced handle is still open")
+	}
+	if body := scrape(); !strings.Contains(body, "fitrkr_db_max_open_connections 7\n") {
+		t.Errorf("after reconnect the metrics still read the old pool:\n%s", body)
+	}
+}
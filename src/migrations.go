@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles are the numbered forward migrations, named NNNN_description.sql.
+// Every statement must be safe to re-run, as databases bootstrapped before
+// schema_migrations existed replay them once.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// uuidKeysSQL converts the managed tables to UUID primary keys. It sits
+// outside the numbered migrations because only PRIMARY_KEYS=uuid applies it.
+//
+//go:embed migrations/optional/uuid_keys.sql
+var uuidKeysSQL string
+
+// Primary key strategies (PRIMARY_KEYS)
+const (
+	primaryKeysSerial = "serial"
+	primaryKeysUUID   = "uuid"
+)
+
+// primaryKeys is how new rows get their ids: serial integers, as the base
+// schema creates them, or gen_random_uuid() once migrate has converted the
+// schema. Ids are read back as strings either way.
+var primaryKeys = primaryKeysSerial
+
+// ParsePrimaryKeys reads the PRIMARY_KEYS setting, defaulting to serial
+func ParsePrimaryKeys(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", primaryKeysSerial:
+		return primaryKeysSerial, nil
+	case primaryKeysUUID:
+		return primaryKeysUUID, nil
+	}
+	return "", fmt.Errorf("expected serial or uuid, got %q", s)
+}
+
+// ConvertToUUIDKeys switches the schema to UUID primary keys in one
+// transaction. It only runs on empty tables and does nothing once converted.
+func ConvertToUUIDKeys(db *sql.DB) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	logSQL(uuidKeysSQL)
+	_, err = tx.Exec(uuidKeysSQL)
+	return err
+}
+
+// migration is one numbered SQL file
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadMigrations reads the embedded migration files ordered by version
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		number, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s is not named NNNN_description.sql", entry.Name())
+		}
+		data, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{version: version, name: name, sql: string(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	for i, mig := range migrations {
+		if mig.version != i+1 {
+			return nil, fmt.Errorf("migration versions must be consecutive from 1, found %04d", mig.version)
+		}
+	}
+	return migrations, nil
+}
+
+// latestSchemaVersion is the version the embedded migrations bring the schema to
+func latestSchemaVersion() int {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0
+	}
+	return len(migrations)
+}
+
+// MigrationReport records which migrations were applied by this run
+type MigrationReport struct {
+	Created []string
+	Existed []string
+	Failed  []string
+}
+
+func (r MigrationReport) String() string {
+	var b strings.Builder
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%s (%d):\n", title, len(items))
+		for _, item := range items {
+			fmt.Fprintf(&b, "  - %s\n", item)
+		}
+	}
+	section("Applied", r.Created)
+	section("Already applied", r.Existed)
+	section("Failed", r.Failed)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+const createSchemaMigrationsQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+// Migrate applies every migration not yet recorded in schema_migrations, in
+// order, each in its own transaction. A failing migration is rolled back and
+// stops the run; the ones before it stay applied.
+func Migrate(db *sql.DB) (report MigrationReport, err error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return report, err
+	}
+
+	logSQL(createSchemaMigrationsQuery)
+	if _, err := db.Exec(createSchemaMigrationsQuery); err != nil {
+		return report, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return report, err
+	}
+
+	for _, mig := range migrations {
+		label := fmt.Sprintf("%04d %s", mig.version, mig.name)
+		if applied[mig.version] {
+			report.Existed = append(report.Existed, label)
+			continue
+		}
+		if err := applyMigration(db, mig); err != nil {
+			report.Failed = append(report.Failed, label)
+			return report, fmt.Errorf("migration %s: %w", label, err)
+		}
+		report.Created = append(report.Created, label)
+	}
+	return report, nil
+}
+
+// migrationLockKey is the pg_advisory_lock key that serialises migration runs
+// across instances; the bytes spell "fitrkrmg"
+const migrationLockKey int64 = 0x666974726b726d67
+
+// migrationLockPoll is how often a waiting instance retries the lock
+const migrationLockPoll = 500 * time.Millisecond
+
+// migrationLockTimeout is how long to wait for another instance to finish
+// migrating before giving up (MIGRATION_LOCK_TIMEOUT)
+var migrationLockTimeout = time.Minute
+
+// errMigrationLock marks a failure to take the migration lock, as opposed to a
+// failing migration
+var errMigrationLock = errors.New("migration lock")
+
+// withMigrationLock runs fn while holding the migration advisory lock, so
+// concurrent instances migrate one at a time. The lock belongs to one pooled
+// connection and is released when fn returns.
+func withMigrationLock(db *sql.DB, fn func() error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationLockTimeout)
+	defer cancel()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errMigrationLock, err)
+	}
+	defer conn.Close()
+
+	logged := false
+	for {
+		var locked bool
+		query := `SELECT pg_try_advisory_lock($1)`
+		logSQL(query, migrationLockKey)
+		if err := conn.QueryRowContext(ctx, query, migrationLockKey).Scan(&locked); err != nil {
+			return fmt.Errorf("%w: %w", errMigrationLock, err)
+		}
+		if locked {
+			break
+		}
+		if !logged {
+			logger.Info("waiting for another instance to finish migrating", "timeout", migrationLockTimeout)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: another instance is still migrating after %s; try again later or raise MIGRATION_LOCK_TIMEOUT", errMigrationLock, migrationLockTimeout)
+		case <-time.After(migrationLockPoll):
+		}
+	}
+
+	defer func() {
+		query := `SELECT pg_advisory_unlock($1)`
+		logSQL(query, migrationLockKey)
+		if _, err := conn.ExecContext(context.Background(), query, migrationLockKey); err != nil {
+			// Drop the connection rather than return it to the pool still
+			// holding the lock
+			logger.Warn("could not release the migration lock", "err", err)
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+	return fn()
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations
+func appliedMigrations(db *sql.DB) (map[int]bool, error) {
+	query := `SELECT version FROM schema_migrations`
+	logSQL(query)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration runs one migration and records it in the same transaction
+func applyMigration(db *sql.DB, mig migration) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	logSQL(mig.sql)
+	if _, err := tx.Exec(mig.sql); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+	logSQL(query, mig.version, mig.name)
+	_, err = tx.Exec(query, mig.version, mig.name)
+	return err
+}
+
+// SchemaVersion returns the highest applied migration, or 0 when the schema
+// has never been migrated
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	query := `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	logSQL(query)
+	if err := db.QueryRowContext(ctx, query).Scan(&exists); err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	query = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	logSQL(query)
+	err := db.QueryRowContext(ctx, query).Scan(&version)
+	return version, err
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS tags (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS exercise_tags (
+	exercise_id INT NOT NULL REFERENCES exercise(id) ON DELETE CASCADE,
+	tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (exercise_id, tag_id)
+);
@@ -0,0 +1,17 @@
+ALTER TABLE muscle_group ADD COLUMN IF NOT EXISTS source_file TEXT;
+ALTER TABLE muscle_group ADD COLUMN IF NOT EXISTS imported_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE training_type ADD COLUMN IF NOT EXISTS source_file TEXT;
+ALTER TABLE training_type ADD COLUMN IF NOT EXISTS imported_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE exercise_category ADD COLUMN IF NOT EXISTS source_file TEXT;
+ALTER TABLE exercise_category ADD COLUMN IF NOT EXISTS imported_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE equipment ADD COLUMN IF NOT EXISTS source_file TEXT;
+ALTER TABLE equipment ADD COLUMN IF NOT EXISTS imported_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE exercise ADD COLUMN IF NOT EXISTS source_file TEXT;
+ALTER TABLE exercise ADD COLUMN IF NOT EXISTS imported_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS source_file TEXT;
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS imported_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1 @@
+ALTER TABLE exercise ADD COLUMN IF NOT EXISTS content_hash TEXT;
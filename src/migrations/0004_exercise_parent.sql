@@ -0,0 +1 @@
+ALTER TABLE exercise ADD COLUMN IF NOT EXISTS parent_id INT REFERENCES exercise(id) ON DELETE SET NULL;
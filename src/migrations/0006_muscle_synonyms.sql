@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS muscle_synonyms (
+	synonym TEXT PRIMARY KEY,
+	muscle_group_id INT NOT NULL REFERENCES muscle_group(id) ON DELETE CASCADE,
+	source_file TEXT,
+	imported_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,21 @@
+-- Make the exercise junction tables' foreign keys deferrable so a bulk import
+-- can check them once at commit (DEFER_CONSTRAINTS=1). They stay
+-- INITIALLY IMMEDIATE, so other writes behave as before.
+DO $$
+DECLARE
+	c RECORD;
+BEGIN
+	FOR c IN
+		SELECT conrelid::regclass AS tbl, conname
+		FROM pg_constraint
+		WHERE contype = 'f'
+			AND NOT condeferrable
+			AND conrelid IN (
+				SELECT to_regclass(t) FROM unnest(ARRAY[
+					'exercise_equipment', 'exercise_training_types', 'exercise_muscles', 'exercise_tags'
+				]) AS t
+			)
+	LOOP
+		EXECUTE format('ALTER TABLE %s ALTER CONSTRAINT %I DEFERRABLE INITIALLY IMMEDIATE', c.tbl, c.conname);
+	END LOOP;
+END $$;
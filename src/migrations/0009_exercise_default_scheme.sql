@@ -0,0 +1,2 @@
+-- Default sets x reps scheme for an exercise, e.g. 3x8-12, NULL when not given
+ALTER TABLE exercise ADD COLUMN IF NOT EXISTS default_scheme TEXT;
@@ -0,0 +1,14 @@
+-- Let muscle groups form a hierarchy (Chest → Upper Chest). parent_id takes
+-- the type of muscle_group.id, so it also applies to a schema already
+-- converted to UUID keys.
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = ANY(current_schemas(false)) AND table_name = 'muscle_group' AND column_name = 'parent_id'
+	) THEN
+		EXECUTE format('ALTER TABLE muscle_group ADD COLUMN parent_id %s REFERENCES muscle_group(id) ON DELETE SET NULL',
+			(SELECT format_type(atttypid, atttypmod) FROM pg_attribute
+				WHERE attrelid = 'muscle_group'::regclass AND attname = 'id'));
+	END IF;
+END $$;
@@ -0,0 +1,68 @@
+-- Switch the reference tables and exercise to UUID primary keys generated by
+-- gen_random_uuid(), for schemas seeded into systems keyed by UUID
+-- (PRIMARY_KEYS=uuid). Foreign keys pointing at them are dropped, retyped and
+-- re-added. Existing ids can't be mapped, so it refuses to run once any of the
+-- tables has rows. Tables whose id is already a uuid are left alone, so it is
+-- safe to re-run.
+DO $$
+DECLARE
+	keyed regclass[];
+	t regclass;
+	c RECORD;
+	fks text[] := '{}';
+	has_rows boolean;
+	seq text;
+	i int;
+BEGIN
+	SELECT array_agg(a.attrelid::regclass) INTO keyed
+	FROM pg_attribute a
+	WHERE a.attrelid IN (
+			SELECT to_regclass(name) FROM unnest(ARRAY[
+				'muscle_group', 'training_type', 'exercise_category', 'equipment', 'tags', 'exercise'
+			]) AS name
+		)
+		AND a.attname = 'id'
+		AND a.atttypid <> 'uuid'::regtype;
+	IF keyed IS NULL THEN
+		RETURN;
+	END IF;
+
+	FOREACH t IN ARRAY keyed LOOP
+		EXECUTE format('SELECT EXISTS (SELECT 1 FROM %s)', t) INTO has_rows;
+		IF has_rows THEN
+			RAISE EXCEPTION '% already has rows; UUID keys can only be adopted on an empty schema', t;
+		END IF;
+	END LOOP;
+
+	-- Drop every foreign key referencing one of the ids, retyping its columns
+	FOR c IN
+		SELECT con.conrelid::regclass AS tbl, con.conname, pg_get_constraintdef(con.oid) AS def,
+			ARRAY(SELECT attname FROM pg_attribute WHERE attrelid = con.conrelid AND attnum = ANY(con.conkey)) AS cols
+		FROM pg_constraint con
+		WHERE con.contype = 'f' AND con.confrelid = ANY(keyed)
+	LOOP
+		EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', c.tbl, c.conname);
+		FOR i IN 1 .. array_length(c.cols, 1) LOOP
+			EXECUTE format('ALTER TABLE %s ALTER COLUMN %I TYPE uuid USING NULL', c.tbl, c.cols[i]);
+		END LOOP;
+		fks := fks || format('ALTER TABLE %s ADD CONSTRAINT %I %s', c.tbl, c.conname, c.def);
+	END LOOP;
+
+	FOREACH t IN ARRAY keyed LOOP
+		IF EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = t AND attname = 'id' AND attidentity <> '') THEN
+			EXECUTE format('ALTER TABLE %s ALTER COLUMN id DROP IDENTITY', t);
+		ELSE
+			seq := pg_get_serial_sequence(t::text, 'id');
+			EXECUTE format('ALTER TABLE %s ALTER COLUMN id DROP DEFAULT', t);
+			IF seq IS NOT NULL THEN
+				EXECUTE format('DROP SEQUENCE %s', seq);
+			END IF;
+		END IF;
+		EXECUTE format('ALTER TABLE %s ALTER COLUMN id TYPE uuid USING gen_random_uuid()', t);
+		EXECUTE format('ALTER TABLE %s ALTER COLUMN id SET DEFAULT gen_random_uuid()', t);
+	END LOOP;
+
+	FOR i IN 1 .. coalesce(array_length(fks, 1), 0) LOOP
+		EXECUTE fks[i];
+	END LOOP;
+END $$;
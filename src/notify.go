@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// webhookTimeout bounds each webhook POST attempt
+const webhookTimeout = 10 * time.Second
+
+// UploadResult is the summary of a single upload, posted to NOTIFY_WEBHOOK after headless runs
+type UploadResult struct {
+	Type     string `json:"type"`
+	File     string `json:"file"`
+	Parsed   int    `json:"parsed"`
+	Inserted int    `json:"inserted"`
+	Skipped  int    `json:"skipped"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	// Duration is how long the insert took, in nanoseconds in JSON
+	Duration time.Duration `json:"duration_ns,omitempty"`
+	// Created lists reference entities an exercises upload created
+	Created CreatedRefs `json:"created,omitzero"`
+	// Failed lists names left out because inserting them failed, with
+	// BATCH_FALLBACK
+	Failed []string `json:"failed,omitempty"`
+	// NoEquipment counts exercise rows whose equipment was a no-equipment
+	// value such as None
+	NoEquipment int `json:"no_equipment,omitempty"`
+	// Malformed lists exercise rows left out because they were too short or
+	// had no name
+	Malformed []string `json:"malformed,omitempty"`
+	// Unresolved lists "name → parent" pairs whose parent couldn't be set
+	Unresolved []string `json:"unresolved_parents,omitempty"`
+	// Offline is set for uploads run against the offline fake, which
+	// stores nothing
+	Offline bool `json:"offline,omitempty"`
+}
+
+func (r UploadResult) String() string {
+	prefix := ""
+	if r.Offline {
+		prefix = "offline: "
+	}
+	if !r.Success {
+		return fmt.Sprintf("%s%s: failed: %s", prefix, r.File, r.Error)
+	}
+	s := prefix + fmt.Sprintf("%s: %d inserted, %d skipped (%s)", r.File, r.Inserted, r.Skipped, r.Type)
+	if len(r.Failed) > 0 {
+		s = prefix + fmt.Sprintf("%s: %d inserted, %d skipped, %d failed: %s (%s)", r.File, r.Inserted, r.Skipped, len(r.Failed), strings.Join(r.Failed, ", "), r.Type)
+	}
+	if r.NoEquipment > 0 {
+		s += fmt.Sprintf("; %d need no equipment", r.NoEquipment)
+	}
+	if len(r.Malformed) > 0 {
+		s += fmt.Sprintf("; %d malformed rows skipped: %s", len(r.Malformed), strings.Join(r.Malformed, ", "))
+	}
+	if len(r.Unresolved) > 0 {
+		s += fmt.Sprintf("; %d unresolved parents: %s", len(r.Unresolved), strings.Join(r.Unresolved, ", "))
+	}
+	return s
+}
+
+// postSummary POSTs the upload result as JSON to url, retrying once on failure.
+// It is a no-op when url is empty.
+func postSummary(url string, result UploadResult) error {
+	if url == "" {
+		return nil
+	}
+	result.Offline = offlineMode
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	for attempt := 1; ; attempt++ {
+		err = postOnce(client, url, body)
+		if err == nil || attempt == 2 {
+			return err
+		}
+		logger.Warn("webhook failed, retrying", "url", url, "err", err)
+	}
+}
+
+func postOnce(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	logger.Info("webhook response", "url", url, "status", resp.Status)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+var (
+	// notifyBell rings the terminal bell when an upload finishes (NOTIFY_BELL=1)
+	notifyBell bool
+	// notifyCommand is run with a title and message when an upload finishes,
+	// e.g. NOTIFY_COMMAND=notify-send
+	notifyCommand string
+	// notifyWebhook receives each headless upload's result as JSON
+	// (NOTIFY_WEBHOOK)
+	notifyWebhook string
+)
+
+// notifyCompletion returns a command that signals a finished upload via the
+// terminal bell and/or the desktop notification command, if configured
+func notifyCompletion(isError bool) tea.Cmd {
+	if !notifyBell && notifyCommand == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		if notifyBell {
+			fmt.Fprint(os.Stdout, "\a")
+		}
+		if notifyCommand != "" {
+			title, message := "FiTrkr CLI", "Upload finished"
+			if isError {
+				message = "Upload failed"
+			}
+			if err := exec.Command(notifyCommand, title, message).Run(); err != nil {
+				logger.Warn("notification command failed", "command", notifyCommand, "err", err)
+			}
+		}
+		return nil
+	}
+}
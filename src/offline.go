@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// offlineMode runs the TUI against an in-memory fake instead of a real
+// database, for UI development and demos (OFFLINE=1 or --offline)
+var offlineMode bool
+
+// offlineCounts are the canned table counts shown in offline mode
+var offlineCounts = map[string]int64{
+	"muscle_group":      31,
+	"training_type":     18,
+	"exercise_category": 16,
+	"equipment":         87,
+	"exercise":          32,
+}
+
+func init() {
+	sql.Register("offline", offlineDriver{})
+}
+
+// OpenOffline returns a *sql.DB backed by the offline fake. Every statement
+// succeeds and nothing is stored.
+func OpenOffline() *sql.DB {
+	db, _ := sql.Open("offline", "")
+	return db
+}
+
+// OpenRecording returns an offline *sql.DB that also records every statement
+// run against it, in order, for previewing the SQL an operation would issue
+func OpenRecording() (*sql.DB, *sqlRecording) {
+	rec := &sqlRecording{}
+	db := sql.OpenDB(offlineConnector{rec: rec})
+	db.SetMaxOpenConns(1)
+	return db, rec
+}
+
+// sqlStatement is one statement run against a recording offline DB
+type sqlStatement struct {
+	Query string
+	Args  []any
+}
+
+// sqlRecording collects the statements run against a recording offline DB
+type sqlRecording struct {
+	mu         sync.Mutex
+	statements []sqlStatement
+}
+
+func (r *sqlRecording) record(query string, args []driver.NamedValue) {
+	if r == nil {
+		return
+	}
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, sqlStatement{Query: query, Args: values})
+}
+
+// Statements returns what has been recorded so far
+func (r *sqlRecording) Statements() []sqlStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]sqlStatement(nil), r.statements...)
+}
+
+type offlineDriver struct{}
+
+func (offlineDriver) Open(string) (driver.Conn, error) { return &offlineConn{}, nil }
+
+type offlineConnector struct {
+	rec *sqlRecording
+}
+
+func (c offlineConnector) Connect(context.Context) (driver.Conn, error) {
+	return &offlineConn{rec: c.rec}, nil
+}
+
+func (offlineConnector) Driver() driver.Driver { return offlineDriver{} }
+
+// offlineConn answers queries with plausible canned results: counts from
+// offlineCounts, a fresh id for RETURNING id and "inserted" for RETURNING (xmax = 0)
+type offlineConn struct {
+	nextID atomic.Int64
+	rec    *sqlRecording // nil unless opened with OpenRecording
+}
+
+func (c *offlineConn) Prepare(query string) (driver.Stmt, error) {
+	return &offlineStmt{conn: c, query: query}, nil
+}
+
+func (c *offlineConn) Close() error { return nil }
+func (c *offlineConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *offlineConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.rec.record("BEGIN", nil)
+	return offlineTx{rec: c.rec}, nil
+}
+
+func (c *offlineConn) Ping(context.Context) error { return nil }
+
+func (c *offlineConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.rec.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+var countTablePattern = regexp.MustCompile(`(?i)COUNT\(\*\)\s+FROM\s+(\w+)`)
+
+var countImportPattern = regexp.MustCompile(`(?i)COUNT\(\*\), MAX\(imported_at\)\s+FROM\s+(\w+)`)
+
+var unionCountPattern = regexp.MustCompile(`(?i)SELECT (\d+), COUNT\(\*\), MAX\(imported_at\)\s+FROM\s+(\w+)`)
+
+func (c *offlineConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.rec.record(query, args)
+	if strings.Contains(query, "UNION ALL") {
+		// Combined counts, one row per table
+		var rows [][]driver.Value
+		for _, match := range unionCountPattern.FindAllStringSubmatch(query, -1) {
+			i, _ := strconv.Atoi(match[1])
+			rows = append(rows, []driver.Value{int64(i), offlineCounts[match[2]], nil})
+		}
+		return &offlineRows{columns: []string{"i", "count", "max"}, values: rows}, nil
+	}
+	if match := countImportPattern.FindStringSubmatch(query); match != nil {
+		// Canned tables have never been imported
+		return &offlineRows{columns: []string{"count", "max"}, values: [][]driver.Value{{offlineCounts[match[1]], nil}}}, nil
+	}
+	if match := countTablePattern.FindStringSubmatch(query); match != nil {
+		return &offlineRows{columns: []string{"count"}, values: [][]driver.Value{{offlineCounts[match[1]]}}}, nil
+	}
+
+	switch {
+	case strings.Contains(query, "information_schema.tables"):
+		// Only the canned tables exist
+		var rows [][]driver.Value
+		for _, a := range args {
+			if table, ok := a.Value.(string); ok && offlineCounts[table] > 0 {
+				rows = append(rows, []driver.Value{table})
+			}
+		}
+		return &offlineRows{columns: []string{"table_name"}, values: rows}, nil
+	case strings.Contains(query, "to_regclass"):
+		// Tables added by migrations don't exist in the fake
+		return &offlineRows{columns: []string{"exists"}, values: [][]driver.Value{{false}}}, nil
+	case strings.Contains(query, "RETURNING id, (xmax = 0)"):
+		return &offlineRows{columns: []string{"id", "inserted"}, values: [][]driver.Value{{c.nextID.Add(1), true}}}, nil
+	case strings.Contains(query, "RETURNING name, (xmax = 0)") || strings.Contains(query, "ON CONFLICT DO NOTHING RETURNING"):
+		// Every name is new; BulkInsertNames passes the source first
+		values := args
+		if strings.Contains(query, "source_file") {
+			values = values[1:]
+		}
+		rows := make([][]driver.Value, len(values))
+		for i, v := range values {
+			rows[i] = []driver.Value{v.Value, true}
+		}
+		return &offlineRows{columns: []string{"name", "inserted"}, values: rows}, nil
+	case strings.Contains(query, "RETURNING (xmax = 0)"):
+		return &offlineRows{columns: []string{"inserted"}, values: [][]driver.Value{{true}}}, nil
+	case strings.Contains(query, "RETURNING id"):
+		return &offlineRows{columns: []string{"id"}, values: [][]driver.Value{{c.nextID.Add(1)}}}, nil
+	}
+	return &offlineRows{}, nil
+}
+
+type offlineStmt struct {
+	conn  *offlineConn
+	query string
+}
+
+func (s *offlineStmt) Close() error  { return nil }
+func (s *offlineStmt) NumInput() int { return -1 }
+
+func (s *offlineStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *offlineStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+type offlineTx struct {
+	rec *sqlRecording
+}
+
+func (t offlineTx) Commit() error {
+	t.rec.record("COMMIT", nil)
+	return nil
+}
+
+func (t offlineTx) Rollback() error {
+	t.rec.record("ROLLBACK", nil)
+	return nil
+}
+
+type offlineRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *offlineRows) Columns() []string { return r.columns }
+func (r *offlineRows) Close() error      { return nil }
+
+func (r *offlineRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// parsedFile is what --parse-only prints: the rows an upload would insert,
+// without touching the database
+type parsedFile struct {
+	Type      string              `json:"type"`
+	File      string              `json:"file"`
+	Seen      int                 `json:"seen"`
+	Names     []string            `json:"names,omitempty"`
+	Exercises []ExerciseUploadRow `json:"exercises,omitempty"`
+	Synonyms  []MuscleSynonym     `json:"synonyms,omitempty"`
+	Note      string              `json:"note,omitempty"`
+}
+
+// guessUploadType picks the upload type for path: typeName when given, else
+// the filename's hint, else DEFAULT_UPLOAD_TYPE
+func guessUploadType(path, typeName string) (UploadType, error) {
+	if typeName != "" {
+		return commandUploadType(typeName)
+	}
+	name := strings.ToLower(filepath.Base(path))
+	for _, hint := range filenameHints {
+		if strings.Contains(name, hint.keyword) {
+			return uploadTypes[uploadTypeIndex(hint.table)], nil
+		}
+	}
+	if i := uploadTypeIndex(defaultUploadType); i >= 0 {
+		return uploadTypes[i], nil
+	}
+	return UploadType{}, fmt.Errorf("can't tell what %s holds; pass --type", filepath.Base(path))
+}
+
+// ParseOnly parses path as an upload of typeName and writes the rows as JSON
+// to w. format overrides the file extension, e.g. "csv". An archive writes an
+// array with one object per data file inside, typed by each file's name. It
+// never connects to the database.
+func ParseOnly(w io.Writer, path, format, typeName string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if isArchive(path) {
+		entries, err := ReadArchive(path)
+		if err != nil {
+			return err
+		}
+		out := make([]parsedFile, 0, len(entries))
+		for _, entry := range entries {
+			uploadType, err := guessUploadType(entry.Name, typeName)
+			if err != nil {
+				return err
+			}
+			parsed, err := parseData(uploadType, entry.Name, entry.Ext(), entry.Data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", entry.Name, err)
+			}
+			out = append(out, parsed)
+		}
+		return enc.Encode(out)
+	}
+
+	uploadType, err := guessUploadType(path, typeName)
+	if err != nil {
+		return err
+	}
+	data, ext, err := readUploadFile(path, uploadType)
+	if err != nil {
+		return err
+	}
+	if format != "" {
+		ext = "." + strings.TrimPrefix(strings.ToLower(format), ".")
+	}
+	out, err := parseData(uploadType, filepath.Base(path), ext, data)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(out)
+}
+
+// parseData parses the contents of file as uploadType would upload them
+func parseData(uploadType UploadType, file, ext string, data []byte) (out parsedFile, err error) {
+	out = parsedFile{Type: uploadType.Table, File: file}
+	switch {
+	case uploadType.Table == "muscle_synonyms":
+		if ext != ".csv" {
+			return out, fmt.Errorf("muscle synonyms must be a two-column CSV, got %s", ext)
+		}
+		out.Synonyms, out.Seen, err = ParseMuscleSynonymsCSV(data)
+		out.Synonyms, out.Note = applyLineRange(out.Synonyms)
+	case uploadType.Parser == nil:
+		var problems []ValidationError
+		out.Exercises, out.Seen, problems, err = ParseExercisesCSVReader(bytes.NewReader(data))
+		out.Exercises, problems, out.Note = applyExerciseLineRange(out.Exercises, problems, out.Seen)
+		out.Note = joinNotes(out.Note, describeMalformedRows(problems))
+	default:
+		out.Names, _, out.Seen, err = uploadType.Parser(ext, data)
+		out.Names, out.Note = applyLineRange(out.Names)
+	}
+	return out, err
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// previewPageSize is how many exercise rows the preview shows at once
+const previewPageSize = 15
+
+// RowStatus is what an upload will do with a single exercise row
+type RowStatus int
+
+const (
+	RowNew RowStatus = iota
+	RowUpdate
+	RowUnchanged
+	RowInvalid
+)
+
+// PreviewExerciseStatuses works out each row's fate against the database:
+// new, updated, unchanged (stored content hash matches) or rejected by validation
+func PreviewExerciseStatuses(db *sql.DB, rows []ExerciseUploadRow, errs []ValidationError) ([]RowStatus, error) {
+	names := make([]string, len(rows))
+	for i, row := range rows {
+		names[i] = row.Name
+	}
+
+	// to_jsonb keeps this working before the content_hash migration has run
+	query := `SELECT name, COALESCE(to_jsonb(exercise)->>'content_hash', '') FROM exercise WHERE name = ANY($1)`
+	logSQL(query, names)
+	rs, err := db.Query(query, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+
+	stored := make(map[string]string)
+	for rs.Next() {
+		var name, hash string
+		if err := rs.Scan(&name, &hash); err != nil {
+			return nil, err
+		}
+		stored[name] = hash
+	}
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]RowStatus, len(rows))
+	for i, row := range rows {
+		hash, exists := stored[row.Name]
+		switch {
+		case !exists:
+			statuses[i] = RowNew
+		case hash == exerciseContentHash(row):
+			statuses[i] = RowUnchanged
+		default:
+			statuses[i] = RowUpdate
+		}
+	}
+	for _, e := range errs {
+		statuses[e.Row-1] = RowInvalid
+	}
+	return statuses, nil
+}
+
+// ExistingNames returns which of names are already live in column of table,
+// looked up together with a single name = ANY($1) query
+func ExistingNames(db *sql.DB, table, column string, names []string) (map[string]bool, error) {
+	where := " WHERE "
+	if live := liveRowsClause(table); live != "" {
+		where = live + " AND "
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s%s%s = ANY($1)", column, table, where, column)
+	matched, err := queryNames(db, query, []any{names})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(matched))
+	for _, name := range matched {
+		existing[name] = true
+	}
+	return existing, nil
+}
+
+// MissingRefNames looks up every category, equipment, type, muscle and tag
+// rows name and returns, per table, the ones an upload would create. It only
+// reads, unlike a dry run. Soft-deleted names count as existing, as the upload
+// restores them.
+func MissingRefNames(db *sql.DB, rows []ExerciseUploadRow) (map[string]map[string]bool, error) {
+	missing := make(map[string]map[string]bool, len(refKinds))
+	for _, kind := range refKinds {
+		names := kind.names(rows)
+		if len(names) == 0 {
+			continue
+		}
+		known, err := existingRefNames(db, kind.table, names)
+		if err != nil {
+			return nil, fmt.Errorf("look up %s names: %w", kind.label, err)
+		}
+		missing[kind.table] = make(map[string]bool)
+		for _, name := range names {
+			if !known[name] {
+				missing[kind.table][name] = true
+			}
+		}
+	}
+	return missing, nil
+}
+
+// newReferences lists the reference names rows would create, given what
+// MissingRefNames found
+func newReferences(rows []ExerciseUploadRow, missing map[string]map[string]bool) CreatedRefs {
+	var refs CreatedRefs
+	for _, kind := range refKinds {
+		var names []string
+		for _, name := range kind.names(rows) {
+			if missing[kind.table][name] {
+				names = append(names, name)
+			}
+		}
+		switch kind.table {
+		case "exercise_category":
+			refs.Categories = names
+		case "equipment":
+			refs.Equipment = names
+		case "training_type":
+			refs.Types = names
+		case "muscle_group":
+			refs.Muscles = names
+		case "tags":
+			refs.Tags = names
+		}
+	}
+	return refs
+}
+
+// newRefsListLimit caps how many names the preview lists per table
+const newRefsListLimit = 8
+
+// describeNewReferences summarises the reference entities an upload would
+// create, one line per table, e.g. "Would create muscles (2): Lats, Traps"
+func describeNewReferences(refs CreatedRefs) []string {
+	if refs.Total() == 0 {
+		return []string{"Would create no new categories, equipment, types, muscles or tags"}
+	}
+	var lines []string
+	for _, rf := range createdRefFiles(refs) {
+		if len(rf.names) == 0 {
+			continue
+		}
+		names := rf.names
+		more := ""
+		if len(names) > newRefsListLimit {
+			more = fmt.Sprintf(" …and %d more", len(names)-newRefsListLimit)
+			names = names[:newRefsListLimit]
+		}
+		lines = append(lines, fmt.Sprintf("Would create %s (%d): %s%s", rf.kind, len(rf.names), strings.Join(names, ", "), more))
+	}
+	return lines
+}
+
+// ColumnStat summarises one multi-value exercise column
+type ColumnStat struct {
+	Name     string
+	Distinct int // distinct values, compared case-insensitively
+	Total    int // values across all rows
+}
+
+// ExerciseColumnStats computes distinct and total value counts for each
+// multi-value column of rows
+func ExerciseColumnStats(rows []ExerciseUploadRow) []ColumnStat {
+	columns := []struct {
+		name   string
+		values func(ExerciseUploadRow) []string
+	}{
+		{"muscles", func(r ExerciseUploadRow) []string { return r.Muscles }},
+		{"equipment", func(r ExerciseUploadRow) []string { return r.Equipment }},
+		{"types", func(r ExerciseUploadRow) []string { return r.Types }},
+		{"tags", func(r ExerciseUploadRow) []string { return r.Tags }},
+	}
+
+	var stats []ColumnStat
+	for _, col := range columns {
+		stat := ColumnStat{Name: col.name}
+		distinct := make(map[string]bool)
+		for _, row := range rows {
+			for _, v := range col.values(row) {
+				distinct[strings.ToLower(v)] = true
+				stat.Total++
+			}
+		}
+		stat.Distinct = len(distinct)
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// describeColumnStats renders e.g. "47 distinct muscles across 1200 exercises, avg 2.3 per exercise"
+func describeColumnStats(stats []ColumnStat, exercises int) []string {
+	var lines []string
+	for _, s := range stats {
+		if s.Total == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d distinct %s across %d exercises, avg %.1f per exercise",
+			s.Distinct, s.Name, exercises, float64(s.Total)/float64(max(exercises, 1))))
+	}
+	return lines
+}
+
+// countStatuses tallies how many rows have each status
+func countStatuses(statuses []RowStatus) map[RowStatus]int {
+	counts := make(map[RowStatus]int)
+	for _, s := range statuses {
+		counts[s]++
+	}
+	return counts
+}
+
+// previewCategories returns the distinct categories of rows, sorted
+func previewCategories(rows []ExerciseUploadRow) []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, row := range rows {
+		if !seen[row.Category] {
+			seen[row.Category] = true
+			categories = append(categories, row.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// visibleRows returns the indexes of pending rows in the selected categories,
+// or of every row when no category filter is set
+func (m model) visibleRows() []int {
+	var visible []int
+	for i, row := range m.pendingRows {
+		if m.categoryFilter == nil || m.categoryFilter[row.Category] {
+			visible = append(visible, i)
+		}
+	}
+	return visible
+}
+
+// selectedCategories lists the categories picked in the filter, sorted
+func (m model) selectedCategories() []string {
+	var selected []string
+	for _, category := range previewCategories(m.pendingRows) {
+		if m.categoryFilter[category] {
+			selected = append(selected, category)
+		}
+	}
+	return selected
+}
+
+// blockingErrors counts validation errors in the visible rows
+func (m model) blockingErrors() int {
+	visible := make(map[int]bool)
+	for _, i := range m.visibleRows() {
+		visible[i] = true
+	}
+	n := 0
+	for _, e := range m.previewErrs {
+		if visible[e.Row-1] {
+			n++
+		}
+	}
+	return n
+}
+
+func updatePreview(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.previewOffset > 0 {
+				m.previewOffset--
+			}
+		case "down", "j":
+			if m.previewOffset < len(m.visibleRows())-previewPageSize {
+				m.previewOffset++
+			}
+		case "c":
+			m.categories = previewCategories(m.pendingRows)
+			m.categoryChoice = 0
+			m.state = stateCategoryFilter
+		case "q", "esc":
+			m.state = stateMenu
+			m.pendingRows, m.previewStatuses, m.previewErrs, m.categoryFilter = nil, nil, nil, nil
+			m.previewMissingRefs = nil
+		case "enter":
+			if m.blockingErrors() > 0 {
+				return m, nil
+			}
+			if m.categoryFilter != nil {
+				// Upload only the selected categories; the checkpoint key
+				// includes them so a resume matches the same subset
+				var rows []ExerciseUploadRow
+				for _, i := range m.visibleRows() {
+					rows = append(rows, m.pendingRows[i])
+				}
+				selected := m.selectedCategories()
+				m.pendingSeen -= len(m.pendingRows) - len(rows)
+				m.pendingRows = rows
+				m.pendingHash = contentHash([]byte(m.pendingHash + "\x1f" + strings.Join(selected, "\x1f")))
+				m.uploadNotes = joinNotes(m.uploadNotes, "Categories uploaded: "+strings.Join(selected, ", "))
+				m.categoryFilter = nil
+			}
+			m.previewStatuses, m.previewMissingRefs = nil, nil
+			return checkUnknownRefs(m)
+		}
+	}
+	return m, nil
+}
+
+func viewPreview(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Exercises preview"))
+	parts = append(parts, "")
+
+	visible := m.visibleRows()
+	shown := make([]RowStatus, len(visible))
+	for j, i := range visible {
+		shown[j] = m.previewStatuses[i]
+	}
+	counts := countStatuses(shown)
+	parts = append(parts, strings.Join([]string{
+		RenderRowStatus(fmt.Sprintf("%d new", counts[RowNew]), RowNew),
+		RenderRowStatus(fmt.Sprintf("%d update", counts[RowUpdate]), RowUpdate),
+		RenderRowStatus(fmt.Sprintf("%d unchanged", counts[RowUnchanged]), RowUnchanged),
+		RenderRowStatus(fmt.Sprintf("%d invalid", counts[RowInvalid]), RowInvalid),
+	}, " • "))
+	parts = append(parts, "")
+
+	visibleRows := make([]ExerciseUploadRow, len(visible))
+	for j, i := range visible {
+		visibleRows[j] = m.pendingRows[i]
+	}
+	for _, line := range describeColumnStats(ExerciseColumnStats(visibleRows), len(visibleRows)) {
+		parts = append(parts, RenderHelpText(line))
+	}
+	parts = append(parts, "")
+
+	if m.uploadNotes != "" {
+		parts = append(parts, RenderHelpText(m.uploadNotes), "")
+	}
+
+	if m.previewMissingRefs != nil {
+		for _, line := range describeNewReferences(newReferences(visibleRows, m.previewMissingRefs)) {
+			parts = append(parts, RenderHelpText(line))
+		}
+		parts = append(parts, "")
+	}
+
+	if len(m.transformSamples) > 0 {
+		parts = append(parts, RenderHelpText("Column transforms, for example:"))
+		for _, sample := range m.transformSamples {
+			parts = append(parts, RenderHelpText("  "+sample.String()))
+		}
+		parts = append(parts, "")
+	}
+
+	reasons := make(map[int]string, len(m.previewErrs))
+	for _, e := range m.previewErrs {
+		reasons[e.Row-1] = e.Reason
+	}
+
+	if m.categoryFilter != nil {
+		parts = append(parts, RenderHelpText(fmt.Sprintf("Categories: %s (%d of %d rows)",
+			strings.Join(m.selectedCategories(), ", "), len(visible), len(m.pendingRows))))
+		parts = append(parts, "")
+	}
+
+	end := min(m.previewOffset+previewPageSize, len(visible))
+	for _, i := range visible[m.previewOffset:end] {
+		row := m.pendingRows[i]
+		// Label rows by file line so they match the validation errors
+		n := row.Line
+		if n == 0 {
+			n = i + 1
+		}
+		line := fmt.Sprintf("%4d  %-32s %s", n, row.Name, row.Category)
+		if reason, ok := reasons[i]; ok {
+			line += "  ← " + reason
+		}
+		parts = append(parts, RenderRowStatus(line, m.previewStatuses[i]))
+	}
+
+	parts = append(parts, "")
+	if m.blockingErrors() > 0 {
+		parts = append(parts, RenderHelpText("Fix the invalid rows before uploading • Scroll: ↑/↓ or j/k • Categories: c • Back: q/esc"))
+	} else {
+		parts = append(parts, RenderHelpText("Upload: enter • Scroll: ↑/↓ or j/k • Categories: c • Back: q/esc"))
+	}
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
+
+func updateCategoryFilter(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.categoryChoice > 0 {
+				m.categoryChoice--
+			}
+		case "down", "j":
+			if m.categoryChoice < len(m.categories)-1 {
+				m.categoryChoice++
+			}
+		case " ", "x":
+			if m.categoryFilter == nil {
+				m.categoryFilter = make(map[string]bool)
+			}
+			category := m.categories[m.categoryChoice]
+			m.categoryFilter[category] = !m.categoryFilter[category]
+		case "a":
+			m.categoryFilter = nil
+		case "enter", "q", "esc":
+			if len(m.selectedCategories()) == 0 {
+				m.categoryFilter = nil
+			}
+			m.previewOffset = 0
+			m.state = statePreview
+		}
+	}
+	return m, nil
+}
+
+func viewCategoryFilter(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Upload only these categories"))
+	parts = append(parts, "")
+
+	rowsPer := make(map[string]int)
+	for _, row := range m.pendingRows {
+		rowsPer[row.Category]++
+	}
+	for i, category := range m.categories {
+		mark := "[ ]"
+		if m.categoryFilter[category] {
+			mark = "[x]"
+		}
+		label := fmt.Sprintf("%s %s (%d)", mark, category, rowsPer[category])
+		parts = append(parts, RenderFileItem(label, i == m.categoryChoice, false))
+	}
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Toggle: space/x • All: a • Done: enter/esc"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// queryPageSize is how many result rows the query runner shows at once
+const queryPageSize = 15
+
+// RunReadOnlyQuery runs a single SELECT statement inside a read-only transaction
+// and returns the column names and every row rendered as strings
+func RunReadOnlyQuery(db *sql.DB, query string) ([]string, [][]string, error) {
+	query = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	if err := checkReadOnly(query); err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	logSQL(query)
+	rs, err := tx.Query(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rs.Close()
+
+	columns, err := rs.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]string
+	for rs.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rs.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+
+		row := make([]string, len(columns))
+		for i, v := range values {
+			switch val := v.(type) {
+			case nil:
+				row[i] = "NULL"
+			case []byte:
+				row[i] = string(val)
+			default:
+				row[i] = fmt.Sprint(val)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, rs.Err()
+}
+
+// checkReadOnly rejects anything that isn't a single SELECT statement
+func checkReadOnly(query string) error {
+	if query == "" {
+		return errors.New("empty query")
+	}
+	fields := strings.Fields(query)
+	if !strings.EqualFold(fields[0], "SELECT") {
+		return errors.New("only SELECT queries are allowed")
+	}
+	if strings.Contains(query, ";") {
+		return errors.New("only a single statement is allowed")
+	}
+	return nil
+}
+
+func updateQuery(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch key.Type {
+	case tea.KeyEsc:
+		m.state = stateMenu
+		return m, nil
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEnter:
+		m.queryColumns, m.queryRows, m.queryErr = RunReadOnlyQuery(m.db, m.queryInput)
+		m.queryOffset = 0
+	case tea.KeyBackspace:
+		if r := []rune(m.queryInput); len(r) > 0 {
+			m.queryInput = string(r[:len(r)-1])
+		}
+	case tea.KeyUp:
+		if m.queryOffset > 0 {
+			m.queryOffset--
+		}
+	case tea.KeyDown:
+		if m.queryOffset < len(m.queryRows)-queryPageSize {
+			m.queryOffset++
+		}
+	case tea.KeySpace:
+		m.queryInput += " "
+	case tea.KeyRunes:
+		m.queryInput += string(key.Runes)
+	}
+	return m, nil
+}
+
+func viewQuery(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Read-only query"))
+	parts = append(parts, "")
+	parts = append(parts, CursorStyle.Render("sql> ")+m.queryInput+"█")
+	parts = append(parts, "")
+
+	switch {
+	case m.queryErr != nil:
+		parts = append(parts, RenderErrorMessage(m.queryErr.Error()))
+	case m.queryColumns != nil:
+		end := min(m.queryOffset+queryPageSize, len(m.queryRows))
+		parts = append(parts, RenderQueryTable(m.queryColumns, m.queryRows[m.queryOffset:end]))
+		parts = append(parts, RenderHelpText(fmt.Sprintf("Rows %d-%d of %d", min(m.queryOffset+1, end), end, len(m.queryRows))))
+	}
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("Run: enter • Scroll: ↑/↓ • Back: esc"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
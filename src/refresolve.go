@@ -0,0 +1,410 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// resolveRefs pauses an exercises upload on every category, equipment, type,
+// muscle or tag it would create, to create it, map it to an existing one or
+// skip it (RESOLVE_REFS)
+var resolveRefs bool
+
+// strictRefs fails an exercises upload that names a reference not yet in the
+// database instead of creating it (STRICT_REFS). Uploads that can't prompt,
+// such as batches, follow it even when RESOLVE_REFS is set.
+var strictRefs bool
+
+// refSuggestThreshold is the trigram similarity used for suggestions when
+// SIMILARITY_THRESHOLD is unset; it matches pg_trgm's default
+const refSuggestThreshold = 0.3
+
+// refKind is a reference table exercise rows name, with how to read and
+// rewrite those names on a row
+type refKind struct {
+	table string
+	label string
+	get   func(row ExerciseUploadRow) []string
+	set   func(row *ExerciseUploadRow, names []string)
+}
+
+var refKinds = []refKind{
+	{"exercise_category", "category",
+		func(row ExerciseUploadRow) []string { return []string{row.Category} },
+		func(row *ExerciseUploadRow, names []string) { row.Category = strings.Join(names, "") }},
+	{"equipment", "equipment",
+		func(row ExerciseUploadRow) []string { return row.Equipment },
+		func(row *ExerciseUploadRow, names []string) { row.Equipment = names }},
+	{"training_type", "type",
+		func(row ExerciseUploadRow) []string { return row.Types },
+		func(row *ExerciseUploadRow, names []string) { row.Types = names }},
+	{"muscle_group", "muscle",
+		func(row ExerciseUploadRow) []string { return row.Muscles },
+		func(row *ExerciseUploadRow, names []string) { row.Muscles = names }},
+	{"tags", "tag",
+		func(row ExerciseUploadRow) []string { return row.Tags },
+		func(row *ExerciseUploadRow, names []string) { row.Tags = names }},
+}
+
+// UnknownRef is a reference name an upload would create, with the existing
+// names it most resembles
+type UnknownRef struct {
+	Table   string
+	Label   string
+	Name    string
+	Matches []string
+}
+
+func (r UnknownRef) String() string {
+	if len(r.Matches) == 0 {
+		return fmt.Sprintf("%s %s", r.Label, r.Name)
+	}
+	return fmt.Sprintf("%s %s (did you mean %s?)", r.Label, r.Name, strings.Join(r.Matches, ", "))
+}
+
+// refAction is what to do with an unknown reference
+type refAction int
+
+const (
+	refCreate refAction = iota
+	refMap
+	refSkip
+)
+
+// refDecision is the choice made for one unknown reference; target is the
+// existing name for refMap
+type refDecision struct {
+	action refAction
+	target string
+}
+
+// FindUnknownRefs returns the reference names in rows that don't exist yet, in
+// the order they first appear. Muscles known as a synonym count as existing.
+// Suggestions need pg_trgm and are left out without it.
+func FindUnknownRefs(db *sql.DB, rows []ExerciseUploadRow) ([]UnknownRef, error) {
+	threshold := similarityThreshold
+	if threshold == 0 {
+		threshold = refSuggestThreshold
+	}
+	suggest := true
+
+	var unknown []UnknownRef
+	for _, kind := range refKinds {
+		names := kind.names(rows)
+		if len(names) == 0 {
+			continue
+		}
+
+		known, err := existingRefNames(db, kind.table, names)
+		if err != nil {
+			return nil, fmt.Errorf("look up %s names: %w", kind.label, err)
+		}
+		for _, name := range names {
+			if known[name] {
+				continue
+			}
+			ref := UnknownRef{Table: kind.table, Label: kind.label, Name: name}
+			if suggest {
+				ref.Matches, err = FindSimilarNames(db, kind.table, name, threshold)
+				if err != nil {
+					logger.Warn("no suggestions for unknown references", "err", err)
+					suggest = false
+				}
+			}
+			unknown = append(unknown, ref)
+		}
+	}
+	return unknown, nil
+}
+
+// names returns the distinct names of this kind rows reference, in the order
+// they first appear, leaving out what an upload never creates
+func (kind refKind) names(rows []ExerciseUploadRow) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for _, name := range kind.get(row) {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[name] || (kind.table == "equipment" && isNoEquipment(name)) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// existingRefNames returns which of names exist in table. Names match
+// exactly, as GetOrInsert* does; muscles also match their synonyms in any case.
+func existingRefNames(db *sql.DB, table string, names []string) (map[string]bool, error) {
+	placeholders := make([]string, len(names))
+	args := make([]any, len(names))
+	for i, name := range names {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = name
+	}
+
+	known := make(map[string]bool)
+	query := fmt.Sprintf(`SELECT name FROM %s WHERE name IN (%s)`, table, strings.Join(placeholders, ", "))
+	matched, err := queryNames(db, query, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range matched {
+		known[name] = true
+	}
+	if table != "muscle_group" {
+		return known, nil
+	}
+
+	var synonyms bool
+	query = `SELECT to_regclass('muscle_synonyms') IS NOT NULL`
+	logSQL(query)
+	if err := db.QueryRow(query).Scan(&synonyms); err != nil || !synonyms {
+		return known, err
+	}
+	for i, p := range placeholders {
+		placeholders[i] = "lower(" + p + ")"
+	}
+	query = fmt.Sprintf(`SELECT lower(synonym) FROM muscle_synonyms WHERE lower(synonym) IN (%s)`, strings.Join(placeholders, ", "))
+	matched, err = queryNames(db, query, args)
+	if err != nil {
+		return nil, err
+	}
+	synonym := make(map[string]bool, len(matched))
+	for _, name := range matched {
+		synonym[name] = true
+	}
+	for _, name := range names {
+		if synonym[strings.ToLower(name)] {
+			known[name] = true
+		}
+	}
+	return known, nil
+}
+
+// queryNames runs a query selecting one text column and returns its values
+func queryNames(db *sql.DB, query string, args []any) ([]string, error) {
+	logSQL(query, args...)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// unknownRefsError reports the references a strict upload refused to create
+func unknownRefsError(refs []UnknownRef) error {
+	const limit = 5
+	var lines []string
+	for i, ref := range refs {
+		if i == limit {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(refs)-limit))
+			break
+		}
+		lines = append(lines, ref.String())
+	}
+	return fmt.Errorf("%d unknown references with STRICT_REFS set; add them first:\n%s", len(refs), strings.Join(lines, "\n"))
+}
+
+// applyRefDecisions rewrites rows according to the decision for each unknown
+// reference. Mapped names are replaced, skipped ones dropped; a row whose
+// category is skipped is dropped entirely, and dropped counts those rows.
+func applyRefDecisions(rows []ExerciseUploadRow, refs []UnknownRef, decisions []refDecision) (out []ExerciseUploadRow, dropped int) {
+	byName := make(map[string]refDecision, len(refs))
+	for i, ref := range refs {
+		byName[ref.Table+"\x1f"+ref.Name] = decisions[i]
+	}
+
+	for _, row := range rows {
+		keep := true
+		for _, kind := range refKinds {
+			var names []string
+			for _, name := range kind.get(row) {
+				decision, ok := byName[kind.table+"\x1f"+strings.TrimSpace(name)]
+				switch {
+				case !ok || decision.action == refCreate:
+					names = append(names, name)
+				case decision.action == refMap:
+					names = append(names, decision.target)
+				}
+			}
+			if kind.table == "exercise_category" && len(names) == 0 {
+				keep = false
+				break
+			}
+			kind.set(&row, names)
+		}
+		if !keep {
+			dropped++
+			continue
+		}
+		out = append(out, row)
+	}
+	return out, dropped
+}
+
+// checkUnknownRefs applies RESOLVE_REFS and STRICT_REFS to the pending
+// exercise rows before they upload
+func checkUnknownRefs(m model) (tea.Model, tea.Cmd) {
+	if !resolveRefs && !strictRefs {
+		return continueExercisesUpload(m)
+	}
+	refs, err := FindUnknownRefs(m.db, m.pendingRows)
+	if err == nil && len(refs) > 0 && !resolveRefs {
+		err = unknownRefsError(refs)
+	}
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error checking references: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		m.pendingRows = nil
+		return m, nil
+	}
+	if len(refs) == 0 {
+		return continueExercisesUpload(m)
+	}
+	m.unknownRefs, m.refDecisions = refs, nil
+	m.refChoice = 0
+	m.state = stateResolveRef
+	return m, nil
+}
+
+// refOptions lists the choices for the reference being resolved: create it,
+// map it to each suggestion, or skip it
+func (m model) refOptions() []refDecision {
+	ref := m.unknownRefs[len(m.refDecisions)]
+	options := []refDecision{{action: refCreate}}
+	for _, match := range ref.Matches {
+		options = append(options, refDecision{action: refMap, target: match})
+	}
+	return append(options, refDecision{action: refSkip})
+}
+
+// decideRef records decision for the current reference and moves on, starting
+// the upload once every reference is resolved
+func decideRef(m model, decision refDecision) (tea.Model, tea.Cmd) {
+	m.refDecisions = append(m.refDecisions, decision)
+	m.refChoice = 0
+	if len(m.refDecisions) < len(m.unknownRefs) {
+		return m, nil
+	}
+
+	var created, mapped, skipped []string
+	var key strings.Builder
+	for i, ref := range m.unknownRefs {
+		d := m.refDecisions[i]
+		switch d.action {
+		case refCreate:
+			created = append(created, ref.Name)
+		case refMap:
+			mapped = append(mapped, ref.Name+" → "+d.target)
+		case refSkip:
+			skipped = append(skipped, ref.Name)
+		}
+		fmt.Fprintf(&key, "\x1f%s\x1f%s\x1f%d\x1f%s", ref.Table, ref.Name, d.action, d.target)
+	}
+
+	rows, dropped := applyRefDecisions(m.pendingRows, m.unknownRefs, m.refDecisions)
+	m.pendingSeen -= dropped
+	m.pendingRows = rows
+	// A resume must see the same rewrites
+	m.pendingHash = contentHash([]byte(m.pendingHash + key.String()))
+	if len(mapped) > 0 {
+		m.uploadNotes = joinNotes(m.uploadNotes, "Mapped references: "+strings.Join(mapped, ", "))
+	}
+	if len(skipped) > 0 {
+		m.uploadNotes = joinNotes(m.uploadNotes, fmt.Sprintf("Skipped references: %s (%d exercises dropped)", strings.Join(skipped, ", "), dropped))
+	}
+	if len(created) > 0 {
+		logger.Info("creating references", "names", created)
+	}
+	m.unknownRefs, m.refDecisions = nil, nil
+
+	if len(m.pendingRows) == 0 {
+		m.state = stateResult
+		m.resultMsg = "Every exercise was skipped; nothing to upload.\nPress enter or q to return to menu."
+		m.isError = false
+		return m, nil
+	}
+	return continueExercisesUpload(m)
+}
+
+func updateResolveRef(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		options := m.refOptions()
+		switch key.String() {
+		case "up", "k":
+			if m.refChoice > 0 {
+				m.refChoice--
+			}
+		case "down", "j":
+			if m.refChoice < len(options)-1 {
+				m.refChoice++
+			}
+		case "c":
+			return decideRef(m, refDecision{action: refCreate})
+		case "s":
+			return decideRef(m, refDecision{action: refSkip})
+		case "b", "backspace":
+			if n := len(m.refDecisions); n > 0 {
+				m.refDecisions = m.refDecisions[:n-1]
+				m.refChoice = 0
+			}
+		case "q", "esc":
+			m.state = stateMenu
+			m.pendingRows, m.unknownRefs, m.refDecisions = nil, nil, nil
+		case "enter":
+			return decideRef(m, options[m.refChoice])
+		}
+	}
+	return m, nil
+}
+
+func viewResolveRef(m model) string {
+	var parts []string
+	ref := m.unknownRefs[len(m.refDecisions)]
+
+	parts = append(parts, RenderMenuTitle(fmt.Sprintf("Unknown %s (%d of %d)", ref.Label, len(m.refDecisions)+1, len(m.unknownRefs))))
+	parts = append(parts, "")
+	parts = append(parts, fmt.Sprintf("%q isn't in %s yet.", ref.Name, ref.Table))
+	if len(ref.Matches) == 0 {
+		parts = append(parts, RenderHelpText("No similar names found."))
+	}
+	parts = append(parts, "")
+
+	for i, option := range m.refOptions() {
+		var label string
+		switch option.action {
+		case refCreate:
+			label = fmt.Sprintf("Create %q", ref.Name)
+		case refMap:
+			label = fmt.Sprintf("Use existing %q", option.target)
+		case refSkip:
+			label = "Skip it"
+			if ref.Table == "exercise_category" {
+				label += " (drops its exercises)"
+			}
+		}
+		parts = append(parts, RenderFileItem(label, i == m.refChoice, false))
+	}
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Choose: enter • Create: c • Skip: s • Previous: b • Cancel: q/esc"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RelationalExport is a full export from another system with explicit ids. Every
+// reference and junction table this tool manages has its own array.
+type RelationalExport struct {
+	MuscleGroups       []namedRow    `json:"muscle_groups"`
+	TrainingTypes      []namedRow    `json:"training_types"`
+	ExerciseCategories []namedRow    `json:"exercise_categories"`
+	Equipment          []namedRow    `json:"equipment"`
+	Tags               []namedRow    `json:"tags"`
+	Exercises          []exerciseRow `json:"exercises"`
+
+	ExerciseEquipment     []junctionRow `json:"exercise_equipment"`
+	ExerciseTrainingTypes []junctionRow `json:"exercise_training_types"`
+	ExerciseMuscles       []junctionRow `json:"exercise_muscles"`
+	ExerciseTags          []junctionRow `json:"exercise_tags"`
+}
+
+type namedRow struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type exerciseRow struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CategoryID  int    `json:"category_id"`
+}
+
+// junctionRow links an exercise to a reference entity; RefID is named by the
+// junction's own column in the export (equipment_id, muscle_group_id, ...)
+type junctionRow struct {
+	ExerciseID int
+	RefID      int
+}
+
+// UnmarshalJSON accepts the junction's real column name for the reference id
+func (j *junctionRow) UnmarshalJSON(data []byte) error {
+	var raw map[string]int
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	j.ExerciseID = raw["exercise_id"]
+	for key, val := range raw {
+		if key != "exercise_id" {
+			j.RefID = val
+		}
+	}
+	return nil
+}
+
+// ImportRelationalJSON inserts an export preserving its ids, in one transaction,
+// then moves each id sequence past the highest imported id. Exports carry
+// integer ids, so it refuses to run against a UUID-keyed schema.
+//
+// A row whose name already exists is not inserted again: the rows that refer
+// to it by its exported id are linked to the existing row instead, and it is
+// reported in skipped. An exported id already taken by a different name fails
+// the import.
+func ImportRelationalJSON(db *sql.DB, path string) (skipped []string, err error) {
+	if primaryKeys == primaryKeysUUID {
+		return nil, errors.New("relational exports carry integer ids, which a UUID-keyed schema (PRIMARY_KEYS=uuid) can't preserve")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = normalizeInput(data)
+
+	var export RelationalExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, describeJSONError(data, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// Each table written gets its own row log marker
+	source := filepath.Base(path)
+	var written []string
+	counts := map[string]int{}
+	logImported := func(table, name, outcome string) {
+		if _, ok := counts[table]; !ok {
+			written = append(written, table)
+		}
+		counts[table]++
+		logRow(tx, rowEvent{Table: table, Source: source, Name: name, Outcome: outcome})
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		logTxEndTables(tx, written, source, counts, err)
+	}()
+
+	// ids maps each table's exported ids to the ids the rows have here
+	ids := map[string]map[int]int{}
+	importRow := func(table, query string, id int, name string, args ...any) error {
+		dbID, inserted, err := importNamedRow(tx, table, query, id, name, args...)
+		if err != nil {
+			return err
+		}
+		if ids[table] == nil {
+			ids[table] = map[int]int{}
+		}
+		ids[table][id] = dbID
+		if inserted {
+			logImported(table, name, rowInserted)
+		} else {
+			logImported(table, name, rowSkipped)
+			note := fmt.Sprintf("%s %d %q: already exists", table, id, name)
+			if dbID != id {
+				note += fmt.Sprintf(" as id %d, linked to it", dbID)
+			}
+			skipped = append(skipped, note)
+		}
+		return nil
+	}
+	// remap returns the id an exported id has here, or the id itself when the
+	// export doesn't list that row
+	remap := func(table string, id int) int {
+		if dbID, ok := ids[table][id]; ok {
+			return dbID
+		}
+		return id
+	}
+
+	named := []struct {
+		table string
+		rows  []namedRow
+	}{
+		{"muscle_group", export.MuscleGroups},
+		{"training_type", export.TrainingTypes},
+		{"exercise_category", export.ExerciseCategories},
+		{"equipment", export.Equipment},
+		{"tags", export.Tags},
+	}
+	for _, n := range named {
+		query := fmt.Sprintf("INSERT INTO %s (id, name) OVERRIDING SYSTEM VALUE VALUES ($1, $2) ON CONFLICT DO NOTHING RETURNING id", n.table)
+		for _, row := range n.rows {
+			if err := importRow(n.table, query, row.ID, row.Name, row.ID, row.Name); err != nil {
+				return skipped, err
+			}
+		}
+	}
+
+	exerciseQuery := `INSERT INTO exercise (id, name, description, category_id) OVERRIDING SYSTEM VALUE VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING RETURNING id`
+	for _, row := range export.Exercises {
+		categoryID := remap("exercise_category", row.CategoryID)
+		if err := importRow("exercise", exerciseQuery, row.ID, row.Name, row.ID, row.Name, row.Description, categoryID); err != nil {
+			return skipped, err
+		}
+	}
+
+	junctions := []struct {
+		table    string
+		column   string
+		refTable string
+		rows     []junctionRow
+	}{
+		{"exercise_equipment", "equipment_id", "equipment", export.ExerciseEquipment},
+		{"exercise_training_types", "training_type_id", "training_type", export.ExerciseTrainingTypes},
+		{"exercise_muscles", "muscle_group_id", "muscle_group", export.ExerciseMuscles},
+		{"exercise_tags", "tag_id", "tags", export.ExerciseTags},
+	}
+	for _, j := range junctions {
+		query := fmt.Sprintf("INSERT INTO %s (exercise_id, %s) VALUES ($1, $2) ON CONFLICT DO NOTHING", j.table, j.column)
+		for _, row := range j.rows {
+			exerciseID, refID := remap("exercise", row.ExerciseID), remap(j.refTable, row.RefID)
+			logSQL(query, exerciseID, refID)
+			res, err := tx.Exec(query, exerciseID, refID)
+			if err != nil {
+				return skipped, fmt.Errorf("%s (%d, %d): %w", j.table, row.ExerciseID, row.RefID, err)
+			}
+			outcome := rowInserted
+			if n, _ := res.RowsAffected(); n == 0 {
+				outcome = rowSkipped
+			}
+			logImported(j.table, fmt.Sprintf("%d → %d", exerciseID, refID), outcome)
+		}
+	}
+
+	for _, table := range []string{"muscle_group", "training_type", "exercise_category", "equipment", "tags", "exercise"} {
+		if err := resetSequence(tx, table); err != nil {
+			return skipped, fmt.Errorf("reset %s sequence: %w", table, err)
+		}
+	}
+	return skipped, nil
+}
+
+// importNamedRow runs query, an insert of one exported row returning its id,
+// and returns the id the row has in the database: its exported id once
+// inserted, or the id of an existing row with the same name. It fails when the
+// exported id belongs to a row with another name.
+func importNamedRow(tx *sql.Tx, table, query string, id int, name string, args ...any) (int, bool, error) {
+	var dbID int
+	logSQL(query, args...)
+	err := tx.QueryRow(query, args...).Scan(&dbID)
+	if err == nil {
+		return dbID, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("%s %d: %w", table, id, err)
+	}
+
+	lookup := fmt.Sprintf("SELECT id FROM %s WHERE name = $1", table)
+	logSQL(lookup, name)
+	err = tx.QueryRow(lookup, name).Scan(&dbID)
+	if err == nil {
+		return dbID, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("%s %d: %w", table, id, err)
+	}
+
+	var existing string
+	lookup = fmt.Sprintf("SELECT name FROM %s WHERE id = $1", table)
+	logSQL(lookup, id)
+	if err := tx.QueryRow(lookup, id).Scan(&existing); err != nil {
+		return 0, false, fmt.Errorf("%s %d %q was not inserted: %w", table, id, name, err)
+	}
+	return 0, false, fmt.Errorf("%s %d %q: id %d already belongs to %q", table, id, name, id, existing)
+}
+
+// resetSequence moves a table's id sequence to its current max id so new rows
+// don't collide with explicitly imported ones
+func resetSequence(tx *sql.Tx, table string) error {
+	query := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM %s`, table, table)
+	logSQL(query)
+	_, err := tx.Exec(query)
+	return err
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// relationalStore is an in-memory set of named tables, id -> name, answering
+// ImportRelationalJSON's statements
+type relationalStore map[string]map[int64]string
+
+var (
+	relationalInsertPattern = regexp.MustCompile(`^INSERT INTO (\w+) \(id, name`)
+	relationalByNamePattern = regexp.MustCompile(`^SELECT id FROM (\w+) WHERE name = \$1`)
+	relationalByIDPattern   = regexp.MustCompile(`^SELECT name FROM (\w+) WHERE id = \$1`)
+)
+
+func (s relationalStore) respond(query string, args []driver.Value) fakeResult {
+	idRow := func(id int64) fakeResult {
+		return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{id}}}
+	}
+	switch {
+	case relationalInsertPattern.MatchString(query):
+		table := s[relationalInsertPattern.FindStringSubmatch(query)[1]]
+		id, name := args[0].(int64), args[1].(string)
+		if _, taken := table[id]; taken || slices.Contains(mapValues(table), name) {
+			return fakeResult{columns: []string{"id"}}
+		}
+		table[id] = name
+		return idRow(id)
+	case relationalByNamePattern.MatchString(query):
+		for id, name := range s[relationalByNamePattern.FindStringSubmatch(query)[1]] {
+			if name == args[0] {
+				return idRow(id)
+			}
+		}
+	case relationalByIDPattern.MatchString(query):
+		if name, ok := s[relationalByIDPattern.FindStringSubmatch(query)[1]][args[0].(int64)]; ok {
+			return fakeResult{columns: []string{"name"}, rows: [][]driver.Value{{name}}}
+		}
+	}
+	return fakeResult{}
+}
+
+func mapValues(m map[int64]string) []string {
+	var values []string
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+func writeExport(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newRelationalStore() relationalStore {
+	store := relationalStore{}
+	for _, table := range []string{"muscle_group", "training_type", "exercise_category", "equipment", "tags", "exercise"} {
+		store[table] = map[int64]string{}
+	}
+	return store
+}
+
+func TestImportRelationalJSONRemapsExistingNames(t *testing.T) {
+	store := newRelationalStore()
+	store["equipment"][7] = "Barbell"
+	store["exercise_category"][4] = "Legs"
+	db, rec := openFakeDB(t, store.respond)
+
+	path := writeExport(t, `{
+		"equipment": [{"id": 1, "name": "Barbell"}, {"id": 2, "name": "Bench"}],
+		"exercise_categories": [{"id": 4, "name": "Legs"}],
+		"exercises": [{"id": 10, "name": "Squat", "category_id": 4}],
+		"exercise_equipment": [{"exercise_id": 10, "equipment_id": 1}, {"exercise_id": 10, "equipment_id": 2}]
+	}`)
+	skipped, err := ImportRelationalJSON(db, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`exercise_category 4 "Legs": already exists`,
+		`equipment 1 "Barbell": already exists as id 7, linked to it`,
+	}
+	slices.Sort(skipped)
+	slices.Sort(want)
+	if !slices.Equal(skipped, want) {
+		t.Errorf("skipped = %q, want %q", skipped, want)
+	}
+
+	var links [][]any
+	for _, s := range statementsMatching(rec, "INSERT INTO exercise_equipment") {
+		links = append(links, s.Args)
+	}
+	if len(links) != 2 || links[0][1] != int64(7) || links[1][1] != int64(2) {
+		t.Errorf("links = %v, want Squat linked to the existing Barbell (7) and the new Bench (2)", links)
+	}
+}
+
+func TestImportRelationalJSONFailsOnIDOfAnotherName(t *testing.T) {
+	store := newRelationalStore()
+	store["equipment"][1] = "Kettlebell"
+	db, rec := openFakeDB(t, store.respond)
+
+	path := writeExport(t, `{"equipment": [{"id": 1, "name": "Barbell"}]}`)
+	_, err := ImportRelationalJSON(db, path)
+	if err == nil || !strings.Contains(err.Error(), `already belongs to "Kettlebell"`) {
+		t.Fatalf("err = %v, want a clash with Kettlebell", err)
+	}
+	statements := rec.Statements()
+	if last := statements[len(statements)-1].Query; last != "ROLLBACK" {
+		t.Errorf("transaction ended with %s, want ROLLBACK", last)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// reportTemplate renders a self-contained HTML import report: no external
+// stylesheets or scripts, so the file can be attached anywhere
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Import report {{.Generated.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 60rem; color: #222; }
+h1 { font-size: 1.4rem; }
+h2 { font-size: 1.1rem; margin-top: 2rem; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .35rem .6rem; border-bottom: 1px solid #ddd; vertical-align: top; }
+td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+.ok { color: #2e7d32; }
+.failed { color: #c62828; }
+.muted { color: #777; }
+</style>
+</head>
+<body>
+<h1>Import report</h1>
+<p class="muted">Generated {{.Generated.Format "Jan 2, 2006 15:04:05 MST"}} • {{len .Results}} uploads • {{.Inserted}} inserted • {{.Skipped}} skipped • {{.Failed}} failed • {{ms .Duration}}</p>
+
+<h2>Uploads</h2>
+<table>
+<tr><th>Type</th><th>File</th><th class="num">Parsed</th><th class="num">Inserted</th><th class="num">Skipped</th><th class="num">Time</th><th>Status</th></tr>
+{{- range .Results}}
+<tr>
+<td>{{.Type}}</td><td>{{.File}}</td>
+<td class="num">{{.Parsed}}</td><td class="num">{{.Inserted}}</td><td class="num">{{.Skipped}}</td><td class="num">{{ms .Duration}}</td>
+{{- if .Success}}<td class="ok">done</td>{{else}}<td class="failed">failed: {{.Error}}</td>{{end}}
+</tr>
+{{- end}}
+</table>
+
+<h2>New reference entities</h2>
+{{- if .Created.Total}}
+<table>
+<tr><th>Kind</th><th class="num">Count</th><th>Names</th></tr>
+{{- range .CreatedKinds}}{{if .Names}}
+<tr><td>{{.Kind}}</td><td class="num">{{len .Names}}</td><td>{{range $i, $n := .Names}}{{if $i}}, {{end}}{{$n}}{{end}}</td></tr>
+{{- end}}{{end}}
+</table>
+{{- else}}
+<p class="muted">None; every category, equipment, type, muscle and tag already existed.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// reportData is what reportTemplate renders
+type reportData struct {
+	Generated time.Time
+	Results   []UploadResult
+	Inserted  int
+	Skipped   int
+	Failed    int
+	Duration  time.Duration
+	Created   CreatedRefs
+}
+
+// createdKind is one kind of reference entity and the names created of it
+type createdKind struct {
+	Kind  string
+	Names []string
+}
+
+// CreatedKinds lists the created names per kind, in menu order
+func (d reportData) CreatedKinds() []createdKind {
+	return []createdKind{
+		{"Muscles", d.Created.Muscles},
+		{"Types", d.Created.Types},
+		{"Categories", d.Created.Categories},
+		{"Equipment", d.Created.Equipment},
+		{"Tags", d.Created.Tags},
+	}
+}
+
+// writeHTMLReport writes a self-contained HTML summary of result to path
+func writeHTMLReport(path string, result UploadResult) error {
+	return writeHTMLReports(path, []UploadResult{result})
+}
+
+// writeHTMLReports writes one HTML report covering several uploads, such as
+// a batch, with their totals and every reference entity they created
+func writeHTMLReports(path string, results []UploadResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	return writeReport(f, results)
+}
+
+// writeReport renders the report of results into f and closes it
+func writeReport(f *os.File, results []UploadResult) error {
+	data := reportData{Generated: time.Now(), Results: results}
+	for _, r := range results {
+		data.Inserted += r.Inserted
+		data.Skipped += r.Skipped
+		data.Duration += r.Duration
+		if !r.Success {
+			data.Failed++
+		}
+		data.Created.merge(r.Created)
+	}
+
+	if err := reportTemplate.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// saveHTMLReport writes results to a new report file and says where on the
+// result screen
+func saveHTMLReport(m model, results []UploadResult) (tea.Model, tea.Cmd) {
+	m.state = stateResult
+	f, err := createReportFile()
+	if err == nil {
+		err = writeReport(f, results)
+	}
+	if err != nil {
+		m.resultMsg = fmt.Sprintf("Error writing report: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+	m.resultMsg = fmt.Sprintf("Wrote a report of %d uploads to %s.\nPress enter or q to return to menu.", len(results), f.Name())
+	m.isError = false
+	return m, nil
+}
+
+// createReportFile creates a new HTML report file named for now, adding a
+// suffix instead of overwriting a report written in the same second
+func createReportFile() (*os.File, error) {
+	base := "import_report_" + time.Now().Format("20060102-150405")
+	path := base + ".html"
+	for i := 2; ; i++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+		path = fmt.Sprintf("%s_%d.html", base, i)
+	}
+}
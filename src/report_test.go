@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveHTMLReportKeepsEarlierReports(t *testing.T) {
+	t.Chdir(t.TempDir())
+	results := []UploadResult{{Type: "Upload Equipment", File: "equipment.csv", Inserted: 3, Success: true}}
+
+	for range 3 {
+		next, _ := saveHTMLReport(initialModel(OpenOffline(), ""), results)
+		if m := next.(model); m.isError {
+			t.Fatal(m.resultMsg)
+		}
+	}
+	reports, err := filepath.Glob("import_report_*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("got reports %q, want 3 files", reports)
+	}
+	for _, path := range reports {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("%s is empty: %v", path, err)
+		}
+	}
+}
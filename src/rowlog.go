@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// rowLogPath streams one JSON line per processed row to this file while an
+// upload runs, so a killed process still leaves a record of what was done
+// (--row-log or ROW_LOG). Empty disables it.
+var rowLogPath string
+
+// Row outcomes. Rows are written inside the upload transaction, so only those
+// followed by a committed marker for their table and source made it in.
+const (
+	rowInserted   = "inserted"
+	rowUpserted   = "upserted"
+	rowUnchanged  = "unchanged"
+	rowSkipped    = "skipped"
+	rowFailed     = "failed"
+	rowMerged     = "merged"
+	rowCommitted  = "committed"
+	rowRolledBack = "rolled_back"
+)
+
+// rowEvent is one line of the row log
+type rowEvent struct {
+	Time    time.Time `json:"time"`
+	Table   string    `json:"table"`
+	Source  string    `json:"source,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Outcome string    `json:"outcome"`
+	Rows    int       `json:"rows,omitempty"` // rows a marker, or a set-based statement like a promotion, covers
+	Error   string    `json:"error,omitempty"`
+}
+
+// rowLog is the open row log, shared by background uploads
+var rowLog struct {
+	sync.Mutex
+	f      *os.File
+	failed bool
+}
+
+// mutedRowLogs holds the transactions, and the handles, whose rows are not
+// logged because they never reach the database: dry runs and SQL previews.
+// Muting is per transaction, so a real upload running alongside still logs.
+var mutedRowLogs sync.Map
+
+// muteRowLog stops logging the rows of key, a *sql.Tx or a *sql.DB whose
+// upload transactions are all muted, until unmute is called
+func muteRowLog(key any) (unmute func()) {
+	mutedRowLogs.Store(key, true)
+	return func() { mutedRowLogs.Delete(key) }
+}
+
+// muteTxOf mutes tx when it was begun on a muted handle
+func muteTxOf(db *sql.DB, tx *sql.Tx) {
+	if _, muted := mutedRowLogs.Load(db); muted {
+		mutedRowLogs.Store(tx, true)
+	}
+}
+
+// logRow appends e, a row processed in tx, to the row log. Each line is
+// written straight to the file so it survives the process being killed.
+// Offline mode and muted transactions log nothing, as no row reaches a
+// database.
+func logRow(tx *sql.Tx, e rowEvent) {
+	if rowLogPath == "" || offlineMode {
+		return
+	}
+	if _, muted := mutedRowLogs.Load(tx); muted {
+		return
+	}
+	rowLog.Lock()
+	defer rowLog.Unlock()
+	if rowLog.failed {
+		return
+	}
+	if rowLog.f == nil {
+		f, err := os.OpenFile(rowLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			// Warn once rather than per row
+			logger.Warn("could not open row log", "path", rowLogPath, "err", err)
+			rowLog.failed = true
+			return
+		}
+		rowLog.f = f
+	}
+
+	e.Time = time.Now()
+	line, err := json.Marshal(e)
+	if err != nil {
+		logger.Warn("could not encode row event", "err", err)
+		return
+	}
+	if _, err := rowLog.f.Write(append(line, '\n')); err != nil {
+		logger.Warn("could not write row log", "err", err)
+	}
+}
+
+// logTxEnd writes the committed or rolled_back marker closing tx, a
+// transaction that processed rows rows of table, and forgets whether tx was
+// muted
+func logTxEnd(tx *sql.Tx, table, source string, rows int, err error) {
+	defer mutedRowLogs.Delete(tx)
+	e := rowEvent{Table: table, Source: source, Outcome: rowCommitted, Rows: rows}
+	if err != nil {
+		e.Outcome, e.Error = rowRolledBack, err.Error()
+	}
+	logRow(tx, e)
+}
+
+// logTxEndTables is logTxEnd for a transaction that wrote several tables: each
+// table in tables gets its own marker covering rows[table]
+func logTxEndTables(tx *sql.Tx, tables []string, source string, rows map[string]int, err error) {
+	defer mutedRowLogs.Delete(tx)
+	for _, table := range tables {
+		e := rowEvent{Table: table, Source: source, Outcome: rowCommitted, Rows: rows[table]}
+		if err != nil {
+			e.Outcome, e.Error = rowRolledBack, err.Error()
+		}
+		logRow(tx, e)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureRowLog points the row log at a temporary file and returns a function
+// reading back the events written so far
+func captureRowLog(t *testing.T) func() []rowEvent {
+	t.Helper()
+	closeRowLog := func() {
+		rowLog.Lock()
+		defer rowLog.Unlock()
+		if rowLog.f != nil {
+			rowLog.f.Close()
+		}
+		rowLog.f, rowLog.failed = nil, false
+	}
+	rowLogPath = filepath.Join(t.TempDir(), "rows.jsonl")
+	t.Cleanup(func() {
+		closeRowLog()
+		rowLogPath = ""
+	})
+
+	return func() []rowEvent {
+		t.Helper()
+		f, err := os.Open(rowLogPath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var events []rowEvent
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var e rowEvent
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, e)
+		}
+		return events
+	}
+}
+
+// outcomes lists each event as "table name outcome", dropping empty names
+func outcomes(events []rowEvent) []string {
+	out := make([]string, len(events))
+	for i, e := range events {
+		out[i] = strings.Join(strings.Fields(e.Table+" "+e.Name+" "+e.Outcome), " ")
+	}
+	return out
+}
+
+func TestDryRunDoesNotMuteOtherUploads(t *testing.T) {
+	events := captureRowLog(t)
+	dryDB, _ := openFakeDB(t, returningID())
+	realDB, _ := openFakeDB(t, returningID())
+
+	// The dry run's transaction stays open while the real upload runs
+	err := runSync(dryDB, "equipment", "dry.csv", true, func(dry *sql.Tx) error {
+		if _, _, _, _, err := BulkInsertNames(realDB, "equipment", []string{"Barbell"}, nil, "real.csv", nil); err != nil {
+			return err
+		}
+		logRow(dry, rowEvent{Table: "equipment", Source: "dry.csv", Name: "Bench", Outcome: rowInserted})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range events() {
+		if e.Source == "dry.csv" {
+			t.Errorf("dry run logged %+v", e)
+		}
+	}
+	if got := len(events()); got != 2 {
+		t.Errorf("got %d events, want the real upload's row and marker: %v", got, outcomes(events()))
+	}
+}
+
+func TestPreviewUploadSQLLogsNothing(t *testing.T) {
+	events := captureRowLog(t)
+	if _, err := PreviewUploadSQL(uploadTypes[uploadTypeIndex("equipment")], ".csv", []byte("name\nBarbell\n"), "e.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if logged := events(); len(logged) != 0 {
+		t.Errorf("preview logged %v", outcomes(logged))
+	}
+}
+
+func TestJunctionAndMergeUploadsAreLogged(t *testing.T) {
+	events := captureRowLog(t)
+
+	db, _ := openFakeDB(t, func(query string, args []driver.Value) fakeResult {
+		switch {
+		case strings.HasPrefix(query, "SELECT id FROM"):
+			if args[0] == "Nowhere" {
+				return fakeResult{}
+			}
+			return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{"id:" + args[0].(string)}}}
+		case strings.Contains(query, "RETURNING (xmax = 0)"):
+			return fakeResult{columns: []string{"inserted"}, rows: [][]driver.Value{{true}}}
+		}
+		return fakeResult{}
+	})
+	j := JunctionTable{Table: "exercise_equipment", LeftTable: "exercise", LeftColumn: "exercise_id", RightTable: "equipment", RightColumn: "equipment_id"}
+	if _, err := InsertJunctionPairs(db, j, []JunctionPair{{"Squat", "Barbell"}, {"Squat", "Nowhere"}}); err == nil {
+		t.Fatal("expected the unknown name to fail the upload")
+	}
+
+	store := &mergeStore{ids: map[string]bool{"db": true, "dbs": true}, links: map[string][]refLink{}}
+	mergeDB, _ := openFakeDB(t, store.respond)
+	if err := MergeReference(mergeDB, "equipment", "dbs", "db"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"exercise_equipment Squat → Barbell inserted",
+		"exercise_equipment Squat → Nowhere failed",
+		"exercise_equipment rolled_back",
+		"equipment dbs → db merged",
+		"equipment committed",
+	}
+	if got := outcomes(events()); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CheckSchema reports which of tables exist on the search path, so callers can
+// skip the ones a partial schema lacks instead of failing on them
+func CheckSchema(ctx context.Context, db *sql.DB, tables []string) (map[string]bool, error) {
+	exists := make(map[string]bool, len(tables))
+	if len(tables) == 0 {
+		return exists, nil
+	}
+	placeholders := make([]string, len(tables))
+	args := make([]any, len(tables))
+	for i, table := range tables {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = table
+	}
+	query := fmt.Sprintf(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = ANY(current_schemas(false)) AND table_name IN (%s)`, strings.Join(placeholders, ", "))
+	logSQL(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		exists[table] = true
+	}
+	return exists, rows.Err()
+}
+
+// DumpSchemaDDL writes CREATE TABLE statements for every table this tool
+// manages, as they currently exist in the database, to path
+func DumpSchemaDDL(db *sql.DB, path string) error {
+	var b strings.Builder
+	b.WriteString("-- Generated by fitrkr-cli from the live database schema\n")
+
+	for _, table := range promotableTables {
+		ddl, err := tableDDL(db, table)
+		if err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+		if ddl == "" {
+			continue // not created yet, e.g. migrations not applied
+		}
+		b.WriteString("\n")
+		b.WriteString(ddl)
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// tableDDL renders a table's columns and constraints from pg_catalog, or ""
+// if the table doesn't exist in the public schema
+func tableDDL(db *sql.DB, table string) (string, error) {
+	columnsQuery := `SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE n.nspname = 'public' AND c.relname = $1 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`
+	logSQL(columnsQuery, table)
+	rows, err := db.Query(columnsQuery, table)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var name, typ, def string
+		var notNull bool
+		if err := rows.Scan(&name, &typ, &notNull, &def); err != nil {
+			return "", err
+		}
+		line := fmt.Sprintf("    %s %s", name, typ)
+		if notNull {
+			line += " NOT NULL"
+		}
+		if def != "" {
+			line += " DEFAULT " + def
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	constraintsQuery := `SELECT con.conname, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public' AND c.relname = $1
+		ORDER BY con.contype DESC, con.conname`
+	logSQL(constraintsQuery, table)
+	crows, err := db.Query(constraintsQuery, table)
+	if err != nil {
+		return "", err
+	}
+	defer crows.Close()
+
+	for crows.Next() {
+		var name, def string
+		if err := crows.Scan(&name, &def); err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("    CONSTRAINT %s %s", name, def))
+	}
+	if err := crows.Err(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);\n", table, strings.Join(lines, ",\n")), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// similarityThreshold flags incoming names whose pg_trgm similarity to an
+// existing name reaches it; zero disables the check (SIMILARITY_THRESHOLD=0.6)
+var similarityThreshold float64
+
+// similarPageSize is how many flagged names the review screen shows at once
+const similarPageSize = 12
+
+// SimilarName is an incoming name and the existing names it nearly matches
+type SimilarName struct {
+	Name    string
+	Matches []string
+}
+
+// FindSimilarNames returns existing names in table whose trigram similarity to
+// name is at least threshold, most similar first. Requires the pg_trgm extension.
+func FindSimilarNames(db *sql.DB, table, name string, threshold float64) ([]string, error) {
+	query := fmt.Sprintf(`SELECT name FROM %s WHERE similarity(name, $1) >= $2 AND name <> $1
+		ORDER BY similarity(name, $1) DESC LIMIT 5`, table)
+	logSQL(query, name, threshold)
+	rows, err := db.Query(query, name, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []string
+	for rows.Next() {
+		var match string
+		if err := rows.Scan(&match); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+// flagSimilarNames runs FindSimilarNames for every name and returns the ones
+// with near-duplicates
+func flagSimilarNames(db *sql.DB, table string, names []string, threshold float64) ([]SimilarName, error) {
+	var flagged []SimilarName
+	for _, name := range dedupeNames(names) {
+		matches, err := FindSimilarNames(db, table, name, threshold)
+		if err != nil {
+			return nil, fmt.Errorf("similarity check for %s: %w", name, err)
+		}
+		if len(matches) > 0 {
+			flagged = append(flagged, SimilarName{Name: name, Matches: matches})
+		}
+	}
+	return flagged, nil
+}
+
+func updateSimilarReview(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.similarChoice > 0 {
+				m.similarChoice--
+			}
+		case "down", "j":
+			if m.similarChoice < len(m.similar)-1 {
+				m.similarChoice++
+			}
+		case " ", "x":
+			name := m.similar[m.similarChoice].Name
+			m.similarMerge[name] = !m.similarMerge[name]
+		case "q", "esc":
+			m.state = stateMenu
+			m.pendingNames, m.similar, m.similarMerge = nil, nil, nil
+		case "enter":
+			var names []string
+			for _, name := range m.pendingNames {
+				if !m.similarMerge[name] {
+					names = append(names, name)
+				}
+			}
+			m.pendingNames, m.similar, m.similarMerge = nil, nil, nil
+			return startNamesUploadCmd(m, names)
+		}
+	}
+	return m, nil
+}
+
+func viewSimilarReview(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle("Possible duplicates"))
+	parts = append(parts, "")
+	parts = append(parts, fmt.Sprintf("%d incoming names closely match existing ones. Mark the ones to merge into the existing name instead of inserting.", len(m.similar)))
+	parts = append(parts, "")
+
+	offset := max(0, m.similarChoice-similarPageSize+1)
+	end := min(offset+similarPageSize, len(m.similar))
+	for i := offset; i < end; i++ {
+		s := m.similar[i]
+		mark := "[keep] "
+		if m.similarMerge[s.Name] {
+			mark = "[merge]"
+		}
+		label := fmt.Sprintf("%s %s ≈ %s", mark, s.Name, strings.Join(s.Matches, ", "))
+		parts = append(parts, RenderFileItem(label, i == m.similarChoice, false))
+	}
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText("Navigation: ↑/↓ or j/k • Toggle merge: space/x • Upload: enter • Cancel: q/esc"))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteExts are the file extensions opened as SQLite source databases
+var sqliteExts = map[string]bool{
+	".db":      true,
+	".sqlite":  true,
+	".sqlite3": true,
+}
+
+// sqliteMapping maps each target table to the SQLite table, and optionally
+// columns, to read from (SQLITE_MAPPING=muscle_group=muscles.name;exercise=legacy_exercises).
+// Without columns, the columns named in the upload's header are read, so a
+// legacy id column is never taken for a name.
+var sqliteMapping = map[string]string{}
+
+// ParseSQLiteMapping parses SQLITE_MAPPING entries of target=table or
+// target=table.col1,col2 separated by semicolons
+func ParseSQLiteMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, entry := range SplitAndTrim(s, ";") {
+		target, source, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(target) == "" || strings.TrimSpace(source) == "" {
+			return nil, fmt.Errorf("mapping %q is not target=table[.columns]", entry)
+		}
+		mapping[strings.TrimSpace(target)] = strings.TrimSpace(source)
+	}
+	return mapping, nil
+}
+
+// ReadSQLiteAsCSV reads the source table mapped to target from the SQLite file
+// at path and renders it as CSV with a header row, so it goes through the same
+// parsers and insert functions as a flat file. header is the upload's
+// canonical header, used to pick the columns of an unmapped table.
+func ReadSQLiteAsCSV(path, target string, header []string) ([]byte, error) {
+	source, ok := sqliteMapping[target]
+	if !ok {
+		source = target
+	}
+	table, columns, _ := strings.Cut(source, ".")
+
+	src, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var selectList string
+	if columns != "" {
+		cols := SplitAndTrim(columns, ",")
+		for i, col := range cols {
+			cols[i] = pgx.Identifier{col}.Sanitize()
+		}
+		selectList = strings.Join(cols, ", ")
+	} else if selectList, err = sqliteHeaderColumns(src, table, target, header); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", selectList, pgx.Identifier{table}.Sanitize())
+	logSQL(query)
+	rows, err := src.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(names); err != nil {
+		return nil, err
+	}
+
+	values := make([]sql.NullString, len(names))
+	dest := make([]any, len(names))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(names))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			record[i] = v.String
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// sqliteHeaderColumns returns the select list for an unmapped SQLite table:
+// its columns named in header, matched ignoring case, in header order. A table
+// lacking a required column needs an explicit SQLITE_MAPPING instead.
+func sqliteHeaderColumns(src *sql.DB, table, target string, header []string) (string, error) {
+	query := fmt.Sprintf("SELECT * FROM %s LIMIT 0", pgx.Identifier{table}.Sanitize())
+	logSQL(query)
+	rows, err := src.Query(query)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", table, err)
+	}
+	columns, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return "", err
+	}
+
+	present := make(map[string]string, len(columns))
+	for _, col := range columns {
+		present[strings.ToLower(col)] = col
+	}
+	var selected, missing []string
+	for _, col := range header {
+		name, optional := strings.CutSuffix(col, "?")
+		if actual, ok := present[strings.ToLower(name)]; ok {
+			selected = append(selected, pgx.Identifier{actual}.Sanitize())
+		} else if !optional {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("SQLite table %s has no %s column; name the columns to read with SQLITE_MAPPING=%s=%s.<columns>",
+			table, strings.Join(missing, ", "), target, table)
+	}
+	if len(selected) == 0 {
+		return "", fmt.Errorf("no columns of SQLite table %s are known for %s; name them with SQLITE_MAPPING=%s=%s.<columns>",
+			table, target, target, table)
+	}
+	return strings.Join(selected, ", "), nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSQLite creates a SQLite file at a temporary path by running stmts
+func writeSQLite(t *testing.T, stmts ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestReadSQLiteAsCSV(t *testing.T) {
+	sqliteMapping = map[string]string{}
+	t.Cleanup(func() { sqliteMapping = map[string]string{} })
+	path := writeSQLite(t,
+		"CREATE TABLE equipment (id INTEGER PRIMARY KEY, name TEXT)",
+		"INSERT INTO equipment (name) VALUES ('Barbell'), ('Bench')",
+		"CREATE TABLE muscle_group (id INTEGER PRIMARY KEY, Name TEXT, parent TEXT)",
+		"INSERT INTO muscle_group (Name, parent) VALUES ('Upper Chest', 'Chest')",
+		"CREATE TABLE legacy (id INTEGER PRIMARY KEY, title TEXT)",
+		"INSERT INTO legacy (title) VALUES ('Squat')",
+	)
+
+	tests := []struct {
+		target  string
+		header  []string
+		mapping string
+		want    string
+		wantErr string
+	}{
+		{target: "equipment", header: nameHeader, want: "name\nBarbell\nBench\n"},
+		{target: "muscle_group", header: muscleHeader, want: "Name,parent\nUpper Chest,Chest\n"},
+		{target: "exercise_category", header: nameHeader, mapping: "legacy.title", want: "title\nSquat\n"},
+		{target: "training_type", header: nameHeader, mapping: "legacy", wantErr: "has no Name column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if tt.mapping != "" {
+				sqliteMapping[tt.target] = tt.mapping
+			}
+			data, err := ReadSQLiteAsCSV(path, tt.target, tt.header)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// sqlPreviewRows is how many parsed rows the SQL preview runs through the
+	// upload; the statements repeat per row, so a sample shows their shape
+	sqlPreviewRows = 3
+	// sqlPreviewArgs is how many bound values are shown per statement
+	sqlPreviewArgs = 8
+	// sqlPreviewPageSize is how many lines of the SQL preview show at once
+	sqlPreviewPageSize = 25
+)
+
+// PreviewUploadSQL runs the upload of data as uploadType against a recording
+// offline DB and returns the statements it issued. Nothing reaches the real
+// database.
+func PreviewUploadSQL(uploadType UploadType, ext string, data []byte, source string) ([]sqlStatement, error) {
+	db, rec := OpenRecording()
+	defer db.Close()
+
+	// The fake reports no optional tables; don't let it settle the cached
+	// answer for the real connection
+	synonymsReady := muscleSynonymsReady
+	muscleSynonymsReady = nil
+	defer func() { muscleSynonymsReady = synonymsReady }()
+	defer muteRowLog(db)()
+
+	var err error
+	switch {
+	case uploadType.Upload != nil:
+		_, _, err = uploadType.Upload(db, ext, data, source)
+	case uploadType.Parser == nil:
+		var rows []ExerciseUploadRow
+		if rows, _, _, err = ParseExercisesCSVReader(bytes.NewReader(data)); err == nil {
+			_, err = InsertExercises(db, rows[:min(len(rows), sqlPreviewRows)], source, nil)
+		}
+	default:
+		var names []string
+		if names, _, _, err = uploadType.Parser(ext, data); err != nil {
+			break
+		}
+		if uploadType.Custom != nil {
+			_, _, err = BulkInsertCustomNames(db, *uploadType.Custom, names, nil)
+		} else {
+			var parents []NameParent
+			if uploadType.Parents != nil {
+				if parents, err = uploadType.Parents(ext, data); err != nil {
+					break
+				}
+			}
+			_, _, _, _, err = BulkInsertNames(db, uploadType.Table, names, parents, source, nil)
+		}
+	}
+	return rec.Statements(), err
+}
+
+// describeStatements renders statements one per block: the query on a single
+// line, then a sample of its bound values
+func describeStatements(statements []sqlStatement) []string {
+	var lines []string
+	for i, st := range statements {
+		lines = append(lines, fmt.Sprintf("%3d  %s", i+1, strings.Join(strings.Fields(st.Query), " ")))
+		if len(st.Args) == 0 {
+			continue
+		}
+		shown := make([]string, 0, sqlPreviewArgs)
+		for j, arg := range st.Args[:min(len(st.Args), sqlPreviewArgs)] {
+			shown = append(shown, fmt.Sprintf("$%d=%v", j+1, arg))
+		}
+		args := strings.Join(shown, " ")
+		if extra := len(st.Args) - sqlPreviewArgs; extra > 0 {
+			args += fmt.Sprintf(" … %d more", extra)
+		}
+		lines = append(lines, "     "+args)
+	}
+	return lines
+}
+
+// openSQLPreview shows the SQL uploading the selected file would run
+func openSQLPreview(m model) (tea.Model, tea.Cmd) {
+	filename := m.fileList[m.fileChoice]
+	if filename == "Back" {
+		return m, nil
+	}
+	uploadType := m.selectedUploadType()
+	data, ext, err := readUploadFile(filepath.Join(dataDir, filename), uploadType)
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error reading file: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+
+	statements, err := PreviewUploadSQL(uploadType, ext, data, filename)
+	m.sqlPreviewLines = describeStatements(statements)
+	if err != nil {
+		m.sqlPreviewLines = append(m.sqlPreviewLines, "", RenderAuditFailure(fmt.Sprintf("The upload would stop here: %v", err)))
+	}
+	m.sqlPreviewTitle = fmt.Sprintf("SQL for %s (%s)", filename, uploadType.Label)
+	m.sqlPreviewOffset = 0
+	m.state = stateSQLPreview
+	return m, nil
+}
+
+func updateSQLPreview(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "k":
+			if m.sqlPreviewOffset > 0 {
+				m.sqlPreviewOffset--
+			}
+		case "down", "j":
+			if m.sqlPreviewOffset < len(m.sqlPreviewLines)-sqlPreviewPageSize {
+				m.sqlPreviewOffset++
+			}
+		case "pgup":
+			m.sqlPreviewOffset = max(m.sqlPreviewOffset-sqlPreviewPageSize, 0)
+		case "pgdown":
+			m.sqlPreviewOffset = max(min(m.sqlPreviewOffset+sqlPreviewPageSize, len(m.sqlPreviewLines)-sqlPreviewPageSize), 0)
+		case "q", "esc":
+			m.sqlPreviewLines = nil
+			m.state = stateFileSelector
+		}
+	}
+	return m, nil
+}
+
+func viewSQLPreview(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle(m.sqlPreviewTitle))
+	parts = append(parts, RenderHelpText(fmt.Sprintf("Nothing is executed; ids come from a stand-in database. Exercises show the first %d rows.", sqlPreviewRows)))
+	parts = append(parts, "")
+
+	end := min(m.sqlPreviewOffset+sqlPreviewPageSize, len(m.sqlPreviewLines))
+	parts = append(parts, m.sqlPreviewLines[m.sqlPreviewOffset:end]...)
+
+	parts = append(parts, "")
+	parts = append(parts, RenderHelpText(fmt.Sprintf("Lines %d–%d of %d • Scroll: ↑/↓ or j/k, pgup/pgdown • Back: q/esc",
+		min(m.sqlPreviewOffset+1, end), end, len(m.sqlPreviewLines))))
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
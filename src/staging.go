@@ -0,0 +1,210 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// stagingSchema, when set, makes uploads land in a separate schema for review
+// before being promoted into the real tables (STAGING_SCHEMA=staging)
+var stagingSchema string
+
+// promotableTables lists every table uploads write to, parents before the
+// junction tables that reference them
+var promotableTables = []string{
+	"muscle_group",
+	"training_type",
+	"exercise_category",
+	"equipment",
+	"tags",
+	"exercise",
+	"exercise_equipment",
+	"exercise_training_types",
+	"exercise_muscles",
+	"exercise_tags",
+}
+
+// PrepareStaging creates the staging schema with an empty copy of each table.
+// The copies share the real tables' id sequences so promoted ids never collide.
+func PrepareStaging(db *sql.DB, tables []string) error {
+	schema := pgx.Identifier{stagingSchema}.Sanitize()
+	stmts := []string{fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", schema)}
+	for _, table := range tables {
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (LIKE public.%s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING INDEXES)", schema, table, table))
+	}
+
+	for _, stmt := range stmts {
+		logSQL(stmt)
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// promoteForeignKeys lists, for each promotable table, the columns holding ids
+// of another promotable table. Staged ids mean nothing in the real tables, so
+// promotion rewrites these through the staged-to-real id map of the target.
+var promoteForeignKeys = map[string][]struct {
+	column string
+	table  string
+}{
+	"muscle_group":            {{"parent_id", "muscle_group"}},
+	"exercise":                {{"category_id", "exercise_category"}, {"parent_id", "exercise"}},
+	"exercise_equipment":      {{"exercise_id", "exercise"}, {"equipment_id", "equipment"}},
+	"exercise_training_types": {{"exercise_id", "exercise"}, {"training_type_id", "training_type"}},
+	"exercise_muscles":        {{"exercise_id", "exercise"}, {"muscle_group_id", "muscle_group"}},
+	"exercise_tags":           {{"exercise_id", "exercise"}, {"tag_id", "tags"}},
+}
+
+// PromoteStaging copies every staged row into the real tables in one
+// transaction, then empties the staging tables. Named rows are matched by
+// name: one that already exists is kept as it is and the staged rows pointing
+// at it are linked to it. tables must list parents before the rows that
+// reference them, as promotableTables does.
+func PromoteStaging(db *sql.DB, tables []string) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		logTxEndTables(tx, tables, stagingSchema, counts, err)
+	}()
+
+	schema := pgx.Identifier{stagingSchema}.Sanitize()
+	for _, table := range tables {
+		columns, err := tableColumns(tx, table)
+		if err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+		promoted, err := promoteTable(tx, schema, table, columns)
+		if err != nil {
+			return fmt.Errorf("promote %s: %w", table, err)
+		}
+		// The promotion is one statement per table, so it is logged as one event
+		counts[table] = promoted
+		logRow(tx, rowEvent{Table: table, Source: stagingSchema, Outcome: rowUpserted, Rows: promoted})
+	}
+
+	// Children first so truncating parents never trips a foreign key
+	for i := len(tables) - 1; i >= 0; i-- {
+		stmt := fmt.Sprintf("DELETE FROM %s.%s", schema, tables[i])
+		logSQL(stmt)
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("clear staged %s: %w", tables[i], err)
+		}
+	}
+	return nil
+}
+
+// promoteTable copies the staged rows of one table into the real table,
+// rewriting its foreign keys to real ids, and returns how many staged rows it
+// promoted. A table with a name column also records which real id each staged
+// id became in promote_<table>, a temporary table dropped on commit, for the
+// tables promoted after it.
+func promoteTable(tx *sql.Tx, schema, table string, columns []string) (int, error) {
+	refs := map[string]string{}
+	for _, fk := range promoteForeignKeys[table] {
+		if slices.Contains(columns, fk.column) {
+			refs[fk.column] = fk.table
+		}
+	}
+
+	// A row's parent in its own table may be promoted after it, so
+	// self-references are set once the whole table is mapped
+	var insertCols, selectCols, selfRefs []string
+	named := slices.Contains(columns, "name")
+	for _, col := range columns {
+		ref, isRef := refs[col]
+		switch {
+		case named && col == "id":
+			continue
+		case isRef && ref == table:
+			selfRefs = append(selfRefs, col)
+			continue
+		case isRef:
+			selectCols = append(selectCols, fmt.Sprintf("(SELECT public_id FROM promote_%s WHERE staged_id = s.%s)", ref, col))
+		default:
+			selectCols = append(selectCols, "s."+col)
+		}
+		insertCols = append(insertCols, col)
+	}
+
+	if !named {
+		stmt := fmt.Sprintf("INSERT INTO public.%s (%s) SELECT %s FROM %s.%s s ON CONFLICT DO NOTHING",
+			table, strings.Join(insertCols, ", "), strings.Join(selectCols, ", "), schema, table)
+		return execCount(tx, stmt)
+	}
+
+	stmt := fmt.Sprintf(`CREATE TEMP TABLE promote_%s ON COMMIT DROP AS
+		SELECT id AS staged_id, id AS public_id, true AS inserted FROM public.%s WITH NO DATA`, table, table)
+	logSQL(stmt)
+	if _, err := tx.Exec(stmt); err != nil {
+		return 0, err
+	}
+	promoted, err := execCount(tx, fmt.Sprintf(`WITH promoted AS (
+			INSERT INTO public.%s (%s) SELECT %s FROM %s.%s s
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id, name, (xmax = 0) AS inserted)
+		INSERT INTO promote_%s SELECT s.id, p.id, p.inserted FROM promoted p JOIN %s.%s s ON s.name = p.name`,
+		table, strings.Join(insertCols, ", "), strings.Join(selectCols, ", "), schema, table, table, schema, table))
+	if err != nil {
+		return 0, err
+	}
+	// Rows that already existed keep their own parent
+	for _, col := range selfRefs {
+		stmt := fmt.Sprintf(`UPDATE public.%s t SET %s = parent.public_id
+			FROM promote_%s child JOIN %s.%s s ON s.id = child.staged_id JOIN promote_%s parent ON parent.staged_id = s.%s
+			WHERE t.id = child.public_id AND child.inserted`, table, col, table, schema, table, table, col)
+		logSQL(stmt)
+		if _, err := tx.Exec(stmt); err != nil {
+			return 0, err
+		}
+	}
+	return promoted, nil
+}
+
+// execCount runs stmt in tx and returns how many rows it affected
+func execCount(tx *sql.Tx, stmt string) (int, error) {
+	logSQL(stmt)
+	res, err := tx.Exec(stmt)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// tableColumns returns the column names of a real table in declaration order
+func tableColumns(tx *sql.Tx, table string) ([]string, error) {
+	query := `SELECT column_name FROM information_schema.columns WHERE table_schema = 'public' AND table_name = $1 ORDER BY ordinal_position`
+	logSQL(query, table)
+	rows, err := tx.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table not found")
+	}
+	return columns, rows.Err()
+}
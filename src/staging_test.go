@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestPromoteStagingRemapsIDsByName(t *testing.T) {
+	stagingSchema = "Review Batch"
+	t.Cleanup(func() { stagingSchema = "" })
+
+	columns := map[string][]string{
+		"exercise_category": {"id", "name"},
+		"muscle_group":      {"id", "name", "parent_id"},
+		"exercise":          {"id", "name", "category_id", "parent_id"},
+		"exercise_muscles":  {"exercise_id", "muscle_group_id"},
+	}
+	db, rec := openFakeDB(t, func(query string, args []driver.Value) fakeResult {
+		if !strings.Contains(query, "information_schema.columns") {
+			return fakeResult{}
+		}
+		result := fakeResult{columns: []string{"column_name"}}
+		for _, col := range columns[args[0].(string)] {
+			result.rows = append(result.rows, []driver.Value{col})
+		}
+		return result
+	})
+
+	tables := []string{"exercise_category", "muscle_group", "exercise", "exercise_muscles"}
+	if err := PromoteStaging(db, tables); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range rec.Statements() {
+		if strings.Contains(s.Query, "Review Batch") && !strings.Contains(s.Query, `"Review Batch"`) {
+			t.Errorf("staging schema not quoted:\n%s", s.Query)
+		}
+	}
+
+	exercise := statementsMatching(rec, "INSERT INTO public.exercise (")
+	if len(exercise) != 1 {
+		t.Fatalf("got %d exercise inserts, want 1", len(exercise))
+	}
+	for _, want := range []string{
+		"INSERT INTO public.exercise (name, category_id)",
+		"(SELECT public_id FROM promote_exercise_category WHERE staged_id = s.category_id)",
+		"ON CONFLICT (name) DO UPDATE",
+		"INSERT INTO promote_exercise",
+	} {
+		if !strings.Contains(exercise[0].Query, want) {
+			t.Errorf("exercise promotion lacks %q:\n%s", want, exercise[0].Query)
+		}
+	}
+
+	junction := statementsMatching(rec, "INSERT INTO public.exercise_muscles")
+	if len(junction) != 1 ||
+		!strings.Contains(junction[0].Query, "FROM promote_exercise WHERE staged_id = s.exercise_id") ||
+		!strings.Contains(junction[0].Query, "FROM promote_muscle_group WHERE staged_id = s.muscle_group_id") {
+		t.Errorf("junction rows not remapped to real ids: %v", junction)
+	}
+
+	if parents := statementsMatching(rec, "SET parent_id = parent.public_id"); len(parents) != 2 {
+		t.Errorf("got %d parent remaps, want one each for muscle_group and exercise", len(parents))
+	}
+
+	statements := rec.Statements()
+	if last := statements[len(statements)-1].Query; last != "COMMIT" {
+		t.Errorf("transaction ended with %s, want COMMIT", last)
+	}
+}
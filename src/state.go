@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// stateFile persists tool state between runs
+const stateFile = ".fitrkr-state.json"
+
+// persistedState is the on-disk contents of stateFile
+type persistedState struct {
+	// Checkpoints maps a file's content hash to the number of exercise rows already committed
+	Checkpoints map[string]int `json:"checkpoints,omitempty"`
+	// LastRun is when the tool was last started
+	LastRun time.Time `json:"last_run,omitzero"`
+}
+
+// loadState reads the state file, returning empty state if it doesn't exist yet
+func loadState() (persistedState, error) {
+	var s persistedState
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+func saveState(s persistedState) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(stateFile, data, 0o644)
+}
+
+// updateState loads the state, applies fn and writes it back
+func updateState(fn func(*persistedState)) error {
+	s, err := loadState()
+	if err != nil {
+		return err
+	}
+	fn(&s)
+	return saveState(s)
+}
+
+// contentHash identifies upload data independent of its file name
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// getCheckpoint returns how many rows of the data with this hash were already committed
+func getCheckpoint(hash string) int {
+	s, err := loadState()
+	if err != nil {
+		logger.Warn("could not read state file", "err", err)
+		return 0
+	}
+	return s.Checkpoints[hash]
+}
+
+func setCheckpoint(hash string, done int) error {
+	return updateState(func(s *persistedState) {
+		if s.Checkpoints == nil {
+			s.Checkpoints = make(map[string]int)
+		}
+		s.Checkpoints[hash] = done
+	})
+}
+
+func clearCheckpoint(hash string) error {
+	return updateState(func(s *persistedState) {
+		delete(s.Checkpoints, hash)
+	})
+}
+
+// recordRun stores the current time as the last run and returns the previous one
+func recordRun() time.Time {
+	var previous time.Time
+	err := updateState(func(s *persistedState) {
+		previous = s.LastRun
+		s.LastRun = time.Now()
+	})
+	if err != nil {
+		logger.Warn("could not record run in state file", "err", err)
+	}
+	return previous
+}
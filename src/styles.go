@@ -2,8 +2,12 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
 // Dark Mode Color Palette with Pastel Pink
@@ -21,33 +25,64 @@ const (
 	LavenderPurple = "#D1B3FF"
 	SoftCream      = "#FFF9FC"
 	MidGray        = "#AAAAAA"
+	SoftYellow     = "#FFE9A8"
 	CharcoalGray   = "#4A4A4A"
 	SoftGray       = "#8A8A8A"
 )
 
+// ansiPalette stands in for the hex palette on 16-color terminals, where the
+// automatic conversion turns the pastels into muddy or unreadable neighbours.
+// 256-color terminals convert the hex values well enough themselves.
+var ansiPalette = map[string]string{
+	PastelPink:     "13", // bright magenta
+	SoftPink:       "13",
+	DeepPink:       "5", // magenta
+	BlushPink:      "13",
+	DarkBackground: "0",  // black
+	OffWhite:       "15", // bright white
+	MintGreen:      "10", // bright green
+	LavenderPurple: "12", // bright blue
+	SoftCream:      "15",
+	MidGray:        "8",  // bright black
+	SoftYellow:     "11", // bright yellow
+	CharcoalGray:   "8",
+	SoftGray:       "8",
+	"#FF6B9D":      "9", // bright red
+	"#FFFFFF":      "15",
+	"#A1E9C5":      "2", // green
+	"#5FD38D":      "10",
+	"#E6C229":      "11",
+	"#666666":      "8",
+}
+
+// paletteColor is a palette hex color along with its 16-color stand-in
+func paletteColor(hex string) lipgloss.TerminalColor {
+	return lipgloss.CompleteColor{TrueColor: hex, ANSI256: hex, ANSI: ansiPalette[hex]}
+}
+
 // Style definitions using lipgloss
 var (
 	BaseStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(CharcoalGray)).
-			Background(lipgloss.Color(SoftCream))
+			Foreground(paletteColor(CharcoalGray)).
+			Background(paletteColor(SoftCream))
 
 	TitleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(DarkBackground)).
+			Foreground(paletteColor(DarkBackground)).
 			Width(40).
 			Align(lipgloss.Left).
 			Bold(true).
 			Padding(1, 2)
 
 	MenuItemStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(DarkBackground)).
+			Foreground(paletteColor(DarkBackground)).
 			PaddingLeft(1).
 			PaddingRight(1).
 			MarginBottom(0).
 			Width(30)
 
 	SelectedMenuItemStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color(DarkBackground)).
-				Background(lipgloss.Color(BlushPink)).
+				Foreground(paletteColor(DarkBackground)).
+				Background(paletteColor(BlushPink)).
 				Bold(true).
 				PaddingLeft(1).
 				PaddingRight(1).
@@ -55,131 +90,464 @@ var (
 				Width(30)
 
 	CursorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(PastelPink)).
+			Foreground(paletteColor(PastelPink)).
 			Bold(true)
 
 	CountBadgeStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(DarkBackground)).
-			Background(lipgloss.Color(MintGreen)).
+			Foreground(paletteColor(DarkBackground)).
+			Background(paletteColor(MintGreen)).
 			Padding(0, 1).
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(MintGreen))
+			BorderForeground(paletteColor(MintGreen))
 
 	SuccessStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(DarkBackground)).
-			Background(lipgloss.Color(MintGreen)).
+			Foreground(paletteColor(DarkBackground)).
+			Background(paletteColor(MintGreen)).
 			Padding(1, 2).
 			MarginTop(1).
 			MarginBottom(1).
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(MintGreen))
+			BorderForeground(paletteColor(MintGreen))
 
 	ErrorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(lipgloss.Color("#FF6B9D")).
+			Foreground(paletteColor("#FFFFFF")).
+			Background(paletteColor("#FF6B9D")).
 			Padding(1, 2).
 			MarginTop(1).
 			MarginBottom(1).
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#FF6B9D"))
+			BorderForeground(paletteColor("#FF6B9D"))
 
 	HelpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(MidGray)).
+			Foreground(paletteColor(MidGray)).
 			Italic(true).
 			MarginTop(1)
 
 	ContainerStyle = lipgloss.NewStyle().
 			Padding(1, 3).
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(PastelPink))
+			BorderForeground(paletteColor(PastelPink))
 
 	FileItemStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(DarkBackground)).
+			Foreground(paletteColor(DarkBackground)).
 			PaddingLeft(2).
 			PaddingRight(2)
 
 	SelectedFileItemStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color(DarkBackground)).
-				Background(lipgloss.Color(MintGreen)).
+				Foreground(paletteColor(DarkBackground)).
+				Background(paletteColor(MintGreen)).
 				Bold(true).
 				PaddingLeft(2).
 				PaddingRight(2).
 				Border(lipgloss.NormalBorder(), false, false, false, true).
-				BorderForeground(lipgloss.Color("#A1E9C5"))
+				BorderForeground(paletteColor("#A1E9C5"))
 
 	BackOptionStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(MidGray)).
+			Foreground(paletteColor(MidGray)).
 			Italic(true).
 			PaddingLeft(2).
 			PaddingRight(2)
 
+	ProgressFilledStyle = lipgloss.NewStyle().
+				Foreground(paletteColor(PastelPink))
+
+	ProgressEmptyStyle = lipgloss.NewStyle().
+				Foreground(paletteColor(MidGray))
+
+	AuditFailureStyle = lipgloss.NewStyle().
+				Foreground(paletteColor("#FF6B9D"))
+
+	RowNewStyle = lipgloss.NewStyle().
+			Foreground(paletteColor("#5FD38D"))
+
+	RowUpdateStyle = lipgloss.NewStyle().
+			Foreground(paletteColor("#E6C229"))
+
+	RowUnchangedStyle = lipgloss.NewStyle().
+				Foreground(paletteColor(MidGray))
+
+	RowInvalidStyle = lipgloss.NewStyle().
+			Foreground(paletteColor("#FF6B9D"))
+
+	QueryHeaderStyle = lipgloss.NewStyle().
+				Foreground(paletteColor(DeepPink)).
+				Bold(true)
+
+	QueryCellStyle = lipgloss.NewStyle().
+			Foreground(paletteColor(DarkBackground))
+
 	SelectedBackOptionStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color(DarkBackground)).
-				Background(lipgloss.Color(MidGray)).
+				Foreground(paletteColor(DarkBackground)).
+				Background(paletteColor(MidGray)).
 				Bold(true).
 				Italic(true).
 				PaddingLeft(2).
 				PaddingRight(2).
 				Border(lipgloss.NormalBorder(), false, false, false, true).
-				BorderForeground(lipgloss.Color("#666666"))
+				BorderForeground(paletteColor("#666666"))
 )
 
+// Cursor glyphs and selection highlighting, see ConfigureCursor
+var (
+	cursorForward       = "❯ "
+	cursorBack          = "❮ "
+	backgroundHighlight = true
+)
+
+// ConfigureCursor sets the selection cursor (CURSOR) and whether the selected
+// row also gets a background (HIGHLIGHT=background) or only the prefix marker
+// (HIGHLIGHT=marker). Limited terminals default to an ASCII cursor.
+func ConfigureCursor(cursor, highlight string) {
+	if cursor == "" && limitedTerminal() {
+		cursor = "> "
+	}
+	if cursor != "" {
+		cursorForward = cursor
+		cursorBack = cursor
+		if cursor == "> " {
+			cursorBack = "< "
+		}
+	}
+	backgroundHighlight = highlight != "marker"
+}
+
+// limitedTerminal reports whether the terminal is unlikely to render the
+// unicode cursor glyphs, e.g. the linux console or a non-UTF-8 locale
+func limitedTerminal() bool {
+	switch os.Getenv("TERM") {
+	case "dumb", "linux", "vt100", "vt220", "ansi":
+		return true
+	}
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(key); locale != "" {
+			locale = strings.ToUpper(locale)
+			return !strings.Contains(locale, "UTF-8") && !strings.Contains(locale, "UTF8")
+		}
+	}
+	return false
+}
+
+// colorProfiles are the COLOR_PROFILE values, for terminals that misreport
+// what they support, such as tmux without truecolor passthrough
+var colorProfiles = map[string]termenv.Profile{
+	"truecolor": termenv.TrueColor,
+	"256":       termenv.ANSI256,
+	"16":        termenv.ANSI,
+	"none":      termenv.Ascii,
+}
+
+// ConfigureColors settles the terminal's color profile before anything
+// renders: detected from the terminal, or forced by COLOR_PROFILE (truecolor,
+// 256, 16 or none). Without color, selection is shown by the cursor marker and
+// bold text, as a background highlight wouldn't show.
+func ConfigureColors(profile string) error {
+	if profile != "" {
+		p, ok := colorProfiles[strings.ToLower(profile)]
+		if !ok {
+			return fmt.Errorf("unknown color profile %q; expected truecolor, 256, 16 or none", profile)
+		}
+		lipgloss.SetColorProfile(p)
+	}
+	if lipgloss.ColorProfile() == termenv.Ascii {
+		backgroundHighlight = false
+	}
+	return nil
+}
+
+// accessibleMode renders a plain layout for screen readers and limited
+// terminals: no colors, borders or badges, and numbered menu options with
+// counts inline (ACCESSIBLE=1)
+var accessibleMode bool
+
+// UseAccessibleStyles switches to accessibleMode, replacing every style with an
+// unstyled one so screens render as plain text
+func UseAccessibleStyles() {
+	accessibleMode = true
+	for _, style := range []*lipgloss.Style{
+		&BaseStyle, &TitleStyle, &MenuItemStyle, &SelectedMenuItemStyle, &CursorStyle,
+		&CountBadgeStyle, &SuccessStyle, &ErrorStyle, &HelpStyle, &ContainerStyle,
+		&FileItemStyle, &SelectedFileItemStyle, &BackOptionStyle, &ProgressFilledStyle,
+		&ProgressEmptyStyle, &AuditFailureStyle, &RowNewStyle, &RowUpdateStyle,
+		&RowUnchangedStyle, &RowInvalidStyle, &QueryHeaderStyle, &QueryCellStyle,
+		&SelectedBackOptionStyle,
+	} {
+		*style = lipgloss.NewStyle()
+	}
+	cursorForward, cursorBack = "> ", "< "
+	backgroundHighlight = false
+}
+
+// selectedStyle returns the highlight style, or the plain style plus bold when
+// selection is shown by the prefix marker alone
+func selectedStyle(highlighted, plain lipgloss.Style) lipgloss.Style {
+	if accessibleMode {
+		return plain
+	}
+	if backgroundHighlight {
+		return highlighted
+	}
+	return plain.Bold(true)
+}
+
+// cursorPad is blank space as wide as the cursor, for unselected rows
+func cursorPad() string {
+	return strings.Repeat(" ", lipgloss.Width(cursorForward))
+}
+
 func RenderMenuTitle(text string) string {
 	return TitleStyle.Render(text)
 }
 
-func RenderAppHeader() string {
-	return TitleStyle.Render("FitTkr CLI")
+func RenderAppHeader(schemaVersion int) string {
+	title := "FitTkr CLI"
+	if offlineMode {
+		title += " (offline)"
+	}
+	if schemaVersion >= 0 {
+		title += fmt.Sprintf(" • schema v%d", schemaVersion)
+		if pending := latestSchemaVersion() - schemaVersion; pending > 0 {
+			title += fmt.Sprintf(" (%d pending)", pending)
+		}
+	}
+	return TitleStyle.Render(title)
 }
 
-func RenderCountBadge(count int) string {
+// recencyBadgeStyle colors a count badge by when its table was last imported:
+// green today, yellow within a week, gray before that. Tables with no import
+// time keep the default badge.
+func recencyBadgeStyle(lastImport time.Time) lipgloss.Style {
+	if lastImport.IsZero() {
+		return CountBadgeStyle
+	}
+	now := time.Now()
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch {
+	case !lastImport.Before(today):
+		return CountBadgeStyle
+	case now.Sub(lastImport) <= 7*24*time.Hour:
+		return CountBadgeStyle.Background(paletteColor(SoftYellow)).BorderForeground(paletteColor(SoftYellow))
+	default:
+		return CountBadgeStyle.Background(paletteColor(MidGray)).BorderForeground(paletteColor(MidGray))
+	}
+}
+
+func RenderCountBadge(count int, lastImport time.Time) string {
+	if accessibleMode {
+		switch {
+		case count == countNA:
+			return "(n/a)"
+		case count < 0:
+			return ""
+		}
+		return fmt.Sprintf("(%d)", count)
+	}
+	if count == countNA {
+		return CountBadgeStyle.Background(paletteColor(MidGray)).BorderForeground(paletteColor(MidGray)).Render("n/a")
+	}
 	if count < 0 {
 		// Reserve space using "00" width for alignment
 		width := lipgloss.Width(CountBadgeStyle.Render("00"))
 		return lipgloss.NewStyle().Width(width).Render(" ")
 	}
-	return CountBadgeStyle.Render(fmt.Sprintf("%d", count))
+	return recencyBadgeStyle(lastImport).Render(fmt.Sprintf("%d", count))
+}
+
+func RenderCountBadgePercent(percent float64, lastImport time.Time) string {
+	if accessibleMode {
+		return fmt.Sprintf("(%.0f%%)", percent)
+	}
+	return recencyBadgeStyle(lastImport).Render(fmt.Sprintf("%.0f%%", percent))
+}
+
+func RenderLoadingBadge() string {
+	if accessibleMode {
+		return "(loading)"
+	}
+	return CountBadgeStyle.Render("…")
+}
+
+func RenderMenuItem(text string, isSelected bool, count int, lastImport time.Time) string {
+	return RenderMenuItemWithBadge(text, isSelected, RenderCountBadge(count, lastImport))
 }
 
-func RenderMenuItem(text string, isSelected bool, count int) string {
-	countBadge := RenderCountBadge(count)
-	var styledText string
+func RenderMenuItemPercent(text string, isSelected bool, percent float64, lastImport time.Time) string {
+	return RenderMenuItemWithBadge(text, isSelected, RenderCountBadgePercent(percent, lastImport))
+}
 
+func RenderMenuItemWithBadge(text string, isSelected bool, badge string) string {
 	if isSelected {
-		cursor := CursorStyle.Render("❯ ")
-		styledText = SelectedMenuItemStyle.Render(text)
-		return lipgloss.JoinHorizontal(lipgloss.Top, cursor, styledText, countBadge)
+		cursor := CursorStyle.Render(cursorForward)
+		return lipgloss.JoinHorizontal(lipgloss.Top, cursor, selectedStyle(SelectedMenuItemStyle, MenuItemStyle).Render(text), badge)
 	}
 
-	styledText = MenuItemStyle.Render(text)
-	return lipgloss.JoinHorizontal(lipgloss.Top, "  ", styledText, countBadge)
+	return lipgloss.JoinHorizontal(lipgloss.Top, cursorPad(), MenuItemStyle.Render(text), badge)
 }
 
 func RenderFileItem(filename string, isSelected bool, isBackOption bool) string {
 	if isBackOption {
 		if isSelected {
-			cursor := CursorStyle.Render("❮ ")
-			return cursor + SelectedBackOptionStyle.Render(filename)
+			cursor := CursorStyle.Render(cursorBack)
+			return cursor + selectedStyle(SelectedBackOptionStyle, BackOptionStyle).Render(filename)
 		}
-		return "  " + BackOptionStyle.Render(filename)
+		return cursorPad() + BackOptionStyle.Render(filename)
 	}
 
 	if isSelected {
-		cursor := CursorStyle.Render("❯ ")
-		return cursor + SelectedFileItemStyle.Render("📄 "+filename)
+		cursor := CursorStyle.Render(cursorForward)
+		if accessibleMode {
+			return cursor + filename
+		}
+		return cursor + selectedStyle(SelectedFileItemStyle, FileItemStyle).Render("📄 "+filename)
 	}
 
-	return "  " + FileItemStyle.Render("📄 "+filename)
+	if accessibleMode {
+		return cursorPad() + filename
+	}
+	return cursorPad() + FileItemStyle.Render("📄 "+filename)
+}
+
+// RenderDeletedItem renders a soft-deleted entry, dimmed
+func RenderDeletedItem(name string, isSelected bool) string {
+	if accessibleMode {
+		cursor := cursorPad()
+		if isSelected {
+			cursor = cursorForward
+		}
+		return cursor + name + " (deleted)"
+	}
+	if isSelected {
+		return CursorStyle.Render(cursorForward) + selectedStyle(SelectedBackOptionStyle, BackOptionStyle).Render("🗑 "+name)
+	}
+	return cursorPad() + BackOptionStyle.Render("🗑 "+name)
+}
+
+// maxMessageWidth caps the result boxes on very wide terminals
+const maxMessageWidth = 100
+
+// messageWidth is the width result boxes wrap at, set from the terminal size;
+// 0 until the first window size is known
+var messageWidth int
+
+// SetMessageWidth fits the result boxes inside the container on a terminal
+// termWidth columns wide
+func SetMessageWidth(termWidth int) {
+	frame := ContainerStyle.GetHorizontalFrameSize() + SuccessStyle.GetHorizontalBorderSize()
+	messageWidth = min(max(termWidth-frame, 20), maxMessageWidth)
+}
+
+// messageStyle applies the current wrap width to a result box style
+func messageStyle(style lipgloss.Style) lipgloss.Style {
+	if messageWidth == 0 {
+		return style
+	}
+	return style.Width(messageWidth)
 }
 
 func RenderSuccessMessage(message string) string {
-	return SuccessStyle.Render("✅ " + message)
+	if accessibleMode {
+		return "Done: " + message
+	}
+	return messageStyle(SuccessStyle).Render("✅ " + message)
 }
 
 func RenderErrorMessage(message string) string {
-	return ErrorStyle.Render("❌ " + message)
+	if accessibleMode {
+		return "Error: " + message
+	}
+	return messageStyle(ErrorStyle).Render("❌ " + message)
 }
 
 func RenderHelpText(text string) string {
 	return HelpStyle.Render(text)
 }
+
+func RenderProgressBar(done, total int) string {
+	const width = 30
+	filled := width
+	percent := 100.0
+	if total > 0 {
+		filled = done * width / total
+		percent = float64(done) / float64(total) * 100
+	}
+
+	bar := ProgressFilledStyle.Render(strings.Repeat("█", filled)) +
+		ProgressEmptyStyle.Render(strings.Repeat("░", width-filled))
+	return fmt.Sprintf("%s %3.0f%% (%d/%d)", bar, percent, done, total)
+}
+
+func RenderAuditFailure(text string) string {
+	return AuditFailureStyle.Render(text)
+}
+
+func RenderRowStatus(text string, status RowStatus) string {
+	switch status {
+	case RowUpdate:
+		return RowUpdateStyle.Render(text)
+	case RowUnchanged:
+		return RowUnchangedStyle.Render(text)
+	case RowInvalid:
+		return RowInvalidStyle.Render(text)
+	default:
+		return RowNewStyle.Render(text)
+	}
+}
+
+// RenderWithStatus places a status next to a header, vertically centred
+func RenderWithStatus(header, status string) string {
+	if status == "" {
+		return header
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Center, header, status)
+}
+
+// RenderConnStatus shows whether the database connection is up
+func RenderConnStatus(reconnecting, lost bool) string {
+	if offlineMode {
+		return ""
+	}
+	if accessibleMode {
+		switch {
+		case reconnecting:
+			return " (reconnecting)"
+		case lost:
+			return " (disconnected)"
+		default:
+			return " (connected)"
+		}
+	}
+	switch {
+	case reconnecting:
+		return RowUpdateStyle.Render("◌ reconnecting…")
+	case lost:
+		return RowInvalidStyle.Render("✕ disconnected")
+	default:
+		return RowNewStyle.Render("● connected")
+	}
+}
+
+func RenderQueryTable(columns []string, rows [][]string) string {
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = lipgloss.Width(col)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	renderRow := func(cells []string, style lipgloss.Style) string {
+		rendered := make([]string, len(cells))
+		for i, cell := range cells {
+			rendered[i] = style.Width(widths[i]).Render(cell)
+		}
+		return strings.Join(rendered, "  ")
+	}
+
+	lines := []string{renderRow(columns, QueryHeaderStyle)}
+	for _, row := range rows {
+		lines = append(lines, renderRow(row, QueryCellStyle))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// subcommand is a headless mode run as `fitrkr <name> [flags] [args]`
+// instead of the TUI
+type subcommand struct {
+	name    string
+	usage   string
+	summary string
+	run     func(db *sql.DB, fs *flag.FlagSet) error
+	// flags declares the subcommand's own flags, if it has any
+	flags func(fs *flag.FlagSet)
+}
+
+// subcommands are listed in this order by usage
+var subcommands = []subcommand{
+	{name: "upload", usage: "upload <type> <file>", summary: "upload a data file as a table, e.g. upload exercises exercises.csv", run: runUploadCommand},
+	{name: "migrate", usage: "migrate", summary: "apply schema migrations", run: runMigrateCommand},
+	{name: "export", usage: "export <table> <file>", summary: "write a table to a CSV file that uploads back: the names, or every exercise column", run: runExportCommand},
+	{name: "count", usage: "count [--output json] [table]", summary: "print each table's row count, or just the number for one table", run: runCountCommand, flags: countFlags},
+}
+
+// lookupSubcommand finds the subcommand named by the first argument left
+// after the global flags. It returns nil without arguments, which runs the TUI.
+func lookupSubcommand(args []string) (*subcommand, *flag.FlagSet, error) {
+	if len(args) == 0 {
+		return nil, nil, nil
+	}
+	for i := range subcommands {
+		cmd := &subcommands[i]
+		if cmd.name != args[0] {
+			continue
+		}
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage: %s %s\n\n%s\n", os.Args[0], cmd.usage, cmd.summary)
+			fs.PrintDefaults()
+		}
+		if cmd.flags != nil {
+			cmd.flags(fs)
+		}
+		if err := fs.Parse(args[1:]); err != nil {
+			return nil, nil, err
+		}
+		return cmd, fs, nil
+	}
+	return nil, nil, fmt.Errorf("unknown command %q; expected one of %s", args[0], strings.Join(subcommandNames(), ", "))
+}
+
+func subcommandNames() []string {
+	names := make([]string, len(subcommands))
+	for i, cmd := range subcommands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// usage prints the global flags and the subcommands
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command]\n\nWithout a command, runs the interactive menu.\n\nCommands:\n", os.Args[0])
+	for _, cmd := range subcommands {
+		fmt.Fprintf(out, "  %-24s %s\n", cmd.usage, cmd.summary)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// wantArgs checks a subcommand got exactly the positional arguments its
+// usage names
+func wantArgs(fs *flag.FlagSet, n int) error {
+	if fs.NArg() != n {
+		fs.Usage()
+		return fmt.Errorf("%s takes %d arguments, got %d", fs.Name(), n, fs.NArg())
+	}
+	return nil
+}
+
+// commandUploadType finds the upload type a subcommand names by table or
+// label, accepting plurals such as exercises, muscle_groups or
+// exercise_categories
+func commandUploadType(name string) (UploadType, error) {
+	candidates := []string{name, strings.TrimSuffix(name, "s")}
+	if stem, ok := strings.CutSuffix(name, "ies"); ok {
+		candidates = append(candidates, stem+"y")
+	}
+	for _, candidate := range candidates {
+		if i := uploadTypeIndex(candidate); i >= 0 {
+			return uploadTypes[i], nil
+		}
+	}
+	tables := make([]string, len(uploadTypes))
+	for i, t := range uploadTypes {
+		tables[i] = t.Table
+	}
+	return UploadType{}, fmt.Errorf("unknown table %q; expected one of %s", name, strings.Join(tables, ", "))
+}
+
+func runUploadCommand(db *sql.DB, fs *flag.FlagSet) error {
+	if err := wantArgs(fs, 2); err != nil {
+		return err
+	}
+	uploadType, err := commandUploadType(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return uploadHeadless(db, uploadType, fs.Arg(1))
+}
+
+// uploadHeadless uploads path without the menu, printing the result and
+// posting it to NOTIFY_WEBHOOK. It fails when the upload did; a webhook that
+// can't be reached is only logged.
+func uploadHeadless(db *sql.DB, uploadType UploadType, path string) error {
+	result := uploadFile(db, uploadType, path)
+	fmt.Println(result)
+	if err := postSummary(notifyWebhook, result); err != nil {
+		logger.Warn("could not post the upload result", "url", notifyWebhook, "err", err)
+	}
+	if !result.Success {
+		return errors.New(result.Error)
+	}
+	return nil
+}
+
+func runMigrateCommand(db *sql.DB, fs *flag.FlagSet) error {
+	if err := wantArgs(fs, 0); err != nil {
+		return err
+	}
+	return runMigrations(db)
+}
+
+// runMigrations applies pending migrations under the migration lock and
+// prints what was applied. With PRIMARY_KEYS=uuid it then converts the schema
+// to UUID keys.
+func runMigrations(db *sql.DB) error {
+	var report MigrationReport
+	err := withMigrationLock(db, func() (err error) {
+		report, err = Migrate(db)
+		if err != nil || primaryKeys != primaryKeysUUID {
+			return err
+		}
+		if err := ConvertToUUIDKeys(db); err != nil {
+			return fmt.Errorf("convert to UUID keys: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, errMigrationLock) {
+		return fmt.Errorf("nothing was migrated: %w", err)
+	}
+	fmt.Println(report)
+	if err != nil {
+		return fmt.Errorf("migration failed and was rolled back, earlier migrations stay applied: %w", err)
+	}
+	fmt.Println("Migrations applied")
+	return nil
+}
+
+func runExportCommand(db *sql.DB, fs *flag.FlagSet) error {
+	if err := wantArgs(fs, 2); err != nil {
+		return err
+	}
+	uploadType, err := commandUploadType(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if uploadType.Table == "muscle_synonyms" {
+		return errors.New("muscle_synonyms has no name column to export")
+	}
+	if uploadType.Table == "exercise" {
+		return exportExercises(db, fs.Arg(1))
+	}
+	rows, err := ListReferenceRows(db, uploadType.Table, false)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(rows))
+	for i, row := range rows {
+		names[i] = row.name
+	}
+	if err := writeNamesCSV(fs.Arg(1), names); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d %s names to %s\n", len(names), uploadType.Table, fs.Arg(1))
+	return nil
+}
+
+// exportExercises writes every exercise column to path, in the layout
+// `upload exercises` reads back
+func exportExercises(db *sql.DB, path string) error {
+	rows, err := ExportExercises(db)
+	if err != nil {
+		return err
+	}
+	text, err := encodeExercisesCSV(rows)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d exercises to %s\n", len(rows), path)
+	return nil
+}
+
+// countOutput is the count subcommand's --output format, text or json
+var countOutput string
+
+func countFlags(fs *flag.FlagSet) {
+	fs.StringVar(&countOutput, "output", "text", "print counts as text, one table per line, or as a json object")
+}
+
+// runCountCommand prints live row counts for deploy checks. With a table it
+// prints only that table's number, for $(...) capture; a table that doesn't
+// exist fails rather than printing n/a.
+func runCountCommand(db *sql.DB, fs *flag.FlagSet) error {
+	if countOutput != "text" && countOutput != "json" {
+		return fmt.Errorf("--output must be text or json, got %q", countOutput)
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("count takes at most 1 argument, got %d", fs.NArg())
+	}
+
+	tables := countedTables()
+	if fs.NArg() == 1 {
+		uploadType, err := commandUploadType(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		tables = []string{uploadType.Table}
+	}
+	counts, err := GetAllCounts(db, tables)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 1 {
+		if counts[0] == countNA {
+			return fmt.Errorf("table %s does not exist", tables[0])
+		}
+		fmt.Println(counts[0])
+		return nil
+	}
+	if countOutput == "json" {
+		// Missing tables are null, so a check can tell them from empty ones
+		out := make(map[string]*int, len(tables))
+		for i, table := range tables {
+			if counts[i] != countNA {
+				out[table] = &counts[i]
+			} else {
+				out[table] = nil
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	for i, table := range tables {
+		if counts[i] == countNA {
+			fmt.Printf("%s n/a\n", table)
+			continue
+		}
+		fmt.Printf("%s %d\n", table, counts[i])
+	}
+	return nil
+}
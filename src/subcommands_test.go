@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommandUploadType(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"exercise", "exercise"},
+		{"exercises", "exercise"},
+		{"muscle_groups", "muscle_group"},
+		{"Training_Types", "training_type"},
+		{"exercise_category", "exercise_category"},
+		{"exercise_categories", "exercise_category"},
+		{"equipment", "equipment"},
+		{"muscle_synonyms", "muscle_synonyms"},
+		{"Upload Equipment", "equipment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := commandUploadType(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Table != tt.want {
+				t.Errorf("commandUploadType(%q) = %s, want %s", tt.name, got.Table, tt.want)
+			}
+		})
+	}
+
+	for _, name := range []string{"", "categories", "exercisess"} {
+		if got, err := commandUploadType(name); err == nil {
+			t.Errorf("commandUploadType(%q) = %s, want an error", name, got.Table)
+		}
+	}
+}
+
+func TestExportCommandNames(t *testing.T) {
+	db, _ := openFakeDB(t, func(query string, args []driver.Value) fakeResult {
+		return fakeResult{
+			columns: []string{"id", "name", "deleted", "source_file", "imported_at"},
+			rows:    [][]driver.Value{{"1", "Barbell", false, "", nil}, {"2", "Bench, flat", false, "", nil}},
+		}
+	})
+	path := filepath.Join(t.TempDir(), "out.csv")
+	runCommand(t, db, "export", "equipment", path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "name\nBarbell\n\"Bench, flat\"\n"; string(data) != want {
+		t.Errorf("wrote %q, want %q", data, want)
+	}
+}
+
+func TestExportCommandExercisesRoundTrip(t *testing.T) {
+	csvHeader = headerAuto
+	t.Cleanup(func() { csvHeader = headerAuto })
+
+	sep := exportListSep
+	db, _ := openFakeDB(t, func(query string, args []driver.Value) fakeResult {
+		if strings.Contains(query, "deleted_at") {
+			return fakeResult{err: errors.New(`column "deleted_at" does not exist`)}
+		}
+		return fakeResult{
+			columns: []string{"name", "description", "category", "equipment", "types", "muscles", "tags", "parent", "scheme"},
+			rows: [][]driver.Value{
+				{"Bench Press", "Flat, barbell", "Chest", "Barbell" + sep + "Bench", "Strength", "Chest" + sep + "Triceps", "push", "", "3x8-12"},
+				{"Incline Press", "", "Chest", "Bar; EZ", "Strength", "Upper Chest", "", "Bench Press", ""},
+			},
+		}
+	})
+	path := filepath.Join(t.TempDir(), "exercises.csv")
+	runCommand(t, db, "export", "exercises", path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header, _, _ := strings.Cut(string(data), "\n"); header != "Name,Description,Category,Equipment,Types,Muscles,Tags,VariationOf,DefaultScheme" {
+		t.Errorf("header = %q", header)
+	}
+
+	rows, _, problems, err := parseExercisesCSV(strings.NewReader(string(data)))
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("re-import failed: %v %v", err, problems)
+	}
+	want := []ExerciseUploadRow{
+		{Name: "Bench Press", Description: "Flat, barbell", Category: "Chest", Equipment: []string{"Barbell", "Bench"},
+			Types: []string{"Strength"}, Muscles: []string{"Chest", "Triceps"}, Tags: []string{"push"}, DefaultScheme: "3x8-12", Line: 2, Row: 1},
+		{Name: "Incline Press", Category: "Chest", Equipment: []string{"Bar; EZ"},
+			Types: []string{"Strength"}, Muscles: []string{"Upper Chest"}, VariationOf: "Bench Press", Line: 3, Row: 2},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("re-imported\n%+v\nwant\n%+v", rows, want)
+	}
+}
+
+// runCommand runs a subcommand line against db, failing the test on error
+func runCommand(t *testing.T, db *sql.DB, args ...string) {
+	t.Helper()
+	cmd, fs, err := lookupSubcommand(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.run(db, fs); err != nil {
+		t.Fatal(err)
+	}
+}
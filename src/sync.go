@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SyncResult is what reconciling a table with a file did, or would do in a
+// dry run
+type SyncResult struct {
+	Added    int          // names inserted or restored; for exercises, rows inserted or updated
+	Unlinked int          // exercise junction links dropped because the file no longer lists them
+	Removed  DeleteImpact // rows missing from the file, when deletion is allowed
+}
+
+func (r SyncResult) String() string {
+	lines := []string{fmt.Sprintf("%d added", r.Added)}
+	if r.Unlinked > 0 {
+		lines = append(lines, fmt.Sprintf("%d junction links removed", r.Unlinked))
+	}
+	if r.Removed.Table != "" {
+		lines = append(lines, r.Removed.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SyncNames makes the names in table match names in one transaction: new
+// names are inserted and, with allowDelete, names missing from the list are
+// deleted (soft-deleted in reference tables)
+func SyncNames(db *sql.DB, table string, names []string, allowDelete bool) (added, deleted int, err error) {
+	result, err := syncNames(db, table, names, "", allowDelete, false)
+	return result.Added, result.Removed.Rows, err
+}
+
+// syncNames is SyncNames recording source as provenance, rolled back when dryRun
+func syncNames(db *sql.DB, table string, names []string, source string, allowDelete, dryRun bool) (result SyncResult, err error) {
+	unique := dedupeNames(names)
+	err = runSync(db, table, source, dryRun, func(tx *sql.Tx) error {
+		added, _, failed, err := insertNameBatches(tx, table, unique, source, nil)
+		if err == nil && len(failed) > 0 {
+			// A sync leaves the table matching the file or not at all
+			err = fmt.Errorf("could not insert %d names, first %s", len(failed), failed[0])
+		}
+		result.Added = added
+		if err != nil || !allowDelete {
+			return err
+		}
+		result.Removed, err = deleteMissingNames(tx, table, unique)
+		return err
+	})
+	return result, err
+}
+
+// SyncExercises upserts rows and drops the junction links of each listed
+// exercise that its row no longer names. With allowDelete, exercises missing
+// from rows are deleted too. Everything happens in one transaction, rolled back
+// when dryRun is set.
+func SyncExercises(db *sql.DB, rows []ExerciseUploadRow, source string, allowDelete, dryRun bool) (result SyncResult, err error) {
+	err = runSync(db, "exercise", source, dryRun, func(tx *sql.Tx) error {
+		imported, err := insertExercisesTx(tx, rows, source, nil)
+		if err != nil {
+			return err
+		}
+		result.Added = len(rows) - imported.Unchanged
+		if result.Unlinked, err = pruneExerciseLinks(tx, rows); err != nil || !allowDelete {
+			return err
+		}
+		names := make([]string, len(rows))
+		for i, row := range rows {
+			names[i] = row.Name
+		}
+		result.Removed, err = deleteMissingNames(tx, "exercise", dedupeNames(names))
+		return err
+	})
+	return result, err
+}
+
+// runSync runs sync of table in an upload transaction, rolling it back for a
+// dry run
+func runSync(db *sql.DB, table, source string, dryRun bool, sync func(tx *sql.Tx) error) (err error) {
+	tx, err := beginUploadTx(db)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		muteRowLog(tx)
+	}
+	defer func() {
+		if err != nil || dryRun {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		logTxEnd(tx, table, source, 0, err)
+	}()
+	return sync(tx)
+}
+
+// deleteMissingNames deletes the rows of table whose name is not in names.
+// Exercises have their junction links removed first, so the delete doesn't
+// depend on how those foreign keys were declared.
+func deleteMissingNames(tx *sql.Tx, table string, names []string) (DeleteImpact, error) {
+	if len(names) == 0 {
+		return DeleteImpact{Table: table}, errors.New("the file lists no names; refusing to delete every row")
+	}
+	placeholders := make([]string, len(names))
+	args := make([]any, len(names))
+	for i, name := range names {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = name
+	}
+	where := fmt.Sprintf("name NOT IN (%s)", strings.Join(placeholders, ", "))
+	if table != "exercise" {
+		return deleteWhere(tx, table, where, args...)
+	}
+
+	cascades, err := countReferences(tx, table, where, args...)
+	if err != nil {
+		return DeleteImpact{Table: table}, err
+	}
+	for _, link := range exerciseLinks {
+		query := fmt.Sprintf("DELETE FROM %s WHERE exercise_id IN (SELECT id FROM exercise WHERE %s)", link.table, where)
+		logSQL(query, args...)
+		if _, err := tx.Exec(query, args...); err != nil {
+			return DeleteImpact{Table: table}, fmt.Errorf("unlink %s: %w", link.table, err)
+		}
+	}
+	impact, err := deleteWhere(tx, table, where, args...)
+	impact.Cascades = cascades
+	return impact, err
+}
+
+// exerciseLinks are the junction tables an exercise row lists values for, with
+// the lookup InsertExercises uses to turn a value into the referenced id
+var exerciseLinks = []struct {
+	table   string
+	column  string
+	values  func(ExerciseUploadRow) []string
+	resolve func(tx *sql.Tx, name string) (string, bool, error)
+}{
+	{"exercise_equipment", "equipment_id", func(r ExerciseUploadRow) []string { return r.Equipment }, GetOrInsertEquipment},
+	{"exercise_training_types", "training_type_id", func(r ExerciseUploadRow) []string { return r.Types }, GetOrInsertType},
+	{"exercise_muscles", "muscle_group_id", func(r ExerciseUploadRow) []string { return r.Muscles }, GetOrInsertMuscle},
+	{"exercise_tags", "tag_id", func(r ExerciseUploadRow) []string { return r.Tags }, GetOrInsertTag},
+}
+
+// pruneExerciseLinks deletes the junction links of each row's exercise that
+// the row no longer lists, returning how many were removed. Values are
+// resolved the same way as on insert, so muscle synonyms keep their link.
+func pruneExerciseLinks(tx *sql.Tx, rows []ExerciseUploadRow) (int, error) {
+	removed := 0
+	for _, row := range rows {
+		for _, link := range exerciseLinks {
+			args := []any{row.Name}
+			var placeholders []string
+			for _, v := range link.values(row) {
+				v = strings.TrimSpace(v)
+				if v == "" || (link.table == "exercise_equipment" && isNoEquipment(v)) {
+					continue
+				}
+				id, _, err := link.resolve(tx, v)
+				if err != nil {
+					return removed, fmt.Errorf("%s %s: %w", link.column, v, err)
+				}
+				args = append(args, id)
+				placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+			}
+
+			query := fmt.Sprintf("DELETE FROM %s WHERE exercise_id = (SELECT id FROM exercise WHERE name = $1)", link.table)
+			if len(placeholders) > 0 {
+				query += fmt.Sprintf(" AND %s NOT IN (%s)", link.column, strings.Join(placeholders, ", "))
+			}
+			logSQL(query, args...)
+			res, err := tx.Exec(query, args...)
+			if err != nil {
+				return removed, fmt.Errorf("%s: unlink %s: %w", row.ref(), link.table, err)
+			}
+			if n, err := res.RowsAffected(); err == nil {
+				removed += int(n)
+			}
+		}
+	}
+	return removed, nil
+}
+
+// startSync parses the file selected in the file selector and dry-runs a full
+// sync of it, asking for confirmation before anything is written
+func startSync(m model) (tea.Model, tea.Cmd) {
+	filename := m.fileList[m.fileChoice]
+	uploadType := m.selectedUploadType()
+	if filename == "Back" {
+		return m, nil
+	}
+	if uploadType.Custom != nil || uploadType.Upload != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("%s can't be synced; only name tables and exercises can.\nPress enter or q to return to menu.", uploadType.Label)
+		m.isError = true
+		return m, nil
+	}
+
+	m.selectedFile = filepath.Join(dataDir, filename)
+	m.uploadSource = filename
+	data, ext, err := readUploadFile(m.selectedFile, uploadType)
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error reading file: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+
+	m.syncList, m.syncRows = nil, nil
+	if uploadType.Parser == nil {
+		var problems []ValidationError
+		m.syncRows, _, problems, err = ParseExercisesCSVReader(bytes.NewReader(data))
+		if err == nil {
+			// A row left out would have its exercise deleted, so sync
+			// refuses malformed rows instead of skipping them
+			if errs := append(problems, ValidateExerciseRows(m.syncRows)...); len(errs) > 0 {
+				err = fmt.Errorf("validation failed:\n%s", formatValidationErrors(errs, 10))
+			}
+		}
+	} else {
+		var lines []int
+		m.syncList, lines, _, err = uploadType.Parser(ext, data)
+		if err == nil {
+			if errs := ValidateNames(m.syncList, lines); len(errs) > 0 {
+				err = fmt.Errorf("validation failed:\n%s", formatValidationErrors(errs, 10))
+			}
+		}
+	}
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Error parsing file: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+
+	m.syncPlan, err = runSyncFor(m, true, true)
+	if err != nil {
+		m.state = stateResult
+		m.resultMsg = fmt.Sprintf("Sync would fail, nothing was changed: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, nil
+	}
+	m.state = stateConfirmSync
+	return m, nil
+}
+
+// runSyncFor syncs the pending names or exercise rows into the selected table
+func runSyncFor(m model, allowDelete, dryRun bool) (SyncResult, error) {
+	if m.syncRows != nil {
+		return SyncExercises(m.db, m.syncRows, m.uploadSource, allowDelete, dryRun)
+	}
+	return syncNames(m.db, m.selectedUploadType().Table, m.syncList, m.uploadSource, allowDelete, dryRun)
+}
+
+func updateConfirmSync(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	var allowDelete bool
+	switch key.String() {
+	case "y":
+		allowDelete = true
+	case "a":
+		allowDelete = false
+	case "n", "q", "esc":
+		m.syncList, m.syncRows = nil, nil
+		m.state = stateFileSelector
+		return m, nil
+	default:
+		return m, nil
+	}
+
+	parsed := len(m.syncList) + len(m.syncRows)
+	start := time.Now()
+	result, err := runSyncFor(m, allowDelete, false)
+	m.syncList, m.syncRows = nil, nil
+	record := UploadResult{Type: m.selectedUploadType().Label, File: m.uploadSource, Parsed: parsed, Inserted: result.Added, Skipped: parsed - result.Added, Success: err == nil,
+		Duration: time.Since(start)}
+	if err != nil {
+		record.Inserted, record.Skipped, record.Error = 0, 0, err.Error()
+	}
+	recordUpload(record)
+
+	m.state = stateResult
+	if err != nil {
+		m.resultMsg = fmt.Sprintf("Sync failed, nothing was changed: %v\nPress enter or q to return to menu.", err)
+		m.isError = true
+		return m, notifyCompletion(true)
+	}
+	m.resultMsg = fmt.Sprintf("Synced %s with %s:\n%s\nPress enter or q to return to menu.", m.selectedUploadType().Table, m.uploadSource, result)
+	m.isError = false
+	return m, notifyCompletion(false)
+}
+
+func viewConfirmSync(m model) string {
+	table := m.selectedUploadType().Table
+	content := RenderErrorMessage(fmt.Sprintf("Make %s match %s?\n\nDry run:\n%s", table, m.uploadSource, m.syncPlan)) +
+		"\n\n" + RenderHelpText("Sync, deleting missing rows: y • Only add: a • Cancel: n/esc")
+	return ContainerStyle.Render(content)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// muscleSynonymsReady caches whether the muscle_synonyms table exists, so
+// GetOrInsertMuscle only consults it once the migration has been applied.
+// Reconnecting resets it, since the new database may differ.
+var muscleSynonymsReady *bool
+
+// resolveMuscleSynonym returns the canonical muscle id for a synonym, or ""
+// when name isn't a known synonym or its muscle has been deleted
+func resolveMuscleSynonym(tx *sql.Tx, name string) (string, error) {
+	if muscleSynonymsReady == nil {
+		var exists bool
+		query := `SELECT to_regclass('muscle_synonyms') IS NOT NULL`
+		logSQL(query)
+		if err := tx.QueryRow(query).Scan(&exists); err != nil {
+			return "", err
+		}
+		muscleSynonymsReady = &exists
+	}
+	if !*muscleSynonymsReady {
+		return "", nil
+	}
+
+	var id string
+	query := `SELECT s.muscle_group_id FROM muscle_synonyms s
+		JOIN muscle_group g ON g.id = s.muscle_group_id AND g.deleted_at IS NULL
+		WHERE lower(s.synonym) = lower($1)`
+	logSQL(query, name)
+	err := tx.QueryRow(query, name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// MuscleSynonym maps an alternative muscle name to its canonical muscle group
+type MuscleSynonym struct {
+	Muscle  string `json:"muscle"`
+	Synonym string `json:"synonym"`
+}
+
+// ParseMuscleSynonymsCSV reads a two-column muscle,synonym CSV, skipping a
+// header row when present
+func ParseMuscleSynonymsCSV(data []byte) ([]MuscleSynonym, int, error) {
+	records, err := readCSVRecords(csv.NewReader(bytes.NewReader(normalizeInput(data))))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(records) > 0 && isHeader(len(records[0]) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), "muscle")) {
+		records = records[1:]
+	}
+
+	var pairs []MuscleSynonym
+	for i, rec := range records {
+		if len(rec) < 2 || strings.TrimSpace(rec[0]) == "" || strings.TrimSpace(rec[1]) == "" {
+			return nil, len(records), fmt.Errorf("row %d: expected muscle,synonym", i+1)
+		}
+		pairs = append(pairs, MuscleSynonym{Muscle: strings.TrimSpace(rec[0]), Synonym: strings.TrimSpace(rec[1])})
+	}
+	return pairs, len(records), nil
+}
+
+// InsertMuscleSynonyms links each synonym to its canonical muscle group,
+// creating the muscle if needed. A synonym already mapped elsewhere is moved to
+// the new muscle. Returns how many synonyms were newly added.
+func InsertMuscleSynonyms(db *sql.DB, pairs []MuscleSynonym, source string) (inserted int, err error) {
+	tx, err := beginUploadTx(db)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	for _, p := range pairs {
+		var muscleID string
+		query := `INSERT INTO muscle_group (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name, deleted_at=NULL RETURNING id`
+		logSQL(query, p.Muscle)
+		if err := tx.QueryRow(query, p.Muscle).Scan(&muscleID); err != nil {
+			return inserted, fmt.Errorf("muscle %s: %w", p.Muscle, err)
+		}
+
+		n, err := countInserted(tx, insertMuscleSynonymQuery, p.Synonym, muscleID, source)
+		if err != nil {
+			return inserted, fmt.Errorf("synonym %s: %w", p.Synonym, err)
+		}
+		inserted += n
+	}
+	return inserted, nil
+}
+
+const insertMuscleSynonymQuery = `INSERT INTO muscle_synonyms (synonym, muscle_group_id, source_file) VALUES ($1, $2, $3)
+	ON CONFLICT (synonym) DO UPDATE SET muscle_group_id=EXCLUDED.muscle_group_id
+	RETURNING (xmax = 0)`
+
+// uploadMuscleSynonyms is the UploadType.Upload hook for synonym files
+func uploadMuscleSynonyms(db *sql.DB, ext string, data []byte, source string) (int, int, error) {
+	if ext != ".csv" {
+		return 0, 0, fmt.Errorf("muscle synonyms must be a two-column CSV, got %s", ext)
+	}
+	pairs, seen, err := ParseMuscleSynonymsCSV(data)
+	if err != nil {
+		return 0, seen, err
+	}
+	inserted, err := InsertMuscleSynonyms(db, pairs, source)
+	return inserted, seen, err
+}
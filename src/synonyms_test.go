@@ -0,0 +1,192 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// withSynonyms answers like returningID, with muscle_synonyms present and
+// mapping each synonym, ignoring case, to the id of its canonical muscle
+func withSynonyms(t *testing.T, synonyms map[string]string) respondFunc {
+	t.Helper()
+	muscleSynonymsReady = nil
+	t.Cleanup(func() { muscleSynonymsReady = nil })
+
+	upsert := returningID()
+	return func(query string, args []driver.Value) fakeResult {
+		switch {
+		case strings.Contains(query, "to_regclass('muscle_synonyms')"):
+			return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{true}}}
+		case strings.Contains(query, "FROM muscle_synonyms"):
+			for synonym, muscle := range synonyms {
+				if strings.EqualFold(synonym, args[0].(string)) {
+					return fakeResult{columns: []string{"muscle_group_id"}, rows: [][]driver.Value{{"muscle_group:" + muscle}}}
+				}
+			}
+			return fakeResult{}
+		}
+		return upsert(query, args)
+	}
+}
+
+func TestInsertExercisesResolvesMuscleSynonyms(t *testing.T) {
+	db, rec := openFakeDB(t, withSynonyms(t, map[string]string{"Quads": "Quadriceps"}))
+	rows := []ExerciseUploadRow{
+		{Name: "Squat", Category: "Legs", Muscles: []string{"Quadriceps"}},
+		{Name: "Leg extension", Category: "Legs", Muscles: []string{"quads"}},
+	}
+
+	result, err := InsertExercises(db, rows, "test.csv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := statementsMatching(rec, "INSERT INTO exercise_muscles")
+	if len(links) != 2 {
+		t.Fatalf("got %d exercise_muscles inserts, want 2", len(links))
+	}
+	for i, link := range links {
+		if got := link.Args[1]; got != "muscle_group:Quadriceps" {
+			t.Errorf("%s linked to muscle %v, want the canonical Quadriceps", rows[i].Name, got)
+		}
+	}
+	for _, s := range statementsMatching(rec, "INSERT INTO muscle_group") {
+		if strings.EqualFold(s.Args[0].(string), "quads") {
+			t.Errorf("the synonym was created as a muscle: %v", s.Args)
+		}
+	}
+	if want := []string{"Quadriceps"}; !slices.Equal(result.Created.Muscles, want) {
+		t.Errorf("created muscles = %q, want %q", result.Created.Muscles, want)
+	}
+}
+
+func TestGetOrInsertMuscleWithoutSynonymsTable(t *testing.T) {
+	muscleSynonymsReady = nil
+	t.Cleanup(func() { muscleSynonymsReady = nil })
+	db, rec := openFakeDB(t, returningID())
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	for range 2 {
+		if id, _, err := GetOrInsertMuscle(tx, "Quads"); err != nil || id != "muscle_group:Quads" {
+			t.Fatalf("GetOrInsertMuscle = %q, %v", id, err)
+		}
+	}
+	if lookups := statementsMatching(rec, "FROM muscle_synonyms"); len(lookups) != 0 {
+		t.Errorf("looked up synonyms in a missing table: %v", lookups)
+	}
+	if checks := statementsMatching(rec, "to_regclass"); len(checks) != 1 {
+		t.Errorf("checked for the synonyms table %d times, want once", len(checks))
+	}
+}
+
+func TestMuscleSynonymsSkipDeletedMuscles(t *testing.T) {
+	db, rec := openFakeDB(t, withSynonyms(t, map[string]string{"Quads": "Quadriceps"}))
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := resolveMuscleSynonym(tx, "quads"); err != nil {
+		t.Fatal(err)
+	}
+	lookups := statementsMatching(rec, "FROM muscle_synonyms")
+	if len(lookups) != 1 || !strings.Contains(lookups[0].Query, "g.deleted_at IS NULL") {
+		t.Errorf("synonym lookup doesn't skip deleted muscles: %v", lookups)
+	}
+}
+
+func TestReconnectResetsMuscleSynonymsCache(t *testing.T) {
+	ready := true
+	muscleSynonymsReady = &ready
+	t.Cleanup(func() { muscleSynonymsReady = nil; currentDB.Store(nil) })
+	first, _ := openFakeDB(t, nil)
+	second, _ := openFakeDB(t, nil)
+
+	initialModel(first, "").Update(reconnectMsg{db: second})
+	if muscleSynonymsReady != nil {
+		t.Error("the synonyms table check survived a reconnect")
+	}
+}
+
+func TestInsertMuscleSynonymsCountsNewSynonyms(t *testing.T) {
+	existing := map[string]bool{"Quads": true}
+	upsert := returningID()
+	db, rec := openFakeDB(t, func(query string, args []driver.Value) fakeResult {
+		if strings.Contains(query, "INSERT INTO muscle_synonyms") {
+			// RETURNING (xmax = 0) is false for a synonym that was moved
+			return fakeResult{columns: []string{"inserted"}, rows: [][]driver.Value{{!existing[args[0].(string)]}}}
+		}
+		return upsert(query, args)
+	})
+	pairs := []MuscleSynonym{
+		{Muscle: "Quadriceps", Synonym: "Quads"},
+		{Muscle: "Quadriceps", Synonym: "Thighs"},
+		{Muscle: "Hamstrings", Synonym: "Hams"},
+	}
+
+	inserted, err := InsertMuscleSynonyms(db, pairs, "synonyms.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted != 2 {
+		t.Errorf("inserted = %d, want 2 (Quads already existed)", inserted)
+	}
+	for _, s := range statementsMatching(rec, "INSERT INTO muscle_synonyms") {
+		if !strings.HasPrefix(s.Args[1].(string), "muscle_group:") {
+			t.Errorf("synonym %v not linked to its muscle's id", s.Args)
+		}
+	}
+}
+
+func TestInsertMuscleSynonymsRollsBackOnError(t *testing.T) {
+	upsert := returningID()
+	db, rec := openFakeDB(t, func(query string, args []driver.Value) fakeResult {
+		if strings.Contains(query, "INSERT INTO muscle_synonyms") && args[0] == "Hams" {
+			return fakeResult{err: errors.New("boom")}
+		}
+		if strings.Contains(query, "INSERT INTO muscle_synonyms") {
+			return fakeResult{columns: []string{"inserted"}, rows: [][]driver.Value{{true}}}
+		}
+		return upsert(query, args)
+	})
+
+	_, err := InsertMuscleSynonyms(db, []MuscleSynonym{{"Quadriceps", "Quads"}, {"Hamstrings", "Hams"}}, "")
+	if err == nil || !strings.Contains(err.Error(), "synonym Hams") {
+		t.Fatalf("err = %v, want a failure naming synonym Hams", err)
+	}
+	statements := rec.Statements()
+	if last := statements[len(statements)-1].Query; last != "ROLLBACK" {
+		t.Errorf("transaction ended with %s, want ROLLBACK", last)
+	}
+}
+
+func TestParseMuscleSynonymsCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []MuscleSynonym
+		wantErr bool
+	}{
+		{"with header", "Muscle,Synonym\nQuadriceps,Quads\n", []MuscleSynonym{{"Quadriceps", "Quads"}}, false},
+		{"without header", "Quadriceps, Quads \nHamstrings,Hams\n", []MuscleSynonym{{"Quadriceps", "Quads"}, {"Hamstrings", "Hams"}}, false},
+		{"missing synonym", "Quadriceps,\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := ParseMuscleSynonymsCSV([]byte(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// columnTransforms rewrite exercise columns as they are parsed, e.g.
+// COLUMN_TRANSFORMS="Category=lower;Muscles=title;Name=trim-prefix:EX-|title"
+var columnTransforms []columnTransform
+
+// transformSampleLimit caps how many before/after pairs the preview shows
+const transformSampleLimit = 5
+
+// columnTransform is the chain of steps applied to one exercise column; list
+// columns are transformed per item, after splitting
+type columnTransform struct {
+	column int // index into exerciseHeader
+	spec   string
+	steps  []func(string) string
+}
+
+// transformSample is one value a transform changed
+type transformSample struct {
+	Column string
+	Before string
+	After  string
+}
+
+func (s transformSample) String() string {
+	return fmt.Sprintf("%s: %q → %q", s.Column, s.Before, s.After)
+}
+
+// ParseColumnTransforms parses COLUMN_TRANSFORMS entries of column=steps
+// separated by semicolons. Steps are lower, upper, title or trim-prefix:X,
+// chained with |, and columns are exercise header names in any case.
+func ParseColumnTransforms(s string) ([]columnTransform, error) {
+	var transforms []columnTransform
+	for _, entry := range SplitAndTrim(s, ";") {
+		name, spec, ok := strings.Cut(entry, "=")
+		name, spec = strings.TrimSpace(name), strings.TrimSpace(spec)
+		if !ok || name == "" || spec == "" {
+			return nil, fmt.Errorf("transform %q is not column=transform", entry)
+		}
+		column := -1
+		for i, col := range exerciseHeader {
+			if strings.EqualFold(strings.TrimSuffix(col, "?"), name) {
+				column = i
+			}
+		}
+		if column < 0 {
+			return nil, fmt.Errorf("transform %q names an unknown column; expected one of %s", entry, strings.Join(exerciseHeader, ", "))
+		}
+
+		t := columnTransform{column: column, spec: spec}
+		for _, step := range strings.Split(spec, "|") {
+			fn, err := parseTransformStep(strings.TrimSpace(step))
+			if err != nil {
+				return nil, fmt.Errorf("transform %q: %w", entry, err)
+			}
+			t.steps = append(t.steps, fn)
+		}
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
+
+func parseTransformStep(step string) (func(string) string, error) {
+	if prefix, ok := strings.CutPrefix(step, "trim-prefix:"); ok {
+		return func(s string) string { return strings.TrimSpace(strings.TrimPrefix(s, prefix)) }, nil
+	}
+	switch step {
+	case "lower":
+		return strings.ToLower, nil
+	case "upper":
+		return strings.ToUpper, nil
+	case "title":
+		return titleCase, nil
+	}
+	return nil, fmt.Errorf("unknown transform %q; expected lower, upper, title or trim-prefix:X", step)
+}
+
+// titleCase upper-cases the first letter of every word and lower-cases the
+// rest, treating spaces, hyphens, slashes and brackets as word breaks
+func titleCase(s string) string {
+	var b strings.Builder
+	start := true
+	for _, r := range s {
+		if start {
+			b.WriteRune(unicode.ToUpper(r))
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+		start = unicode.IsSpace(r) || strings.ContainsRune("-/(", r)
+	}
+	return b.String()
+}
+
+func (t columnTransform) apply(s string) string {
+	for _, step := range t.steps {
+		s = step(s)
+	}
+	return s
+}
+
+// exerciseField returns the column of row as either a single value or a list
+func exerciseField(row *ExerciseUploadRow, column int) (*string, []string) {
+	switch column {
+	case 0:
+		return &row.Name, nil
+	case 1:
+		return &row.Description, nil
+	case 2:
+		return &row.Category, nil
+	case 3:
+		return nil, row.Equipment
+	case 4:
+		return nil, row.Types
+	case 5:
+		return nil, row.Muscles
+	case 6:
+		return nil, row.Tags
+	case 7:
+		return &row.VariationOf, nil
+	default:
+		return &row.DefaultScheme, nil
+	}
+}
+
+// transformExerciseRows applies columnTransforms to rows in place and returns
+// a sample of the distinct values they changed
+func transformExerciseRows(rows []ExerciseUploadRow) []transformSample {
+	var samples []transformSample
+	seen := make(map[transformSample]bool)
+	change := func(t columnTransform, value *string) {
+		before := *value
+		*value = t.apply(before)
+		sample := transformSample{strings.TrimSuffix(exerciseHeader[t.column], "?"), before, *value}
+		if before != *value && !seen[sample] && len(samples) < transformSampleLimit {
+			seen[sample] = true
+			samples = append(samples, sample)
+		}
+	}
+
+	for i := range rows {
+		for _, t := range columnTransforms {
+			single, list := exerciseField(&rows[i], t.column)
+			if single != nil {
+				change(t, single)
+				continue
+			}
+			for j := range list {
+				change(t, &list[j])
+			}
+		}
+	}
+	return samples
+}
@@ -1,53 +1,249 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
 )
 
+// headerMode controls whether the first CSV row is treated as a header
+type headerMode int
+
+const (
+	headerAuto    headerMode = iota // each parser's own heuristic
+	headerPresent                   // --has-header
+	headerAbsent                    // --no-header
+)
+
+func (h headerMode) String() string {
+	switch h {
+	case headerPresent:
+		return "has header"
+	case headerAbsent:
+		return "no header"
+	default:
+		return "auto"
+	}
+}
+
+// csvHeader overrides the CSV parsers' header detection
+var csvHeader headerMode
+
+// isHeader decides whether a CSV's first row is a header, using guess from
+// the parser's heuristic unless csvHeader overrides it
+func isHeader(guess bool) bool {
+	switch csvHeader {
+	case headerPresent:
+		return true
+	case headerAbsent:
+		return false
+	default:
+		return guess
+	}
+}
+
+// lineRange selects data rows by position, 1-based and inclusive, counted after
+// the header, for every upload type. A last of 0 runs to the end of the file.
+type lineRange struct {
+	first, last int
+}
+
+// uploadLines limits uploads to a slice of the file (--lines); the zero value
+// uploads every row
+var uploadLines lineRange
+
+// ParseLineRange parses "first-last" or "first-" into a lineRange
+func ParseLineRange(s string) (lineRange, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	first, err := strconv.Atoi(strings.TrimSpace(from))
+	if !ok || err != nil || first < 1 {
+		return lineRange{}, fmt.Errorf("expected first-last or first-, got %q", s)
+	}
+	r := lineRange{first: first}
+	if to = strings.TrimSpace(to); to != "" {
+		if r.last, err = strconv.Atoi(to); err != nil || r.last < first {
+			return lineRange{}, fmt.Errorf("expected first-last with last >= first, got %q", s)
+		}
+	}
+	return r, nil
+}
+
+func (r lineRange) String() string {
+	if r.last == 0 {
+		return fmt.Sprintf("%d-", r.first)
+	}
+	return fmt.Sprintf("%d-%d", r.first, r.last)
+}
+
+// span returns the slice [first, last) of n data rows that r selects
+func (r lineRange) span(n int) (first, last int) {
+	first, last = min(r.first-1, n), n
+	if r.last > 0 {
+		last = min(r.last, n)
+	}
+	return first, max(first, last)
+}
+
+// lineRangeNote describes the rows uploadLines selected out of total, e.g.
+// "Uploaded rows 1–100 of 5000."
+func lineRangeNote(first, last, total int) string {
+	if first >= last {
+		return fmt.Sprintf("No rows in %s; the file has %d.", uploadLines, total)
+	}
+	return fmt.Sprintf("Uploaded rows %d–%d of %d.", first+1, last, total)
+}
+
+// applyLineRange keeps the parsed items inside uploadLines and describes the
+// slice with lineRangeNote. The note is empty when no range is set.
+func applyLineRange[T any](items []T) ([]T, string) {
+	if uploadLines.first == 0 {
+		return items, ""
+	}
+	first, last := uploadLines.span(len(items))
+	return items[first:last], lineRangeNote(first, last, len(items))
+}
+
+// applyExerciseLineRange is applyLineRange for exercise rows. The range counts
+// the seen data rows after the header, malformed ones included, so it selects
+// the same rows as for any other upload type; problems outside it are dropped.
+func applyExerciseLineRange(rows []ExerciseUploadRow, problems []ValidationError, seen int) ([]ExerciseUploadRow, []ValidationError, string) {
+	if uploadLines.first == 0 {
+		return rows, problems, ""
+	}
+	first, last := uploadLines.span(seen)
+	inRange := func(row int) bool { return row > first && row <= last }
+	rows = slices.DeleteFunc(slices.Clone(rows), func(r ExerciseUploadRow) bool { return !inRange(r.Row) })
+	problems = slices.DeleteFunc(problems, func(p ValidationError) bool { return !inRange(p.Row) })
+	return rows, problems, lineRangeNote(first, last, seen)
+}
+
 // ParseCSV parses a CSV file and returns a slice of names (first column, skipping header if present)
-func ParseCSV(path string) ([]string, error) {
+// along with the number of data rows seen in the file
+func ParseCSV(path string) ([]string, int, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer f.Close()
+	return ParseCSVReader(f)
+}
+
+// ParseCSVReader is ParseCSV for any reader
+func ParseCSVReader(in io.Reader) ([]string, int, error) {
+	names, _, seen, err := ParseCSVReaderLines(in)
+	return names, seen, err
+}
 
-	r := csv.NewReader(f)
-	records, err := r.ReadAll()
+// ParseCSVReaderLines is ParseCSVReader that also returns the file line each
+// name's row starts on
+func ParseCSVReaderLines(in io.Reader) (names []string, lines []int, seen int, err error) {
+	records, recordLines, err := readCSVRecordLines(csv.NewReader(in))
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
-	var names []string
 	for i, rec := range records {
-		if len(rec) == 0 {
+		// Skip header if present
+		if i == 0 && isHeader(len(rec) > 0 && (rec[0] == "name" || rec[0] == "Name")) {
 			continue
 		}
-		// Skip header if present
-		if i == 0 && (rec[0] == "name" || rec[0] == "Name") {
+		seen++
+		if len(rec) == 0 {
 			continue
 		}
 		names = append(names, rec[0])
+		lines = append(lines, recordLines[i])
+	}
+	return names, lines, seen, nil
+}
+
+// nameColumns is an ordered list of CSV header names to take each row's name from;
+// the first non-empty one wins (NAME_COLUMNS=name_en,name_es). Empty means first column.
+var nameColumns []string
+
+// ParseCSVColumns reads a CSV with a header row and takes each row's name from the
+// first non-empty candidate column. Rows where every candidate is empty yield an
+// empty name so validation can report them. lines holds the file line each
+// name's row starts on.
+func ParseCSVColumns(in io.Reader, candidates []string) (names []string, lines []int, seen int, err error) {
+	records, recordLines, err := readCSVRecordLines(csv.NewReader(in))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if len(records) < 1 {
+		return nil, nil, 0, errors.New("no records found")
+	}
+	if csvHeader == headerAbsent {
+		return nil, nil, 0, errors.New("name columns are looked up by header, which --no-header disables")
+	}
+
+	var indexes []int
+	for _, candidate := range candidates {
+		for i, col := range records[0] {
+			if strings.EqualFold(strings.TrimSpace(col), candidate) {
+				indexes = append(indexes, i)
+				break
+			}
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, nil, 0, fmt.Errorf("none of the name columns %v found in header", candidates)
+	}
+
+	for r, rec := range records[1:] {
+		name := ""
+		for _, i := range indexes {
+			if i < len(rec) && strings.TrimSpace(rec[i]) != "" {
+				name = rec[i]
+				break
+			}
+		}
+		names = append(names, name)
+		lines = append(lines, recordLines[r+1])
 	}
-	return names, nil
+	return names, lines, len(records) - 1, nil
 }
 
 // ParseJSON expects a JSON array of objects with a "name" field
-func ParseJSON(path string) ([]string, error) {
-	data, err := os.ReadFile(path)
+func ParseJSON(path string) ([]string, int, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	defer f.Close()
+	return ParseJSONReader(f)
+}
+
+// ParseJSONReader is ParseJSON for any reader
+func ParseJSONReader(in io.Reader) ([]string, int, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, 0, err
+	}
+	data = normalizeInput(data)
+	if validateSchema {
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, 0, describeJSONError(data, err)
+		}
+		if err := checkRecordsSchema(doc); err != nil {
+			return nil, 0, err
+		}
 	}
 	var arr []map[string]any
 	if err := json.Unmarshal(data, &arr); err != nil {
-		return nil, err
+		return nil, 0, describeJSONError(data, err)
 	}
 	var names []string
 	for _, obj := range arr {
@@ -55,18 +251,38 @@ func ParseJSON(path string) ([]string, error) {
 			names = append(names, name)
 		}
 	}
-	return names, nil
+	return names, len(arr), nil
 }
 
 // ParseYAML expects a YAML list of objects with a "name" field
-func ParseYAML(path string) ([]string, error) {
-	data, err := os.ReadFile(path)
+func ParseYAML(path string) ([]string, int, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	defer f.Close()
+	return ParseYAMLReader(f)
+}
+
+// ParseYAMLReader is ParseYAML for any reader
+func ParseYAMLReader(in io.Reader) ([]string, int, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, 0, err
+	}
+	data = normalizeInput(data)
+	if validateSchema {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, 0, describeYAMLError(data, err)
+		}
+		if err := checkRecordsSchema(doc); err != nil {
+			return nil, 0, err
+		}
 	}
 	var arr []map[string]any
 	if err := yaml.Unmarshal(data, &arr); err != nil {
-		return nil, err
+		return nil, 0, describeYAMLError(data, err)
 	}
 	var names []string
 	for _, obj := range arr {
@@ -74,67 +290,362 @@ func ParseYAML(path string) ([]string, error) {
 			names = append(names, name)
 		}
 	}
-	return names, nil
+	return names, len(arr), nil
+}
+
+// CaseCollision is a name that appeared in several casings; Kept is the first
+// occurrence, which is the one that gets uploaded
+type CaseCollision struct {
+	Kept    string
+	Dropped []string
+}
+
+// CollapseCaseVariants keeps the first occurrence of each name compared
+// case-insensitively, preserving its original casing, and reports the variants
+// that were dropped in favour of it
+func CollapseCaseVariants(names []string) ([]string, []CaseCollision) {
+	first := make(map[string]int) // lowercase name -> index into collisions
+	var kept []string
+	var collisions []CaseCollision
+	for _, name := range names {
+		key := strings.ToLower(name)
+		i, ok := first[key]
+		if !ok {
+			first[key] = len(collisions)
+			collisions = append(collisions, CaseCollision{Kept: name})
+			kept = append(kept, name)
+			continue
+		}
+		if name != collisions[i].Kept {
+			collisions[i].Dropped = append(collisions[i].Dropped, name)
+		}
+	}
+
+	var out []CaseCollision
+	for _, c := range collisions {
+		if len(c.Dropped) > 0 {
+			out = append(out, c)
+		}
+	}
+	return kept, out
+}
+
+// utf8BOM is the byte order mark some editors prepend to UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// normalizeInput strips a leading UTF-8 BOM and converts CRLF/CR line endings to LF
+func normalizeInput(data []byte) []byte {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+}
+
+// readCSVRecords reads every record of r with each field cleaned by cleanField
+func readCSVRecords(r *csv.Reader) ([][]string, error) {
+	records, _, err := readCSVRecordLines(r)
+	return records, err
+}
+
+// readCSVRecordLines is readCSVRecords that also returns the file line each
+// record starts on, which differs from its index once a quoted field spans
+// lines
+func readCSVRecordLines(r *csv.Reader) (records [][]string, lines []int, err error) {
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return records, lines, nil
+		}
+		if err != nil {
+			return records, lines, err
+		}
+		line, _ := r.FieldPos(0)
+		cleanRecord(rec)
+		records = append(records, rec)
+		lines = append(lines, line)
+	}
+}
+
+// cleanRecord applies cleanField to every field of rec in place
+func cleanRecord(rec []string) {
+	for i, field := range rec {
+		rec[i] = cleanField(field)
+	}
+}
+
+// cleanField trims surrounding whitespace and control characters from a CSV
+// field. encoding/csv only folds \r\n line endings, so files saved with stray
+// CRs would otherwise leave "Biceps\r" next to "Biceps".
+func cleanField(field string) string {
+	return strings.TrimFunc(field, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	})
+}
+
+// snippetAt returns up to 40 bytes of data around offset, on a single line
+func snippetAt(data []byte, offset int) string {
+	offset = max(0, min(offset, len(data)))
+	start := max(0, offset-20)
+	end := min(len(data), offset+20)
+	return strings.ReplaceAll(string(data[start:end]), "\n", "⏎")
+}
+
+// describeJSONError adds the byte offset and surrounding content to a JSON decode error
+func describeJSONError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var offset int64
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	return fmt.Errorf("%w (at byte %d near %q)", err, offset, snippetAt(data, int(offset)))
+}
+
+// yamlLinePattern extracts the line number yaml.v3 includes in its error messages
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// describeYAMLError adds the byte offset and offending line to a YAML decode error
+func describeYAMLError(data []byte, err error) error {
+	match := yamlLinePattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(match[1])
+
+	offset := 0
+	for i := 1; i < line; i++ {
+		next := bytes.IndexByte(data[offset:], '\n')
+		if next < 0 {
+			return err
+		}
+		offset += next + 1
+	}
+	end := bytes.IndexByte(data[offset:], '\n')
+	if end < 0 {
+		end = len(data) - offset
+	}
+	return fmt.Errorf("%w (at byte %d: %q)", err, offset, string(data[offset:offset+end]))
 }
 
 // --- Exercises Bulk Upload ---
-// CSV format:
-// Name,Description,Category,Equipment,Types,Muscles
-// Push-up,A bodyweight exercise...,Chest,Bodyweight,"Strength","Chest;Triceps"
+// CSV format (Tags, VariationOf and DefaultScheme are optional). With a header, columns are
+// matched by name in any order; without one they are taken by position:
+// Name,Description,Category,Equipment,Types,Muscles,Tags
+// Push-up,A bodyweight exercise...,Chest,Bodyweight,"Strength","Chest;Triceps","push;compound"
 
 type ExerciseUploadRow struct {
-	Name        string
-	Description string
-	Category    string
-	Equipment   []string
-	Types       []string // split by ;
-	Muscles     []string // split by ;
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"`
+	Equipment   []string `json:"equipment"`
+	Types       []string `json:"types"`                  // split by ;
+	Muscles     []string `json:"muscles"`                // split by ;
+	Tags        []string `json:"tags,omitempty"`         // split by ;
+	VariationOf string   `json:"variation_of,omitempty"` // name of the base exercise, if any
+	// DefaultScheme is the default sets x reps, e.g. 3x8-12, validated by
+	// ParseRepScheme and stored normalized
+	DefaultScheme string `json:"default_scheme,omitempty"`
+	// Line is where the row starts in its file, 0 when it didn't come from one.
+	// It labels errors; --lines counts Row instead.
+	Line int `json:"line,omitempty"`
+	// Row is the row's position among the file's data rows, from 1
+	Row int `json:"-"`
 }
 
-func ParseExercisesCSV(path string) ([]ExerciseUploadRow, error) {
+// ref names the row in errors by its file line and name, e.g. line 47 (Squat)
+func (r ExerciseUploadRow) ref() string {
+	if r.Line == 0 {
+		return r.Name
+	}
+	return fmt.Sprintf("line %d (%s)", r.Line, r.Name)
+}
+
+// ParseExercisesCSV returns the parsed exercise rows along with the number of
+// data rows seen in the file. Rows too short to hold every required column,
+// or without a name, are left out and returned as problems so the rest of the
+// file can still be uploaded.
+func ParseExercisesCSV(path string) ([]ExerciseUploadRow, int, []ValidationError, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, err
 	}
 	defer f.Close()
+	return ParseExercisesCSVReader(f)
+}
+
+// ParseExercisesCSVReader is ParseExercisesCSV for any reader. Rows come
+// back with COLUMN_TRANSFORMS applied.
+func ParseExercisesCSVReader(in io.Reader) ([]ExerciseUploadRow, int, []ValidationError, error) {
+	rows, seen, problems, err := parseExercisesCSV(in)
+	transformExerciseRows(rows)
+	return rows, seen, problems, err
+}
 
-	r := csv.NewReader(f)
-	records, err := r.ReadAll()
+// parseExercisesCSV is ParseExercisesCSVReader before any column transforms
+func parseExercisesCSV(in io.Reader) (rows []ExerciseUploadRow, seen int, problems []ValidationError, err error) {
+	// Short rows are reported as problems rather than failing the file
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	records, lines, err := readCSVRecordLines(r)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, err
 	}
 	if len(records) < 1 {
-		return nil, errors.New("no records found")
+		return nil, 0, nil, errors.New("no records found")
+	}
+
+	// Header: Name,Description,Category,Equipment,Types,Muscles[,Tags[,VariationOf]]
+	cols := exerciseColumnsByPosition()
+	if isHeader(true) {
+		if mapped, ok, err := exerciseColumnsByHeader(records[0]); err != nil {
+			return nil, 0, nil, err
+		} else if ok {
+			cols = mapped
+		}
+		records, lines = records[1:], lines[1:]
 	}
 
-	// Header: Name,Description,Category,Equipment,Types,Muscles
-	var rows []ExerciseUploadRow
 	for i, rec := range records {
-		if i == 0 {
-			continue // skip header
+		if cols.positional && strictColumns && len(rec) > len(exerciseHeader) {
+			return nil, 0, nil, fmt.Errorf("line %d has %d columns, expected at most %d (--strict-columns)", lines[i], len(rec), len(exerciseHeader))
+		}
+		if len(rec) <= cols.required {
+			problems = append(problems, ValidationError{Row: i + 1, Line: lines[i],
+				Reason: fmt.Sprintf("%d columns, expected at least %d", len(rec), cols.required+1)})
+			continue
 		}
-		if len(rec) < 6 {
+		if strings.TrimSpace(rec[cols.index[0]]) == "" {
+			problems = append(problems, ValidationError{Row: i + 1, Line: lines[i], Reason: "empty name"})
 			continue
 		}
 		row := ExerciseUploadRow{
-			Name:        strings.TrimSpace(rec[0]),
-			Description: strings.TrimSpace(rec[1]),
-			Category:    strings.TrimSpace(rec[2]),
-			Equipment:   SplitAndTrim(rec[3], ";"), // now as []string
-			Types:       SplitAndTrim(rec[4], ";"),
-			Muscles:     SplitAndTrim(rec[5], ";"),
+			Name:        strings.TrimSpace(rec[cols.index[0]]),
+			Description: strings.TrimSpace(rec[cols.index[1]]),
+			Category:    strings.TrimSpace(rec[cols.index[2]]),
+			Equipment:   SplitAndTrim(rec[cols.index[3]], ";"), // now as []string
+			Types:       SplitAndTrim(rec[cols.index[4]], ";"),
+			Muscles:     SplitAndTrim(rec[cols.index[5]], ";"),
+			Line:        lines[i],
+			Row:         i + 1,
+		}
+		if i := cols.index[6]; i >= 0 && i < len(rec) {
+			row.Tags = SplitAndTrim(rec[i], ";")
+		}
+		if i := cols.index[7]; i >= 0 && i < len(rec) {
+			row.VariationOf = strings.TrimSpace(rec[i])
+		}
+		if i := cols.index[8]; i >= 0 && i < len(rec) {
+			row.DefaultScheme = strings.TrimSpace(rec[i])
 		}
 		rows = append(rows, row)
 	}
-	return rows, nil
+	return rows, len(records), problems, nil
+}
+
+// strictColumns makes exercise imports fail on columns they don't know
+// instead of ignoring them (--strict-columns)
+var strictColumns bool
+
+// exerciseColumns maps each exerciseHeader column to its index in a record,
+// -1 when an optional column is absent
+type exerciseColumns struct {
+	index      []int
+	required   int  // highest index a row must reach to be imported
+	positional bool // no header; columns are in exerciseHeader order
+}
+
+func exerciseColumnsByPosition() exerciseColumns {
+	cols := exerciseColumns{index: make([]int, len(exerciseHeader)), positional: true}
+	for i, col := range exerciseHeader {
+		cols.index[i] = i
+		if !strings.HasSuffix(col, "?") {
+			cols.required = i
+		}
+	}
+	return cols
+}
+
+// exerciseColumnsByHeader maps columns by their header names, ignoring case and
+// surrounding spaces. ok is false when the header has no Name column, in which
+// case columns are taken by position. Unknown columns are logged once and
+// skipped, or rejected with strictColumns.
+func exerciseColumnsByHeader(header []string) (cols exerciseColumns, ok bool, err error) {
+	present := make(map[string]int, len(header))
+	for i, col := range header {
+		present[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	if _, ok := present["name"]; !ok {
+		return cols, false, nil
+	}
+
+	cols.index = make([]int, len(exerciseHeader))
+	known := make(map[string]bool, len(exerciseHeader))
+	var missing []string
+	for i, col := range exerciseHeader {
+		name, optional := strings.CutSuffix(col, "?")
+		known[strings.ToLower(name)] = true
+		idx, found := present[strings.ToLower(name)]
+		switch {
+		case found:
+			cols.index[i] = idx
+			if !optional {
+				cols.required = max(cols.required, idx)
+			}
+		case optional:
+			cols.index[i] = -1
+		default:
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return cols, false, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+
+	var extra []string
+	for _, col := range header {
+		if !known[strings.ToLower(strings.TrimSpace(col))] {
+			extra = append(extra, col)
+		}
+	}
+	if len(extra) > 0 {
+		if strictColumns {
+			return cols, false, fmt.Errorf("unexpected columns: %s (--strict-columns)", strings.Join(extra, ", "))
+		}
+		logger.Info("ignoring unknown exercise columns", "columns", extra)
+	}
+	return cols, true, nil
 }
 
-// SplitAndTrim splits a string by sep, trims spaces and quotes
+// SplitAndTrim splits a string by sep, trims spaces and quotes. Quoted segments
+// are kept whole, so `"Hang; Power";Clean` yields "Hang; Power" and "Clean".
 func SplitAndTrim(s, sep string) []string {
-	parts := strings.Split(s, sep)
+	var parts []string
+	var current strings.Builder
+	inQuotes := false
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '"':
+			inQuotes = !inQuotes
+			current.WriteByte(s[i])
+			i++
+		case !inQuotes && strings.HasPrefix(s[i:], sep):
+			parts = append(parts, current.String())
+			current.Reset()
+			i += len(sep)
+		default:
+			current.WriteByte(s[i])
+			i++
+		}
+	}
+	parts = append(parts, current.String())
+
 	var out []string
 	for _, p := range parts {
-		p = strings.TrimSpace(strings.Trim(p, `"`))
+		p = strings.TrimSpace(strings.Trim(strings.TrimSpace(p), `"`))
 		if p != "" {
 			out = append(out, p)
 		}
@@ -142,115 +653,534 @@ func SplitAndTrim(s, sep string) []string {
 	return out
 }
 
-func InsertExercises(db *sql.DB, rows []ExerciseUploadRow) error {
-	tx, err := db.Begin()
+const (
+	insertExerciseEquipmentQuery = `INSERT INTO exercise_equipment (exercise_id, equipment_id)
+			 VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	insertExerciseTypeQuery = `INSERT INTO exercise_training_types (exercise_id, training_type_id)
+			 VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	insertExerciseMuscleQuery = `INSERT INTO exercise_muscles (exercise_id, muscle_group_id)
+			 VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	insertExerciseTagQuery = `INSERT INTO exercise_tags (exercise_id, tag_id)
+			 VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	setExerciseParentQuery = `UPDATE exercise SET parent_id = $1 WHERE id = $2`
+)
+
+// CreatedRefs holds the reference entity names that were newly created while
+// importing exercises, as opposed to ones that already existed
+type CreatedRefs struct {
+	Categories []string `json:"categories,omitempty"`
+	Equipment  []string `json:"equipment,omitempty"`
+	Types      []string `json:"types,omitempty"`
+	Muscles    []string `json:"muscles,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// Total returns the number of newly created reference entities
+func (c CreatedRefs) Total() int {
+	return len(c.Categories) + len(c.Equipment) + len(c.Types) + len(c.Muscles) + len(c.Tags)
+}
+
+// skipUnchanged stores a content hash per exercise and skips re-uploaded rows
+// whose hash matches, avoiding the upsert and junction churn (SKIP_UNCHANGED=1)
+var skipUnchanged bool
+
+// noEquipment are equipment values meaning an exercise needs none, compared
+// ignoring case. They are skipped rather than created as equipment
+// (NO_EQUIPMENT=None,Bodyweight,No Equipment replaces the default).
+var noEquipment = []string{"None"}
+
+// isNoEquipment reports whether an equipment value is a noEquipment sentinel
+func isNoEquipment(value string) bool {
+	value = strings.TrimSpace(value)
+	return slices.ContainsFunc(noEquipment, func(s string) bool { return strings.EqualFold(s, value) })
+}
+
+// ExerciseImportResult summarises what InsertExercises did beyond inserting rows
+type ExerciseImportResult struct {
+	Created     CreatedRefs
+	Unchanged   int // rows skipped because their content hash matched
+	NoEquipment int // rows whose equipment named a noEquipment sentinel
+	// UnresolvedParents lists "exercise → parent" pairs whose VariationOf
+	// named an exercise that doesn't exist
+	UnresolvedParents []string
+}
+
+func (r *ExerciseImportResult) merge(other ExerciseImportResult) {
+	r.Created.merge(other.Created)
+	r.Unchanged += other.Unchanged
+	r.NoEquipment += other.NoEquipment
+	r.UnresolvedParents = append(r.UnresolvedParents, other.UnresolvedParents...)
+}
+
+// exerciseContentHash fingerprints everything an upload would write for a row
+func exerciseContentHash(row ExerciseUploadRow) string {
+	fields := []string{
+		row.Name,
+		row.Description,
+		row.Category,
+		strings.Join(row.Equipment, ";"),
+		strings.Join(row.Types, ";"),
+		strings.Join(row.Muscles, ";"),
+		strings.Join(row.Tags, ";"),
+	}
+	// Only hashed when set so rows without a parent keep their existing hash
+	if row.VariationOf != "" {
+		fields = append(fields, row.VariationOf)
+	}
+	if row.DefaultScheme != "" {
+		fields = append(fields, "scheme:"+row.DefaultScheme)
+	}
+	return contentHash([]byte(strings.Join(fields, "\x1f")))
+}
+
+// exerciseUpsertQuery builds the exercise upsert for the active provenance and
+// content-hash settings. Args are name, description, category_id, source_file,
+// default_scheme and, with skipUnchanged, content_hash. A file without schemes
+// keeps the ones already stored.
+func exerciseUpsertQuery() string {
+	columns := "name, description, category_id, source_file, default_scheme"
+	values := "$1, $2, $3, $4, $5"
+	updates := "description=EXCLUDED.description, default_scheme=COALESCE(EXCLUDED.default_scheme, exercise.default_scheme)"
+	if updateProvenance {
+		updates += ", source_file=EXCLUDED.source_file, imported_at=now()"
+	}
+	if skipUnchanged {
+		columns += ", content_hash"
+		values += ", $6"
+		updates += ", content_hash=EXCLUDED.content_hash"
+	}
+	return fmt.Sprintf("INSERT INTO exercise (%s) VALUES (%s) ON CONFLICT (name) DO UPDATE SET %s RETURNING id", columns, values, updates)
+}
+
+// normalizedScheme is the scheme to store for row, NULL when it has none
+func normalizedScheme(row ExerciseUploadRow) sql.NullString {
+	scheme, err := ParseRepScheme(row.DefaultScheme)
+	if row.DefaultScheme == "" || err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: scheme.String(), Valid: true}
+}
+
+// InsertExercises upserts rows in one transaction, calling onProgress, when
+// set, with how many rows are done as each finishes. A failure rolls back
+// every row.
+func InsertExercises(db *sql.DB, rows []ExerciseUploadRow, source string, onProgress func(done int)) (result ExerciseImportResult, err error) {
+	tx, err := beginUploadTx(db)
 	if err != nil {
-		return err
+		return result, err
 	}
 	defer func() {
 		if err != nil {
 			tx.Rollback()
-		} else {
-			tx.Commit()
+		} else if err = tx.Commit(); err != nil && deferConstraints {
+			err = describeDeferredFailure(err)
 		}
+		logTxEnd(tx, "exercise", source, len(rows), err)
 	}()
+	return insertExercisesTx(tx, rows, source, onProgress)
+}
+
+// insertExercisesTx upserts exercise rows and their junction links within tx
+func insertExercisesTx(tx *sql.Tx, rows []ExerciseUploadRow, source string, onProgress func(done int)) (result ExerciseImportResult, err error) {
+	created := &result.Created
+	if deferConstraints {
+		query := `SET CONSTRAINTS ALL DEFERRED`
+		logSQL(query)
+		if _, err := tx.Exec(query); err != nil {
+			return result, fmt.Errorf("defer constraints: %w", err)
+		}
+	}
+
+	// Parents are resolved after every row is inserted, so a variation may
+	// name a base exercise that appears later in the same batch
+	type variation struct {
+		id                string
+		name, ref, parent string
+	}
+	var variations []variation
+
+	for i, row := range rows {
+		if onProgress != nil {
+			onProgress(i)
+		}
+		var hash string
+		if skipUnchanged {
+			hash = exerciseContentHash(row)
+			var stored sql.NullString
+			query := `SELECT content_hash FROM exercise WHERE name = $1`
+			logSQL(query, row.Name)
+			err := tx.QueryRow(query, row.Name).Scan(&stored)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return result, fmt.Errorf("%s: check content hash: %w", row.ref(), err)
+			}
+			if stored.Valid && stored.String == hash {
+				result.Unchanged++
+				logRow(tx, rowEvent{Table: "exercise", Source: source, Name: row.Name, Outcome: rowUnchanged})
+				continue
+			}
+		}
 
-	for _, row := range rows {
 		// Category
-		catID, err := GetOrInsertCategory(tx, row.Category)
+		catID, isNew, err := GetOrInsertCategory(tx, row.Category)
 		if err != nil {
-			return fmt.Errorf("category %s: %w", row.Category, err)
+			return result, fmt.Errorf("%s: category %s: %w", row.ref(), row.Category, err)
+		}
+		if isNew {
+			created.Categories = appendNew(created.Categories, row.Category)
 		}
 
 		// Insert exercise (no equipment_id)
-		var exID int
-		err = tx.QueryRow(
-			`INSERT INTO exercise (name, description, category_id) 
-			 VALUES ($1, $2, $3)
-			 ON CONFLICT (name) DO UPDATE SET description=EXCLUDED.description 
-			 RETURNING id`,
-			row.Name, row.Description, catID,
-		).Scan(&exID)
+		var exID string
+		query := exerciseUpsertQuery()
+		args := []any{row.Name, row.Description, catID, source, normalizedScheme(row)}
+		if skipUnchanged {
+			args = append(args, hash)
+		}
+		logSQL(query, args...)
+		err = tx.QueryRow(query, args...).Scan(&exID)
 		if err != nil {
-			return fmt.Errorf("insert exercise %s: %w", row.Name, err)
+			return result, fmt.Errorf("%s: insert exercise: %w", row.ref(), err)
+		}
+		if row.VariationOf != "" {
+			variations = append(variations, variation{exID, row.Name, row.ref(), row.VariationOf})
 		}
 
-		for _, e := range row.Equipment {
-			e = strings.TrimSpace(e)
-			if e == "" || strings.EqualFold(e, "None") {
-				continue
-			}
-			equipID, err := GetOrInsertEquipment(tx, e)
-			if err != nil {
-				return fmt.Errorf("equipment %s: %w", e, err)
-			}
-			_, err = tx.Exec(
-				`INSERT INTO exercise_equipment (exercise_id, equipment_id) 
-		 VALUES ($1, $2) ON CONFLICT DO NOTHING`,
-				exID, equipID,
-			)
-			if err != nil {
-				return fmt.Errorf("insert equipment junction: %w", err)
-			}
+		if _, err := linkExercise(tx, exID, row, created); err != nil {
+			return result, fmt.Errorf("%s: %w", row.ref(), err)
+		}
+		if slices.ContainsFunc(row.Equipment, isNoEquipment) {
+			result.NoEquipment++
 		}
+		logRow(tx, rowEvent{Table: "exercise", Source: source, Name: row.Name, Outcome: rowUpserted})
+	}
+	if onProgress != nil {
+		onProgress(len(rows))
+	}
 
-		// Types (training_type)
-		for _, t := range row.Types {
-			typeID, err := GetOrInsertType(tx, t)
-			if err != nil {
-				return fmt.Errorf("type %s: %w", t, err)
-			}
-			_, err = tx.Exec(
-				`INSERT INTO exercise_training_types (exercise_id, training_type_id) 
-				 VALUES ($1, $2) ON CONFLICT DO NOTHING`,
-				exID, typeID,
-			)
-			if err != nil {
-				return fmt.Errorf("insert type junction: %w", err)
-			}
+	for _, v := range variations {
+		var parentID string
+		query := `SELECT id FROM exercise WHERE name = $1`
+		logSQL(query, v.parent)
+		err := tx.QueryRow(query, v.parent).Scan(&parentID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && parentID == v.id) {
+			result.UnresolvedParents = append(result.UnresolvedParents, v.name+" → "+v.parent)
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("%s: parent: %w", v.ref, err)
+		}
+		logSQL(setExerciseParentQuery, parentID, v.id)
+		if _, err := tx.Exec(setExerciseParentQuery, parentID, v.id); err != nil {
+			return result, fmt.Errorf("%s: set parent: %w", v.ref, err)
 		}
+	}
+	return result, nil
+}
 
-		// Muscles
-		for _, m := range row.Muscles {
-			muscleID, err := GetOrInsertMuscle(tx, m)
-			if err != nil {
-				return fmt.Errorf("muscle %s: %w", m, err)
-			}
-			_, err = tx.Exec(
-				`INSERT INTO exercise_muscles (exercise_id, muscle_group_id) 
-				 VALUES ($1, $2) ON CONFLICT DO NOTHING`,
-				exID, muscleID,
-			)
-			if err != nil {
-				return fmt.Errorf("insert muscle junction: %w", err)
-			}
+// JunctionAppendResult is what AppendExerciseJunctions added
+type JunctionAppendResult struct {
+	Added   int      // junction rows that weren't linked yet
+	Missing []string // exercises in the file that don't exist
+	Created CreatedRefs
+}
+
+func (r JunctionAppendResult) String() string {
+	s := fmt.Sprintf("Added %d links", r.Added)
+	if len(r.Missing) > 0 {
+		s += fmt.Sprintf("\nSkipped %d exercises that don't exist: %s", len(r.Missing), strings.Join(r.Missing, ", "))
+	}
+	return s
+}
+
+// AppendExerciseJunctions adds the equipment, type, muscle and tag links of
+// rows to exercises that already exist, leaving the exercise records
+// themselves untouched. Rows naming a missing exercise are skipped and
+// reported. Everything happens in one transaction.
+func AppendExerciseJunctions(db *sql.DB, rows []ExerciseUploadRow) (result JunctionAppendResult, err error) {
+	tx, err := beginUploadTx(db)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		logTxEnd(tx, "exercise links", "", len(rows), err)
+	}()
+
+	for _, row := range rows {
+		var exID string
+		query := `SELECT id FROM exercise WHERE name = $1`
+		logSQL(query, row.Name)
+		err := tx.QueryRow(query, row.Name).Scan(&exID)
+		if errors.Is(err, sql.ErrNoRows) {
+			result.Missing = append(result.Missing, row.Name)
+			logRow(tx, rowEvent{Table: "exercise links", Name: row.Name, Outcome: rowSkipped, Error: "no such exercise"})
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("%s: look up exercise: %w", row.ref(), err)
+		}
+		added, err := linkExercise(tx, exID, row, &result.Created)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", row.ref(), err)
+		}
+		result.Added += added
+		logRow(tx, rowEvent{Table: "exercise links", Name: row.Name, Outcome: rowInserted})
+	}
+	return result, nil
+}
+
+// linkExercise adds the equipment, type, muscle and tag junctions named by row
+// to exercise exID, creating missing reference entities and recording them in
+// created. It returns how many junctions were new.
+func linkExercise(tx *sql.Tx, exID string, row ExerciseUploadRow, created *CreatedRefs) (added int, err error) {
+	for _, e := range row.Equipment {
+		e = strings.TrimSpace(e)
+		if e == "" || isNoEquipment(e) {
+			continue
+		}
+		equipID, isNew, err := GetOrInsertEquipment(tx, e)
+		if err != nil {
+			return added, fmt.Errorf("equipment %s: %w", e, err)
+		}
+		if isNew {
+			created.Equipment = appendNew(created.Equipment, e)
+		}
+		n, err := execAffected(tx, insertExerciseEquipmentQuery, exID, equipID)
+		if err != nil {
+			return added, fmt.Errorf("insert equipment junction: %w", err)
+		}
+		added += n
+	}
+
+	// Types (training_type)
+	for _, t := range row.Types {
+		typeID, isNew, err := GetOrInsertType(tx, t)
+		if err != nil {
+			return added, fmt.Errorf("type %s: %w", t, err)
+		}
+		if isNew {
+			created.Types = appendNew(created.Types, t)
+		}
+		n, err := execAffected(tx, insertExerciseTypeQuery, exID, typeID)
+		if err != nil {
+			return added, fmt.Errorf("insert type junction: %w", err)
+		}
+		added += n
+	}
+
+	// Muscles
+	for _, m := range row.Muscles {
+		muscleID, isNew, err := GetOrInsertMuscle(tx, m)
+		if err != nil {
+			return added, fmt.Errorf("muscle %s: %w", m, err)
+		}
+		if isNew {
+			created.Muscles = appendNew(created.Muscles, m)
+		}
+		n, err := execAffected(tx, insertExerciseMuscleQuery, exID, muscleID)
+		if err != nil {
+			return added, fmt.Errorf("insert muscle junction: %w", err)
+		}
+		added += n
+	}
+
+	// Tags
+	for _, t := range row.Tags {
+		tagID, isNew, err := GetOrInsertTag(tx, t)
+		if err != nil {
+			return added, fmt.Errorf("tag %s: %w", t, err)
+		}
+		if isNew {
+			created.Tags = appendNew(created.Tags, t)
+		}
+		n, err := execAffected(tx, insertExerciseTagQuery, exID, tagID)
+		if err != nil {
+			return added, fmt.Errorf("insert tag junction: %w", err)
+		}
+		added += n
+	}
+	return added, nil
+}
+
+// execAffected runs an insert and returns how many rows it added
+func execAffected(tx *sql.Tx, query string, args ...any) (int, error) {
+	logSQL(query, args...)
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// deferConstraints checks the junction tables' foreign keys once at commit
+// instead of per row during an exercises import (DEFER_CONSTRAINTS=1). It speeds
+// up large seeds into an empty database, but a violation is only reported at
+// commit, without the row that caused it, and rolls back the whole transaction
+// (or commit batch with EXERCISE_COMMIT_SIZE). Needs migration 0007, which makes
+// those keys deferrable; on older schemas the setting has no effect.
+var deferConstraints bool
+
+// exerciseCommitSize is how many exercise rows (with their junctions)
+// InsertExercisesInBatches commits per transaction. Zero commits everything in
+// one transaction (EXERCISE_COMMIT_SIZE).
+var exerciseCommitSize int
+
+// InsertExercisesInBatches inserts rows[start:] committing every exerciseCommitSize
+// rows in its own transaction, and calls onCommit with the number of rows committed
+// so far so an interrupted upload can later resume from there. onProgress, when
+// set, is called with the number of rows processed as each finishes.
+func InsertExercisesInBatches(db *sql.DB, rows []ExerciseUploadRow, start int, source string, onCommit, onProgress func(done int)) (ExerciseImportResult, error) {
+	var result ExerciseImportResult
+	size := exerciseCommitSize
+	if size <= 0 {
+		size = max(len(rows)-start, 1)
+	}
+	for i := start; i < len(rows); i += size {
+		end := min(i+size, len(rows))
+		var batchProgress func(done int)
+		if onProgress != nil {
+			batchProgress = func(done int) { onProgress(i + done) }
+		}
+		batch, err := InsertExercises(db, rows[i:end], source, batchProgress)
+		if err != nil {
+			return result, fmt.Errorf("rows %d-%d: %w", i+1, end, err)
+		}
+		result.merge(batch)
+		if onCommit != nil {
+			onCommit(end)
+		}
+	}
+	return result, nil
+}
+
+// merge appends other's newly created names to c, skipping ones c already lists
+func (c *CreatedRefs) merge(other CreatedRefs) {
+	c.Categories = appendNew(c.Categories, other.Categories...)
+	c.Equipment = appendNew(c.Equipment, other.Equipment...)
+	c.Types = appendNew(c.Types, other.Types...)
+	c.Muscles = appendNew(c.Muscles, other.Muscles...)
+	c.Tags = appendNew(c.Tags, other.Tags...)
+}
+
+// appendNew appends each of names not already in list, so a reference
+// reported as created by several rows or batches is listed once
+func appendNew(list []string, names ...string) []string {
+	for _, name := range names {
+		if !slices.Contains(list, name) {
+			list = append(list, name)
 		}
 	}
-	return nil
+	return list
 }
 
-func GetOrInsertCategory(tx *sql.Tx, name string) (int, error) {
-	var id int
-	err := tx.QueryRow(`INSERT INTO exercise_category (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name RETURNING id`, name).Scan(&id)
-	return id, err
+func GetOrInsertCategory(tx *sql.Tx, name string) (string, bool, error) {
+	var id string
+	var created bool
+	query := `INSERT INTO exercise_category (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name, deleted_at=NULL RETURNING id, (xmax = 0)`
+	logSQL(query, name)
+	err := tx.QueryRow(query, name).Scan(&id, &created)
+	return id, created, err
 }
 
-func GetOrInsertEquipment(tx *sql.Tx, name string) (int, error) {
-	var id int
-	err := tx.QueryRow(`INSERT INTO equipment (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name RETURNING id`, name).Scan(&id)
-	return id, err
+func GetOrInsertEquipment(tx *sql.Tx, name string) (string, bool, error) {
+	var id string
+	var created bool
+	query := `INSERT INTO equipment (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name, deleted_at=NULL RETURNING id, (xmax = 0)`
+	logSQL(query, name)
+	err := tx.QueryRow(query, name).Scan(&id, &created)
+	return id, created, err
 }
 
-func GetOrInsertType(tx *sql.Tx, name string) (int, error) {
-	var id int
-	err := tx.QueryRow(`INSERT INTO training_type (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name RETURNING id`, name).Scan(&id)
-	return id, err
+func GetOrInsertType(tx *sql.Tx, name string) (string, bool, error) {
+	var id string
+	var created bool
+	query := `INSERT INTO training_type (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name, deleted_at=NULL RETURNING id, (xmax = 0)`
+	logSQL(query, name)
+	err := tx.QueryRow(query, name).Scan(&id, &created)
+	return id, created, err
 }
 
-func GetOrInsertMuscle(tx *sql.Tx, name string) (int, error) {
-	var id int
-	err := tx.QueryRow(`INSERT INTO muscle_group (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name RETURNING id`, name).Scan(&id)
-	return id, err
+// GetOrInsertMuscle resolves name through muscle_synonyms first, so either
+// form of a muscle maps to the same canonical row
+func GetOrInsertMuscle(tx *sql.Tx, name string) (string, bool, error) {
+	if id, err := resolveMuscleSynonym(tx, name); err != nil || id != "" {
+		return id, false, err
+	}
+
+	var id string
+	var created bool
+	query := `INSERT INTO muscle_group (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name, deleted_at=NULL RETURNING id, (xmax = 0)`
+	logSQL(query, name)
+	err := tx.QueryRow(query, name).Scan(&id, &created)
+	return id, created, err
+}
+
+func GetOrInsertTag(tx *sql.Tx, name string) (string, bool, error) {
+	var id string
+	var created bool
+	query := `INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name, deleted_at=NULL RETURNING id, (xmax = 0)`
+	logSQL(query, name)
+	err := tx.QueryRow(query, name).Scan(&id, &created)
+	return id, created, err
+}
+
+// appendNames appends names to a single-column CSV file, skipping any that are
+// already present (case-insensitive)
+func appendNames(path string, names []string) error {
+	existing, _, err := ParseCSV(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	var toWrite []string
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" || have[key] {
+			continue
+		}
+		have[key] = true
+		toWrite = append(toWrite, name)
+	}
+	if len(toWrite) == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Make sure we start on a fresh line
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := f.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+
+	w := csv.NewWriter(f)
+	for _, name := range toWrite {
+		if err := w.Write([]string{name}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
 }
 
 // DB is an interface for *sql.DB or a transaction, for testability
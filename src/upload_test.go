@@ -0,0 +1,296 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseExercisesCSVTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		header headerMode
+		csv    string
+		want   []string
+	}{
+		{
+			name: "three tags by header",
+			csv:  "Name,Description,Category,Equipment,Types,Muscles,Tags\nPush-up,,Chest,None,Strength,Chest,push;compound;home-friendly\n",
+			want: []string{"push", "compound", "home-friendly"},
+		},
+		{
+			name:   "three tags by position",
+			header: headerAbsent,
+			csv:    "Push-up,,Chest,None,Strength,Chest, push ; compound ;home-friendly\n",
+			want:   []string{"push", "compound", "home-friendly"},
+		},
+		{
+			name: "no tags column",
+			csv:  "Name,Description,Category,Equipment,Types,Muscles\nPush-up,,Chest,None,Strength,Chest\n",
+			want: nil,
+		},
+		{
+			name:   "empty tags cell",
+			header: headerAbsent,
+			csv:    "Push-up,,Chest,None,Strength,Chest,\n",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvHeader = tt.header
+			t.Cleanup(func() { csvHeader = headerAuto })
+			rows, _, problems, err := ParseExercisesCSVReader(strings.NewReader(tt.csv))
+			if err != nil || len(problems) > 0 {
+				t.Fatalf("parse: %v %v", err, problems)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("got %d rows, want 1", len(rows))
+			}
+			if !slices.Equal(rows[0].Tags, tt.want) {
+				t.Errorf("tags = %q, want %q", rows[0].Tags, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsertExercisesLinksThreeTags(t *testing.T) {
+	db, rec := openFakeDB(t, returningID())
+	row := ExerciseUploadRow{Name: "Push-up", Category: "Chest", Tags: []string{"push", "compound", "home-friendly"}}
+
+	result, err := InsertExercises(db, []ExerciseUploadRow{row}, "test.csv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.Created.Tags, row.Tags) {
+		t.Errorf("created tags = %q, want %q", result.Created.Tags, row.Tags)
+	}
+
+	links := statementsMatching(rec, "INSERT INTO exercise_tags")
+	if len(links) != 3 {
+		t.Fatalf("got %d exercise_tags inserts, want 3", len(links))
+	}
+	for i, link := range links {
+		want := []any{"exercise:Push-up", "tags:" + row.Tags[i]}
+		if !slices.Equal(link.Args, want) {
+			t.Errorf("link %d args = %v, want %v", i, link.Args, want)
+		}
+	}
+	if types := statementsMatching(rec, "INSERT INTO training_type"); len(types) != 0 {
+		t.Errorf("tags were stored as types: %v", types)
+	}
+}
+
+func TestSplitAndTrim(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"plain", "Chest; Triceps ;Shoulders", []string{"Chest", "Triceps", "Shoulders"}},
+		{"quoted segment keeps separator", `"Hang; Power";Clean`, []string{"Hang; Power", "Clean"}},
+		{"quoted segment last", `Clean; "Hang; Power"`, []string{"Clean", "Hang; Power"}},
+		{"several quoted segments", `"A;B";"C;D"`, []string{"A;B", "C;D"}},
+		{"empty segments dropped", "Chest;;  ;Back", []string{"Chest", "Back"}},
+		{"empty", "", nil},
+		{"unterminated quote keeps the rest whole", `"Hang; Power`, []string{"Hang; Power"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitAndTrim(tt.in, ";"); !slices.Equal(got, tt.want) {
+				t.Errorf("SplitAndTrim(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExercisesCSVQuotedSeparator(t *testing.T) {
+	csvHeader = headerAbsent
+	t.Cleanup(func() { csvHeader = headerAuto })
+
+	rows, _, _, err := ParseExercisesCSVReader(strings.NewReader(`Hang clean,,Olympic,Barbell,"""Hang; Power"";Clean",Legs` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Hang; Power", "Clean"}; len(rows) != 1 || !slices.Equal(rows[0].Types, want) {
+		t.Errorf("types = %q, want %q", rows, want)
+	}
+}
+
+func TestCollapseCaseVariants(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         []string
+		want       []string
+		collisions []CaseCollision
+	}{
+		{"no variants", []string{"Barbell", "Dumbbell"}, []string{"Barbell", "Dumbbell"}, nil},
+		{"first casing kept", []string{"barbell", "Barbell", "BARBELL"}, []string{"barbell"},
+			[]CaseCollision{{Kept: "barbell", Dropped: []string{"Barbell", "BARBELL"}}}},
+		{"exact duplicates aren't collisions", []string{"Barbell", "Barbell"}, []string{"Barbell"}, nil},
+		{"order preserved around collisions", []string{"Chest", "Back", "chest", "Legs", "BACK"}, []string{"Chest", "Back", "Legs"},
+			[]CaseCollision{{Kept: "Chest", Dropped: []string{"chest"}}, {Kept: "Back", Dropped: []string{"BACK"}}}},
+		{"empty", nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, collisions := CollapseCaseVariants(tt.in)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("kept = %q, want %q", got, tt.want)
+			}
+			if !slices.EqualFunc(collisions, tt.collisions, func(a, b CaseCollision) bool {
+				return a.Kept == b.Kept && slices.Equal(a.Dropped, b.Dropped)
+			}) {
+				t.Errorf("collisions = %+v, want %+v", collisions, tt.collisions)
+			}
+		})
+	}
+}
+
+func TestDescribeCaseCollisions(t *testing.T) {
+	if got := describeCaseCollisions(nil); got != "" {
+		t.Errorf("no collisions described as %q", got)
+	}
+	got := describeCaseCollisions([]CaseCollision{{Kept: "Barbell", Dropped: []string{"barbell", "BARBELL"}}})
+	want := "⚠ 1 names appeared in several casings; kept the first:\n  Barbell ← barbell, BARBELL"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseCSVReaderCRLF(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"CRLF line endings", "Name\r\nBiceps\r\nTriceps\r\n"},
+		{"stray CR before CRLF", "Name\r\nBiceps\r\r\nTriceps\r\r\n"},
+		{"CR only", "Name\rBiceps\rTriceps\r"},
+		{"tabs and control characters", "Name\nBiceps\t\x00\n\x0bTriceps \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, _, err := ParseCSVReader(strings.NewReader(string(normalizeInput([]byte(tt.csv)))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"Biceps", "Triceps"}; !slices.Equal(names, want) {
+				t.Errorf("names = %q, want %q", names, want)
+			}
+		})
+	}
+}
+
+func TestNameLines(t *testing.T) {
+	t.Cleanup(func() { nameColumns = nil })
+	// The quoted note spans two lines, so "" sits on line 5, not line 4
+	data := "Name,Note\nBiceps,\"two\nlines\"\nTriceps,\n,blank\n"
+	for _, columns := range [][]string{nil, {"name"}} {
+		nameColumns = columns
+		names, lines, seen, err := parseNames(".csv", []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen != 3 || !slices.Equal(names, []string{"Biceps", "Triceps", ""}) || !slices.Equal(lines, []int{2, 4, 5}) {
+			t.Errorf("columns %q: names %q lines %v seen %d", columns, names, lines, seen)
+		}
+		errs := ValidateNames(names, lines)
+		if len(errs) != 1 || errs[0].Error() != "line 5: empty name" {
+			t.Errorf("columns %q: validation errors %v", columns, errs)
+		}
+	}
+
+	// JSON has no lines, so problems keep their row
+	names, lines, _, err := parseNames(".json", []byte(`[{"name": "Biceps"}, {"name": ""}]`))
+	if err != nil || lines != nil {
+		t.Fatalf("json: lines %v, err %v", lines, err)
+	}
+	if errs := ValidateNames(names, lines); len(errs) != 1 || errs[0].Error() != "row 2: empty name" {
+		t.Errorf("json validation errors %v", errs)
+	}
+
+	failed := []FailedName{{Name: "Triceps", Err: errors.New("too long")}}
+	locateFailedNames(failed, nameLines([]string{"Biceps", "Triceps", "Triceps"}, []int{2, 4, 7}))
+	if got := failed[0].String(); got != "Triceps (line 4: too long)" {
+		t.Errorf("failed name = %q", got)
+	}
+}
+
+func TestParseExercisesCSVCRLF(t *testing.T) {
+	data := "Name,Description,Category,Equipment,Types,Muscles\r\n" +
+		"Curl,Elbow flexion,Arms,Dumbbell\r,Strength,Biceps\r\r\n" +
+		"Pushdown,,Arms,Cable,Strength,Triceps\r\n"
+	rows, _, problems, err := ParseExercisesCSVReader(strings.NewReader(data))
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("parse: %v %v", err, problems)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if got := rows[0].Equipment; !slices.Equal(got, []string{"Dumbbell"}) {
+		t.Errorf("equipment = %q", got)
+	}
+	if got := []string{rows[0].Muscles[0], rows[1].Muscles[0]}; !slices.Equal(got, []string{"Biceps", "Triceps"}) {
+		t.Errorf("muscles = %q, want clean names", got)
+	}
+}
+
+func TestApplyExerciseLineRangeCountsDataRows(t *testing.T) {
+	csvHeader = headerPresent
+	uploadLines = lineRange{first: 2, last: 4}
+	t.Cleanup(func() { csvHeader, uploadLines = headerAuto, lineRange{} })
+
+	data := "Name,Description,Category,Equipment,Types,Muscles\n" +
+		"A,,Cat,Bar,Strength,Chest\n" + // row 1
+		"B,,Cat\n" + // row 2, malformed
+		"C,\"two\nlines\",Cat,Bar,Strength,Chest\n" + // row 3, lines 4-5
+		"D,,Cat,Bar,Strength,Chest\n" + // row 4, line 6
+		"E,,Cat,Bar,Strength,Chest\n" // row 5
+	rows, seen, problems, err := ParseExercisesCSVReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, problems, note := applyExerciseLineRange(rows, problems, seen)
+	var names []string
+	for _, row := range rows {
+		names = append(names, row.Name)
+	}
+	if want := []string{"C", "D"}; !slices.Equal(names, want) {
+		t.Errorf("rows = %q, want %q", names, want)
+	}
+	if rows[1].Line != 6 {
+		t.Errorf("D is labelled line %d, want its file line 6", rows[1].Line)
+	}
+	if len(problems) != 1 || problems[0].Line != 3 {
+		t.Errorf("problems = %v, want the malformed line 3", problems)
+	}
+	// The same range over names selects the same data rows
+	_, nameNote := applyLineRange([]string{"A", "B", "C", "D", "E"})
+	if want := "Uploaded rows 2–4 of 5."; note != want || nameNote != want {
+		t.Errorf("notes = %q and %q, want %q", note, nameNote, want)
+	}
+}
+
+func TestCreatedRefsListedOnce(t *testing.T) {
+	// The offline fake reports every upsert as an insert
+	rows := []ExerciseUploadRow{
+		{Name: "Bench press", Category: "Chest", Equipment: []string{"Barbell"}},
+		{Name: "Squat", Category: "Legs", Equipment: []string{"Barbell", "Rack"}},
+	}
+	result, err := InsertExercises(OpenOffline(), rows, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Barbell", "Rack"}; !slices.Equal(result.Created.Equipment, want) {
+		t.Errorf("created equipment = %q, want %q", result.Created.Equipment, want)
+	}
+
+	var merged CreatedRefs
+	merged.merge(CreatedRefs{Equipment: []string{"Barbell"}, Tags: []string{"push"}})
+	merged.merge(CreatedRefs{Equipment: []string{"Barbell", "Cable"}, Tags: []string{"push"}})
+	if !slices.Equal(merged.Equipment, []string{"Barbell", "Cable"}) || !slices.Equal(merged.Tags, []string{"push"}) {
+		t.Errorf("merged = %+v, want each name once", merged)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// urlFetchTimeout bounds how long a URL import waits for the download
+const urlFetchTimeout = 30 * time.Second
+
+// maxURLFetch caps how much of a URL import is read into memory
+const maxURLFetch = 64 << 20
+
+// errSheetNotPublic means Google returned its sign-in page instead of the sheet
+var errSheetNotPublic = errors.New("the sheet is not publicly accessible; share it as \"Anyone with the link\" or publish it to the web")
+
+var sheetIDPattern = regexp.MustCompile(`^/spreadsheets/d/([^/]+)`)
+
+// googleSheetsCSVURL rewrites any Google Sheets link (edit, view or export) to
+// its CSV export, keeping the sheet tab (gid). Other URLs are returned as is.
+func googleSheetsCSVURL(u *url.URL) *url.URL {
+	if u.Host != "docs.google.com" {
+		return u
+	}
+	match := sheetIDPattern.FindStringSubmatch(u.Path)
+	if match == nil {
+		return u
+	}
+
+	gid := u.Query().Get("gid")
+	if gid == "" {
+		// Edit links carry the tab in the fragment: #gid=123
+		if values, err := url.ParseQuery(u.Fragment); err == nil {
+			gid = values.Get("gid")
+		}
+	}
+
+	query := url.Values{"format": {"csv"}}
+	if gid != "" {
+		query.Set("gid", gid)
+	}
+	return &url.URL{
+		Scheme:   "https",
+		Host:     u.Host,
+		Path:     "/spreadsheets/d/" + match[1] + "/export",
+		RawQuery: query.Encode(),
+	}
+}
+
+// FetchURL downloads an upload file over HTTP and returns its contents with the
+// format to parse them as, taken from the content type or the URL's extension
+func FetchURL(rawURL string) ([]byte, string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, "", fmt.Errorf("not an http(s) URL: %q", rawURL)
+	}
+	u = googleSheetsCSVURL(u)
+	isSheet := u.Host == "docs.google.com"
+
+	client := &http.Client{Timeout: urlFetchTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		// The error repeats the URL, tokens and all
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = urlSource(urlErr.URL)
+		}
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if isSheet && (mediaType == "text/html" || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		return nil, "", errSheetNotPublic
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("fetch %s: %s", urlSource(u.String()), resp.Status)
+	}
+
+	// Read one byte past the limit to tell a file of exactly maxURLFetch
+	// bytes from a larger one
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxURLFetch+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxURLFetch {
+		return nil, "", fmt.Errorf("the download is larger than %d MiB", maxURLFetch>>20)
+	}
+
+	switch {
+	case mediaType == "text/csv" || isSheet:
+		return data, ".csv", nil
+	case mediaType == "application/json":
+		return data, ".json", nil
+	case strings.Contains(mediaType, "yaml"):
+		return data, ".yaml", nil
+	}
+	if ext := strings.ToLower(path.Ext(u.Path)); ext == ".json" || ext == ".yaml" || ext == ".yml" {
+		return data, ext, nil
+	}
+	return data, ".csv", nil
+}
+
+// urlSource is how an imported URL is recorded in source_file and the audit
+// log: without userinfo, query string or fragment, which may carry tokens
+func urlSource(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "url"
+	}
+	u.User, u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = nil, "", false, "", ""
+	return u.String()
+}
+
+// urlFetchMsg carries the result of a background FetchURL
+type urlFetchMsg struct {
+	data   []byte
+	ext    string
+	source string
+	err    error
+}
+
+// fetchURL runs FetchURL in the background
+func fetchURL(rawURL string) tea.Cmd {
+	return func() tea.Msg {
+		data, ext, err := FetchURL(rawURL)
+		return urlFetchMsg{data: data, ext: ext, source: urlSource(rawURL), err: err}
+	}
+}
+
+func updateURLInput(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if fetched, ok := msg.(urlFetchMsg); ok {
+		if !m.urlFetching {
+			// Cancelled with esc while the download ran
+			return m, nil
+		}
+		m.urlFetching = false
+		if fetched.err != nil {
+			m.state = stateResult
+			m.resultMsg = fmt.Sprintf("Error fetching URL: %v\nPress enter or q to return to menu.", fetched.err)
+			m.isError = true
+			return m, nil
+		}
+		m.uploadSource = fetched.source
+		m.urlInput = ""
+		return uploadData(m, fetched.ext, fetched.data)
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch key.Type {
+	case tea.KeyEsc:
+		m.state = stateMenu
+		m.urlInput = ""
+		m.urlFetching = false
+		return m, nil
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	}
+	if m.urlFetching {
+		return m, nil
+	}
+
+	switch key.Type {
+	case tea.KeyEnter:
+		m.urlFetching = true
+		return m, fetchURL(m.urlInput)
+	case tea.KeyBackspace:
+		if r := []rune(m.urlInput); len(r) > 0 {
+			m.urlInput = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes:
+		m.urlInput += string(key.Runes)
+	}
+	return m, nil
+}
+
+func viewURLInput(m model) string {
+	var parts []string
+
+	parts = append(parts, RenderMenuTitle(fmt.Sprintf("URL → %s", menuOptions[m.menuChoice])))
+	parts = append(parts, "")
+	if m.urlFetching {
+		parts = append(parts, CursorStyle.Render("url> ")+m.urlInput)
+		parts = append(parts, "")
+		parts = append(parts, RenderHelpText("Fetching… • Cancel: esc"))
+	} else {
+		parts = append(parts, CursorStyle.Render("url> ")+m.urlInput+"█")
+		parts = append(parts, "")
+		parts = append(parts, RenderHelpText("Google Sheets links are fetched as CSV • Fetch: enter • Back: esc"))
+	}
+
+	return ContainerStyle.Render(strings.Join(parts, "\n"))
+}
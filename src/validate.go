@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// failFast makes validation stop at the first problem instead of collecting all (FAIL_FAST=1)
+var failFast bool
+
+// ValidationError describes a problem with a single data row (1-based). Line
+// is where the row starts in its file, when known.
+type ValidationError struct {
+	Row    int
+	Line   int
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Reason)
+}
+
+// ValidateNames checks a list of names for blank entries. lines holds the file
+// line of each name, or is nil when the parser doesn't report them.
+func ValidateNames(names []string, lines []int) []ValidationError {
+	var errs []ValidationError
+	for i, name := range names {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, ValidationError{Row: i + 1, Line: lineAt(lines, i), Reason: "empty name"})
+			if failFast {
+				return errs
+			}
+		}
+	}
+	return errs
+}
+
+// lineAt is lines[i], or 0 when lines doesn't cover i
+func lineAt(lines []int, i int) int {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return 0
+}
+
+// nameLines maps each name to the file line of its first occurrence, for
+// locating names after they have been deduped
+func nameLines(names []string, lines []int) map[string]int {
+	if lines == nil {
+		return nil
+	}
+	byName := make(map[string]int, len(names))
+	for i, name := range names {
+		if _, ok := byName[name]; !ok {
+			byName[name] = lineAt(lines, i)
+		}
+	}
+	return byName
+}
+
+// ValidateExerciseRows checks parsed exercise rows for missing required fields
+func ValidateExerciseRows(rows []ExerciseUploadRow) []ValidationError {
+	var errs []ValidationError
+	for i, row := range rows {
+		var reasons []string
+		if row.Name == "" {
+			reasons = append(reasons, "empty name")
+		}
+		if row.Category == "" {
+			reasons = append(reasons, "empty category")
+		}
+		if row.DefaultScheme != "" {
+			if _, err := ParseRepScheme(row.DefaultScheme); err != nil {
+				reasons = append(reasons, err.Error())
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		errs = append(errs, ValidationError{Row: i + 1, Line: row.Line, Reason: strings.Join(reasons, ", ")})
+		if failFast {
+			return errs
+		}
+	}
+	return errs
+}
+
+// malformedLineLimit caps how many line numbers describeMalformedRows lists
+const malformedLineLimit = 5
+
+// describeMalformedRows summarises rows the parser left out, e.g.
+// "Skipped 4 malformed rows (lines 12, 19, 23, 40)"
+func describeMalformedRows(problems []ValidationError) string {
+	if len(problems) == 0 {
+		return ""
+	}
+	var where []string
+	for i, p := range problems {
+		if i == malformedLineLimit {
+			where = append(where, "…")
+			break
+		}
+		where = append(where, strconv.Itoa(p.Line))
+	}
+	noun := "rows"
+	if len(problems) == 1 {
+		noun = "row"
+	}
+	return fmt.Sprintf("Skipped %d malformed %s (lines %s)", len(problems), noun, strings.Join(where, ", "))
+}
+
+// formatValidationErrors renders up to limit validation errors, one per line
+func formatValidationErrors(errs []ValidationError, limit int) string {
+	var lines []string
+	for i, e := range errs {
+		if i == limit {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(errs)-limit))
+			break
+		}
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RepScheme is a default sets x reps prescription, e.g. 3x8-12. RepsMin equals
+// RepsMax for a fixed rep count.
+type RepScheme struct {
+	Sets    int
+	RepsMin int
+	RepsMax int
+}
+
+func (r RepScheme) String() string {
+	if r.RepsMin == r.RepsMax {
+		return fmt.Sprintf("%dx%d", r.Sets, r.RepsMin)
+	}
+	return fmt.Sprintf("%dx%d-%d", r.Sets, r.RepsMin, r.RepsMax)
+}
+
+var repSchemePattern = regexp.MustCompile(`^(\d+)\s*[xX×]\s*(\d+)(?:\s*[-–]\s*(\d+))?$`)
+
+// ParseRepScheme parses "sets x reps" or "sets x min-max", e.g. 3x8 or 3 x 8–12
+func ParseRepScheme(s string) (RepScheme, error) {
+	match := repSchemePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return RepScheme{}, fmt.Errorf("malformed scheme %q, expected e.g. 3x8 or 3x8-12", s)
+	}
+	// The pattern only matches digits, so these conversions can't fail
+	sets, _ := strconv.Atoi(match[1])
+	r := RepScheme{Sets: sets}
+	r.RepsMin, _ = strconv.Atoi(match[2])
+	r.RepsMax = r.RepsMin
+	if match[3] != "" {
+		r.RepsMax, _ = strconv.Atoi(match[3])
+	}
+	switch {
+	case r.Sets == 0 || r.RepsMin == 0:
+		return RepScheme{}, fmt.Errorf("scheme %q needs at least one set and one rep", s)
+	case r.RepsMax < r.RepsMin:
+		return RepScheme{}, fmt.Errorf("scheme %q has a rep range that goes down", s)
+	}
+	return r, nil
+}